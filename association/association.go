@@ -11,6 +11,10 @@ import (
 	"github.com/edgestore/edgestore/internal/model"
 )
 
+// AnyType is used in place of an association type to index associations
+// regardless of their type.
+const AnyType = "*"
+
 func NewAssociationID(in model.ID, atype string, out model.ID) model.ID {
 	return model.ID(fmt.Sprintf("%s:%s:%s", in, atype, out))
 }
@@ -19,6 +23,22 @@ func NewAssociationTypeID(in model.ID, atype string) model.ID {
 	return model.ID(fmt.Sprintf("%s:%s", in, atype))
 }
 
+// NewEdgeID returns the ID of the key holding the ID of the association linking in to out with type atype.
+func NewEdgeID(in model.ID, atype string, out model.ID) model.ID {
+	return model.ID(fmt.Sprintf("edge:%s", NewAssociationID(in, atype, out)))
+}
+
+// NewInverseAssociationTypeID returns the ID of the index of associations of type atype pointing to out.
+func NewInverseAssociationTypeID(out model.ID, atype string) model.ID {
+	return model.ID(fmt.Sprintf("inverse:%s:%s", out, atype))
+}
+
+// NewInverseIndexedID returns the ID of the key marking the inverse indexes of a tenant as
+// backfilled from the event store.
+func NewInverseIndexedID() model.ID {
+	return model.ID("backfill:inverse")
+}
+
 type Association struct {
 	CreatedAt *time.Time    `json:"created_at"`
 	Data      model.Data    `json:"data,omitempty"`
@@ -32,12 +52,23 @@ type Association struct {
 	Version   model.Version `json:"version"`
 }
 
+const (
+	// RejectDuplicate rejects associations linking two entities already linked by the same type.
+	RejectDuplicate = "reject"
+
+	// UpsertDuplicate updates the data of the existing association instead.
+	UpsertDuplicate = "upsert"
+)
+
 type InsertAssociation struct {
 	model.CommandModel
 	Data model.Data `json:"data"`
 	In   model.ID   `json:"in" binding:"required"`
 	Out  model.ID   `json:"out" binding:"required"`
 	Type string     `json:"atype" binding:"required"`
+
+	// OnDuplicate is either RejectDuplicate (default) or UpsertDuplicate.
+	OnDuplicate string `json:"on_duplicate"`
 }
 
 type UpdateAssociation struct {
@@ -66,6 +97,7 @@ type AssociationUpdated struct {
 type AssociationDeleted struct {
 	model.EventModel
 	DeletedAt *time.Time
+	Type      string `json:"atype,omitempty"`
 }
 
 func (o *Association) On(event model.Event) error {
@@ -76,10 +108,14 @@ func (o *Association) On(event model.Event) error {
 		o.In = v.In
 		o.Out = v.Out
 		o.Data = v.Data
+		o.DeletedAt = nil
 		o.Type = v.Type
 	case *AssociationUpdated:
 		o.Data = v.Data
-		o.Type = v.Type
+		// Updates recorded before they carried the type keep the one of the insert.
+		if v.Type != "" {
+			o.Type = v.Type
+		}
 	case *AssociationDeleted:
 		o.DeletedAt = v.DeletedAt
 	default:
@@ -154,6 +190,10 @@ func (o *Association) applyInsert(cmd *InsertAssociation) (model.Event, error) {
 		return nil, errors.E(errors.Invalid, "missing type")
 	}
 
+	if cmd.Type == AnyType {
+		return nil, errors.E(errors.Invalid, fmt.Sprintf("type %q is reserved", AnyType))
+	}
+
 	now := time.Now()
 	inserted := &AssociationInserted{
 		EventModel: model.EventModel{
@@ -181,6 +221,7 @@ func (o *Association) applyUpdate(cmd *UpdateAssociation) (model.Event, error) {
 			At:       &now,
 		},
 		Data: cmd.Data,
+		Type: o.Type,
 	}
 
 	return updated, nil
@@ -196,40 +237,41 @@ func (o *Association) applyDelete(cmd *DeleteAssociation) (model.Event, error) {
 			At:       &now,
 		},
 		DeletedAt: &now,
+		Type:      o.Type,
 	}
 
 	return deleted, nil
 }
 
 func convertMapStringToAssociation(m map[string]string) (*Association, error) {
-	var createdAt time.Time
+	var createdAt *time.Time
 	if _, exists := m["created_at"]; exists {
 		value, err := time.Parse(time.RFC3339, m["created_at"])
 		if err != nil {
 			return nil, err
 		}
 
-		createdAt = value
+		createdAt = &value
 	}
 
-	var deletedAt time.Time
+	var deletedAt *time.Time
 	if _, exists := m["deleted_at"]; exists {
 		t, err := time.Parse(time.RFC3339, m["deleted_at"])
 		if err != nil {
 			return nil, err
 		}
 
-		deletedAt = t
+		deletedAt = &t
 	}
 
-	var updatedAt time.Time
+	var updatedAt *time.Time
 	if _, exists := m["updated_at"]; exists {
 		t, err := time.Parse(time.RFC3339, m["updated_at"])
 		if err != nil {
 			return nil, err
 		}
 
-		updatedAt = t
+		updatedAt = &t
 	}
 
 	var data model.Data
@@ -246,23 +288,23 @@ func convertMapStringToAssociation(m map[string]string) (*Association, error) {
 	}
 
 	assoc := &Association{
-		CreatedAt: &createdAt,
-		DeletedAt: &deletedAt,
+		CreatedAt: createdAt,
+		DeletedAt: deletedAt,
 		ID:        model.ID(m["id"]),
 		In:        model.ID(m["in"]),
 		Out:       model.ID(m["out"]),
 		Data:      data,
 		TenantID:  model.ID(m["tenant_id"]),
 		Type:      m["atype"],
-		UpdatedAt: &updatedAt,
+		UpdatedAt: updatedAt,
 		Version:   model.Version(version),
 	}
 
 	return assoc, nil
 }
 
-func convertAssociationToMapString(a *Association) model.Data {
-	m := make(model.Data)
+func convertAssociationToMapString(a *Association) map[string]interface{} {
+	m := make(map[string]interface{})
 
 	if a.CreatedAt != nil {
 		m["created_at"] = a.CreatedAt.Format(time.RFC3339)
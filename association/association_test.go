@@ -0,0 +1,44 @@
+package association
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssociation_On_LegacyUpdate(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	store := eventstore.NewInMemory(logger)
+	repo := eventstore.NewRepository(&Association{}, store, NewSerializer(), logger)
+
+	// Update events recorded before they carried the type of the association.
+	id := NewAssociationID("alice", "follows", "bob")
+	history := eventstore.History{
+		{AggregateID: id, TenantID: testTenant, Version: 1, Data: []byte(`{"kind": "AssociationInserted", "payload": {"id": "alice:follows:bob", "tenant_id": "anonymous", "version": 1, "at": "2019-01-29T18:42:00Z", "in": "alice", "out": "bob", "atype": "follows"}}`)},
+		{AggregateID: id, TenantID: testTenant, Version: 2, Data: []byte(`{"kind": "AssociationUpdated", "payload": {"id": "alice:follows:bob", "tenant_id": "anonymous", "version": 2, "at": "2019-01-30T18:42:00Z", "data": {"since": 2019}}}`)},
+	}
+	require.NoError(t, store.Save(ctx, id, testTenant, history))
+
+	agg, err := repo.Load(ctx, id, testTenant)
+	require.NoError(t, err)
+
+	assoc := agg.(*Association)
+	assert.Equal(t, "follows", assoc.Type)
+	assert.Equal(t, model.Data{"since": float64(2019)}, assoc.Data)
+	assert.Equal(t, model.Version(2), assoc.Version)
+
+	// New updates carry the type, and replay the same.
+	_, err = repo.Apply(ctx, &UpdateAssociation{CommandModel: model.CommandModel{ID: id, TenantID: testTenant}, Data: model.Data{"since": 2020}})
+	require.NoError(t, err)
+
+	agg, err = repo.Load(ctx, id, testTenant)
+	require.NoError(t, err)
+	assert.Equal(t, "follows", agg.(*Association).Type)
+	assert.Equal(t, model.Version(3), agg.(*Association).Version)
+}
@@ -0,0 +1,254 @@
+package association
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// Cardinality limits the number of associations of a type around a single entity.
+type Cardinality string
+
+const (
+	// OneToOne allows a single outgoing association per source and a single incoming association per target.
+	OneToOne Cardinality = "one-to-one"
+
+	// OneToMany allows a single incoming association per target.
+	OneToMany Cardinality = "one-to-many"
+
+	// ManyToOne allows a single outgoing association per source.
+	ManyToOne Cardinality = "many-to-one"
+
+	// ManyToMany does not limit associations.
+	ManyToMany Cardinality = "many-to-many"
+)
+
+func (c Cardinality) valid() bool {
+	switch c {
+	case OneToOne, OneToMany, ManyToOne, ManyToMany:
+		return true
+	}
+
+	return false
+}
+
+// singleSource reports whether a source entity may have a single outgoing association.
+func (c Cardinality) singleSource() bool {
+	return c == OneToOne || c == ManyToOne
+}
+
+// singleTarget reports whether a target entity may have a single incoming association.
+func (c Cardinality) singleTarget() bool {
+	return c == OneToOne || c == OneToMany
+}
+
+// definitionIDPrefix starts the IDs of definitions, which share the store of associations.
+// Association IDs cannot start with it.
+const definitionIDPrefix = "atype:"
+
+func NewDefinitionID(atype string) model.ID {
+	return model.ID(fmt.Sprintf("%s%s", definitionIDPrefix, atype))
+}
+
+// reservedID reports whether an association may not use id, which is kept for definitions.
+func reservedID(id model.ID) bool {
+	return strings.HasPrefix(string(id), definitionIDPrefix)
+}
+
+// NewSourceSlotID returns the ID of the key holding the ID of the single association of type atype
+// leaving in, when its definition allows a single one.
+func NewSourceSlotID(in model.ID, atype string) model.ID {
+	return model.ID(fmt.Sprintf("slot:in:%s:%s", in, atype))
+}
+
+// NewTargetSlotID returns the ID of the key holding the ID of the single association of type atype
+// pointing to out, when its definition allows a single one.
+func NewTargetSlotID(out model.ID, atype string) model.ID {
+	return model.ID(fmt.Sprintf("slot:out:%s:%s", out, atype))
+}
+
+// Definition constrains the associations of a type. Associations of types without
+// a definition are accepted between any two entities.
+type Definition struct {
+	Cardinality Cardinality   `json:"cardinality"`
+	CreatedAt   *time.Time    `json:"created_at"`
+	DeletedAt   *time.Time    `json:"deleted_at,omitempty"`
+	ID          model.ID      `json:"id"`
+	SourceTypes []string      `json:"source_otypes"`
+	TargetTypes []string      `json:"target_otypes"`
+	TenantID    model.ID      `json:"tenant_id"`
+	Type        string        `json:"atype"`
+	UpdatedAt   *time.Time    `json:"updated_at"`
+	Version     model.Version `json:"version"`
+}
+
+type InsertDefinition struct {
+	model.CommandModel
+	Cardinality Cardinality `json:"cardinality"`
+	SourceTypes []string    `json:"source_otypes"`
+	TargetTypes []string    `json:"target_otypes"`
+	Type        string      `json:"atype" binding:"required"`
+}
+
+type UpdateDefinition struct {
+	model.CommandModel
+	Cardinality Cardinality `json:"cardinality"`
+	SourceTypes []string    `json:"source_otypes"`
+	TargetTypes []string    `json:"target_otypes"`
+}
+
+type DeleteDefinition struct {
+	model.CommandModel
+}
+
+type DefinitionInserted struct {
+	model.EventModel
+	Cardinality Cardinality `json:"cardinality"`
+	SourceTypes []string    `json:"source_otypes"`
+	TargetTypes []string    `json:"target_otypes"`
+	Type        string      `json:"atype"`
+}
+
+type DefinitionUpdated struct {
+	model.EventModel
+	Cardinality Cardinality `json:"cardinality"`
+	SourceTypes []string    `json:"source_otypes"`
+	TargetTypes []string    `json:"target_otypes"`
+}
+
+type DefinitionDeleted struct {
+	model.EventModel
+	DeletedAt *time.Time
+}
+
+func (d *Definition) On(event model.Event) error {
+	const op errors.Op = "graph/Definition.On"
+
+	switch v := event.(type) {
+	case *DefinitionInserted:
+		d.Cardinality = v.Cardinality
+		d.DeletedAt = nil
+		d.SourceTypes = v.SourceTypes
+		d.TargetTypes = v.TargetTypes
+		d.Type = v.Type
+	case *DefinitionUpdated:
+		d.Cardinality = v.Cardinality
+		d.SourceTypes = v.SourceTypes
+		d.TargetTypes = v.TargetTypes
+	case *DefinitionDeleted:
+		d.DeletedAt = v.DeletedAt
+	default:
+		return errors.E(op, errors.Internal, fmt.Errorf("invalid event %T", event))
+	}
+
+	d.ID = event.EventID()
+	d.TenantID = event.EventTenantID()
+	d.Version = event.EventVersion()
+
+	if d.CreatedAt == nil {
+		d.CreatedAt = event.EventAt()
+	}
+
+	d.UpdatedAt = event.EventAt()
+
+	return nil
+}
+
+func (d *Definition) Apply(ctx context.Context, cmd model.Command) ([]model.Event, error) {
+	const op errors.Op = "graph/Definition.Apply"
+
+	if cmd.CommandID() == "" {
+		return nil, errors.E(op, errors.Internal, "missing ID")
+	}
+
+	if cmd.CommandTenantID() == "" {
+		return nil, errors.E(op, errors.Internal, "missing tenant ID")
+	}
+
+	now := time.Now()
+	eventModel := model.EventModel{
+		ID:       cmd.CommandID(),
+		TenantID: cmd.CommandTenantID(),
+		Version:  d.Version + 1,
+		At:       &now,
+	}
+
+	var event model.Event
+	switch v := cmd.(type) {
+	case *InsertDefinition:
+		if v.Type == "" {
+			return nil, errors.E(op, errors.Invalid, "missing type")
+		}
+
+		cardinality, err := validCardinality(v.Cardinality)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		event = &DefinitionInserted{
+			EventModel:  eventModel,
+			Cardinality: cardinality,
+			SourceTypes: v.SourceTypes,
+			TargetTypes: v.TargetTypes,
+			Type:        v.Type,
+		}
+	case *UpdateDefinition:
+		cardinality, err := validCardinality(v.Cardinality)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		event = &DefinitionUpdated{
+			EventModel:  eventModel,
+			Cardinality: cardinality,
+			SourceTypes: v.SourceTypes,
+			TargetTypes: v.TargetTypes,
+		}
+	case *DeleteDefinition:
+		event = &DefinitionDeleted{
+			EventModel: eventModel,
+			DeletedAt:  &now,
+		}
+	default:
+		return nil, errors.E(op, errors.Internal, "unknown command")
+	}
+
+	return []model.Event{event}, nil
+}
+
+// validCardinality defaults an empty cardinality to ManyToMany.
+func validCardinality(c Cardinality) (Cardinality, error) {
+	if c == "" {
+		return ManyToMany, nil
+	}
+
+	if !c.valid() {
+		return "", errors.E(errors.Invalid, fmt.Sprintf("invalid cardinality %q", c))
+	}
+
+	return c, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptSource reports whether entities of type otype may be the source of associations of this type.
+func (d *Definition) acceptSource(otype string) bool {
+	return len(d.SourceTypes) == 0 || contains(d.SourceTypes, otype)
+}
+
+// acceptTarget reports whether entities of type otype may be the target of associations of this type.
+func (d *Definition) acceptTarget(otype string) bool {
+	return len(d.TargetTypes) == 0 || contains(d.TargetTypes, otype)
+}
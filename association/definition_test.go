@@ -0,0 +1,72 @@
+package association
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinition_Apply(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	repo := eventstore.NewRepository(&Definition{}, eventstore.NewInMemory(logger), NewDefinitionSerializer(), logger)
+
+	id := NewDefinitionID("primary_email")
+	cmd := model.CommandModel{ID: id, TenantID: "anonymous"}
+
+	_, err := repo.Apply(ctx, &InsertDefinition{
+		CommandModel: cmd,
+		Type:         "primary_email",
+		SourceTypes:  []string{"user"},
+		TargetTypes:  []string{"email"},
+		Cardinality:  OneToOne,
+	})
+	assert.Nil(t, err)
+
+	agg, err := repo.Load(ctx, id, "anonymous")
+	assert.Nil(t, err)
+
+	def := agg.(*Definition)
+	assert.Equal(t, "primary_email", def.Type)
+	assert.Equal(t, OneToOne, def.Cardinality)
+	assert.True(t, def.acceptSource("user"))
+	assert.False(t, def.acceptSource("group"))
+	assert.True(t, def.acceptTarget("email"))
+
+	_, err = repo.Apply(ctx, &UpdateDefinition{CommandModel: cmd})
+	assert.Nil(t, err)
+
+	agg, err = repo.Load(ctx, id, "anonymous")
+	assert.Nil(t, err)
+
+	def = agg.(*Definition)
+	assert.Equal(t, ManyToMany, def.Cardinality)
+	assert.True(t, def.acceptSource("group"))
+	assert.EqualValues(t, 2, def.Version)
+}
+
+func TestDefinition_Apply_InvalidCardinality(t *testing.T) {
+	def := &Definition{}
+	_, err := def.Apply(context.Background(), &InsertDefinition{
+		CommandModel: model.CommandModel{ID: NewDefinitionID("follows"), TenantID: "anonymous"},
+		Type:         "follows",
+		Cardinality:  "some-to-some",
+	})
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
+
+func TestCardinality(t *testing.T) {
+	assert.True(t, OneToOne.singleSource())
+	assert.True(t, OneToOne.singleTarget())
+	assert.False(t, OneToMany.singleSource())
+	assert.True(t, OneToMany.singleTarget())
+	assert.True(t, ManyToOne.singleSource())
+	assert.False(t, ManyToOne.singleTarget())
+	assert.False(t, ManyToMany.singleSource())
+	assert.False(t, ManyToMany.singleTarget())
+}
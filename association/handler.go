@@ -18,17 +18,7 @@ func NewSetAssociationToCacheHandler(assoc *Association, svc *Service) func() er
 
 func NewApplyAssociationHandler(cmd model.Command, svc *Service) func() error {
 	return func() error {
-		ctx := context.Background()
-		assoc, err := svc.applyAssociationToDatabase(ctx, cmd)
-		if err != nil {
-			return err
-		}
-
-		// Set aside cache
-		if err := svc.setAssociationToCache(ctx, assoc); err != nil {
-			return err
-		}
-
-		return nil
+		_, err := svc.applyAssociation(context.Background(), cmd)
+		return err
 	}
 }
@@ -2,13 +2,21 @@ package association
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"runtime"
+	"sync"
 	"time"
 
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/cache/rediscache"
 	"github.com/edgestore/edgestore/internal/errors"
 	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/limit"
 	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
 	"github.com/edgestore/edgestore/internal/worker"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -38,21 +46,50 @@ func NewSerializer() *eventstore.JSONSerializer {
 	return eventstore.NewJSONSerializer(events...)
 }
 
+func NewDefinitionSerializer() *eventstore.JSONSerializer {
+	events := []model.Event{
+		DefinitionDeleted{},
+		DefinitionInserted{},
+		DefinitionUpdated{},
+	}
+
+	return eventstore.NewJSONSerializer(events...)
+}
+
+// EntityGetter is implemented by entity.Service.
+type EntityGetter interface {
+	GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error)
+}
+
 type Service struct {
 	associations  *eventstore.Repository
+	authorizer    auth.Authorizer
 	cache         *redis.Client
 	cachePrefix   string
+	definitions   *eventstore.Repository
+	entities      EntityGetter
 	jobDispatcher *worker.Dispatcher
 	jobQueue      chan worker.Job
 	logger        logrus.FieldLogger
+	operations    *operation.Tracker
+	quotas        limit.Quotas
+
+	// backfilling holds a mutex per tenant, serializing the backfills of its inverse indexes.
+	backfilling sync.Map
 }
 
 type Config struct {
+	// Authorizer, when set, authorizes every call by the principal of its context.
+	Authorizer     auth.Authorizer
 	Cache          *redis.Client
 	CacheKeyPrefix string
+	Entities       EntityGetter
 	Logger         logrus.FieldLogger
 	Observers      []eventstore.Observer
-	Store          eventstore.Store
+	Operations     *operation.Tracker
+	// Quotas, when set, reserves the event bytes of every command before it is enqueued.
+	Quotas limit.Quotas
+	Store  eventstore.Store
 }
 
 func New(cfg *Config) *Service {
@@ -62,14 +99,46 @@ func New(cfg *Config) *Service {
 
 	return &Service{
 		associations:  eventstore.NewRepository(&Association{}, cfg.Store, NewSerializer(), cfg.Logger, cfg.Observers...),
+		authorizer:    cfg.Authorizer,
 		cache:         cfg.Cache,
 		cachePrefix:   cfg.CacheKeyPrefix,
+		definitions:   eventstore.NewRepository(&Definition{}, cfg.Store, NewDefinitionSerializer(), cfg.Logger),
+		entities:      cfg.Entities,
 		jobDispatcher: dispatcher,
 		jobQueue:      jobQueue,
 		logger:        cfg.Logger.WithField("component", "association-service"),
+		operations:    cfg.Operations,
+		quotas:        cfg.Quotas,
 	}
 }
 
+// authorize checks that the caller of ctx may apply verb to the associations, or definitions, of
+// type atype.
+func (s *Service) authorize(ctx context.Context, tenantID model.ID, resource string, verb string, atype string) error {
+	if s.authorizer == nil {
+		return nil
+	}
+
+	return s.authorizer.Authorize(ctx, tenantID, resource, verb, atype)
+}
+
+// readable removes from page the associations the caller of ctx may not read.
+func (s *Service) readable(ctx context.Context, page *model.Page[*Association]) *model.Page[*Association] {
+	if s.authorizer == nil {
+		return page
+	}
+
+	items := page.Items[:0]
+	for _, assoc := range page.Items {
+		if s.authorize(ctx, assoc.TenantID, auth.ResourceAssociations, auth.VerbRead, assoc.Type) == nil {
+			items = append(items, assoc)
+		}
+	}
+
+	page.Items = items
+	return page
+}
+
 func (s *Service) getAssociationFromCache(ctx context.Context, id model.ID, tenantID model.ID) (*Association, error) {
 	key := NewCacheKey(s.cachePrefix, id, tenantID)
 
@@ -98,19 +167,101 @@ func (s *Service) setAssociationToCache(ctx context.Context, assoc *Association)
 		return err
 	}
 
-	typeKey := NewCacheKey(s.cachePrefix, NewAssociationTypeID(assoc.In, assoc.Type), assoc.TenantID)
+	typeKeys := []string{
+		NewCacheKey(s.cachePrefix, NewAssociationTypeID(assoc.In, assoc.Type), assoc.TenantID),
+		NewCacheKey(s.cachePrefix, NewAssociationTypeID(assoc.In, AnyType), assoc.TenantID),
+		NewCacheKey(s.cachePrefix, NewInverseAssociationTypeID(assoc.Out, assoc.Type), assoc.TenantID),
+		NewCacheKey(s.cachePrefix, NewInverseAssociationTypeID(assoc.Out, AnyType), assoc.TenantID),
+	}
+
+	claimKeys := s.claimKeys(assoc.In, assoc.Type, assoc.Out, assoc.TenantID)
+	edgeKey, slotKeys := claimKeys[0], claimKeys[1:]
+
+	// Deleted associations are kept in cache but removed from the adjacency index, and release
+	// their edge and slots.
+	if assoc.DeletedAt != nil {
+		for _, typeKey := range typeKeys {
+			if _, err := s.cache.ZRem(ctx, typeKey, assocKey).Result(); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range claimKeys {
+			if err := dropClaimScript.Run(ctx, s.cache, []string{key}, string(assoc.ID)).Err(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if _, err := s.cache.Set(ctx, edgeKey, string(assoc.ID), DefaultExpiration).Result(); err != nil {
+		return err
+	}
+
+	// Applied associations keep the slots claimed while they were pending.
+	for _, key := range slotKeys {
+		if err := keepClaimScript.Run(ctx, s.cache, []string{key}, string(assoc.ID)).Err(); err != nil {
+			return err
+		}
+	}
+
 	z := redis.Z{
 		Member: assocKey,
 		Score:  float64(assoc.UpdatedAt.Unix()),
 	}
 
-	if _, err := s.cache.ZAdd(ctx, typeKey, z).Result(); err != nil {
-		return err
+	for _, typeKey := range typeKeys {
+		if _, err := s.cache.ZAdd(ctx, typeKey, z).Result(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// getAssociationsFromCache loads the associations referenced by an adjacency index key,
+// most recently updated first. Members that are no longer cached are skipped.
+func (s *Service) getAssociationsFromCache(ctx context.Context, typeKey string, p *model.Pagination) (*model.Page[*Association], error) {
+	keys, next, err := rediscache.RevRangeAfter(ctx, s.cache, typeKey, p)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, 0, len(keys))
+	pipe := s.cache.Pipeline()
+	for _, key := range keys {
+		cmds = append(cmds, pipe.HGetAll(ctx, key))
+	}
+
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	assocs := make([]*Association, 0, len(cmds))
+	for i, cmd := range cmds {
+		m := cmd.Val()
+		if len(m) == 0 {
+			continue
+		}
+
+		assoc, err := convertMapStringToAssociation(m)
+		if err != nil {
+			return nil, errors.E(err, errors.Internal, fmt.Sprintf("unable to parse cached association %s", keys[i]))
+		}
+
+		if assoc.DeletedAt != nil {
+			continue
+		}
+
+		assocs = append(assocs, assoc)
+	}
+
+	return model.NewPage(assocs, next), nil
+}
+
 func (s *Service) getAssociationFromDatabase(ctx context.Context, id model.ID, tenantID model.ID) (*Association, error) {
 	agg, err := s.associations.Load(ctx, id, tenantID)
 	if err != nil {
@@ -124,6 +275,7 @@ func (s *Service) applyAssociationToDatabase(ctx context.Context, cmd model.Comm
 	// Create new aggregate
 	if _, err := s.associations.Apply(ctx, cmd); err != nil {
 		s.logger.Error(err)
+		s.release(cmd)
 		return nil, err
 	}
 
@@ -136,10 +288,255 @@ func (s *Service) applyAssociationToDatabase(ctx context.Context, cmd model.Comm
 	return agg.(*Association), nil
 }
 
+// applyAssociation applies cmd and caches the resulting association.
+func (s *Service) applyAssociation(ctx context.Context, cmd model.Command) (*Association, error) {
+	assoc, err := s.applyAssociationToDatabase(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set aside cache
+	if err := s.setAssociationToCache(ctx, assoc); err != nil {
+		return nil, err
+	}
+
+	return assoc, nil
+}
+
+// usage returns the event bytes cmd takes.
+func usage(cmd model.Command) limit.Usage {
+	b, _ := json.Marshal(cmd)
+	return limit.Usage{EventBytes: int64(len(b))}
+}
+
+// reserve reserves the storage of cmd. Deletes are recorded unchecked, so that tenants over their
+// quota can still remove associations.
+func (s *Service) reserve(ctx context.Context, cmd model.Command) error {
+	if s.quotas == nil {
+		return nil
+	}
+
+	if _, ok := cmd.(*DeleteAssociation); ok {
+		return s.quotas.Record(ctx, cmd.CommandTenantID(), usage(cmd))
+	}
+
+	return s.quotas.Reserve(ctx, cmd.CommandTenantID(), usage(cmd))
+}
+
+// release undoes the reservation of a command which was not applied.
+func (s *Service) release(cmd model.Command) {
+	if s.quotas == nil {
+		return
+	}
+
+	if err := s.quotas.Record(context.Background(), cmd.CommandTenantID(), usage(cmd).Negate()); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// applyMode is how enqueue applies a command.
+type applyMode int
+
+const (
+	// applyAsync applies the command in the background, tracked by an operation.
+	applyAsync applyMode = iota
+
+	// applyAwait applies the command in the background and waits for it.
+	applyAwait
+
+	// applyInline applies the command in the calling goroutine, for imports which have their own
+	// concurrency.
+	applyInline
+)
+
+// enqueue applies cmd in the background and returns the operation tracking it. With applyAwait,
+// it blocks until cmd is applied and returns the resulting association instead, as applyInline does
+// without going through the job queue; when the wait ends first, it returns the operation along
+// with a PendingError.
+func (s *Service) enqueue(ctx context.Context, name string, cmd model.Command, mode applyMode) (*Association, *operation.Operation, error) {
+	if err := s.reserve(ctx, cmd); err != nil {
+		return nil, nil, err
+	}
+
+	switch mode {
+	case applyInline:
+		assoc, err := s.applyAssociation(ctx, cmd)
+		return assoc, nil, err
+	case applyAsync:
+		o, job, err := s.operations.Track(ctx, cmd.CommandTenantID(), worker.NewJob(name, NewApplyAssociationHandler(cmd, s)))
+		if err != nil {
+			s.release(cmd)
+			return nil, nil, err
+		}
+
+		s.jobQueue <- job
+		return nil, o, nil
+	}
+
+	// The command is tracked as asynchronous ones are, for the client to follow it when the wait
+	// ends before it is applied.
+	var assoc *Association
+	o, job, err := s.operations.Track(ctx, cmd.CommandTenantID(), worker.NewJob(name, func() (err error) {
+		assoc, err = s.applyAssociation(context.Background(), cmd)
+		return err
+	}))
+	if err != nil {
+		s.release(cmd)
+		return nil, nil, err
+	}
+
+	err = worker.Await(ctx, s.jobQueue, name, job.Do)
+	switch err {
+	case worker.ErrPending:
+		return nil, o, errors.E(errors.Transient, &operation.PendingError{Operation: o})
+	case context.DeadlineExceeded, context.Canceled:
+		// The command was never queued.
+		s.release(cmd)
+		return nil, nil, errors.E(errors.Transient, fmt.Sprintf("%s was not queued: %v", name, err))
+	}
+
+	return assoc, nil, err
+}
+
 func (s *Service) GetAssociation(ctx context.Context, id model.ID, tenantID model.ID) (*Association, error) {
 	const op errors.Op = "graph/Service.GetAssociation"
-	s.logger.Infof("%s: id=%s, tenant=%s", id, tenantID)
+	s.logger.Infof("%s: id=%s, tenant=%s", op, id, tenantID)
+
+	assoc, err := s.getAssociation(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, assoc.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return assoc, nil
+}
+
+// GetAssociations returns the associations with the given IDs, in the same order, fetching cached
+// associations in a single round trip and the others in a single query. Associations that do not
+// exist, and those the caller may not read, are returned as nil; the IDs of the latter are
+// returned as forbidden.
+func (s *Service) GetAssociations(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*Association, []model.ID, error) {
+	const op errors.Op = "graph/Service.GetAssociations"
+	s.logger.Infof("%s: ids=%d, tenant=%s", op, len(ids), tenantID)
+
+	if tenantID == "" {
+		return nil, nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	pipe := s.cache.Pipeline()
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, NewCacheKey(s.cachePrefix, id, tenantID)))
+	}
+
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, nil, errors.E(op, errors.IO, err)
+		}
+	}
+
+	assocs := make([]*Association, len(ids))
+	var misses []model.ID
+	for i, cmd := range cmds {
+		if m := cmd.Val(); len(m) > 0 {
+			assoc, err := convertMapStringToAssociation(m)
+			if err != nil {
+				return nil, nil, errors.E(op, errors.Internal, err)
+			}
+
+			assocs[i] = assoc
+			continue
+		}
+
+		if ids[i] != "" {
+			misses = append(misses, ids[i])
+		}
+	}
+
+	// Cache misses are loaded at once.
+	if len(misses) > 0 {
+		aggregates, err := s.associations.LoadAll(ctx, misses, tenantID)
+		if err != nil {
+			return nil, nil, errors.E(op, err)
+		}
+
+		for i, id := range ids {
+			if agg, ok := aggregates[id]; ok && assocs[i] == nil {
+				assocs[i] = agg.(*Association)
+			}
+		}
+
+		// Set aside cache
+		for _, agg := range aggregates {
+			assoc := agg.(*Association)
+			s.setAside(assoc)
+		}
+	}
+
+	var forbidden []model.ID
+	for i, assoc := range assocs {
+		if assoc == nil {
+			continue
+		}
+
+		if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, assoc.Type); err != nil {
+			if !errors.Is(errors.Permission, err) {
+				return nil, nil, errors.E(op, err)
+			}
+
+			assocs[i] = nil
+			forbidden = append(forbidden, ids[i])
+		}
+	}
+
+	return assocs, forbidden, nil
+}
+
+// GetAssociationAtLeast returns an association at version or later. The event store is read when
+// the cached association is older, or not cached yet.
+func (s *Service) GetAssociationAtLeast(ctx context.Context, id model.ID, tenantID model.ID, version model.Version) (*Association, error) {
+	const op errors.Op = "graph/Service.GetAssociationAtLeast"
 
+	cached, err := s.getAssociationFromCache(ctx, id, tenantID)
+	if err != nil && !errors.Is(errors.NotFound, err) {
+		return nil, errors.E(op, err)
+	}
+
+	assoc := cached
+	if assoc == nil || assoc.Version < version {
+		if assoc, err = s.getAssociationFromDatabase(ctx, id, tenantID); err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		if assoc.Version < version {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("association %s has no version %d", id, version))
+		}
+
+		s.setAside(assoc)
+	}
+
+	if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, assoc.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return assoc, nil
+}
+
+// setAside caches an association read from the event store in the background. Reads do not wait
+// for a full queue: the association is cached by a later read instead.
+func (s *Service) setAside(assoc *Association) {
+	job := worker.NewJob(fmt.Sprintf("set-association-cache-%s", assoc.ID), NewSetAssociationToCacheHandler(assoc, s))
+	select {
+	case s.jobQueue <- job:
+	default:
+	}
+}
+
+// getAssociation is GetAssociation without authorization.
+func (s *Service) getAssociation(ctx context.Context, id model.ID, tenantID model.ID) (*Association, error) {
 	cached, err := s.getAssociationFromCache(ctx, id, tenantID)
 	if err != nil && !errors.Is(errors.NotFound, err) {
 		return nil, err
@@ -156,52 +553,765 @@ func (s *Service) GetAssociation(ctx context.Context, id model.ID, tenantID mode
 	}
 
 	// Set aside cache
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("set-entity-cache-%s", assoc.ID), NewSetAssociationToCacheHandler(assoc, s))
+	s.setAside(assoc)
 
 	return assoc, nil
 }
 
-func (s *Service) CreateAssociation(ctx context.Context, cmd *InsertAssociation) error {
+// GetAssociationRevision returns the version and update time of an association. Cached associations are looked up
+// without decoding their data.
+func (s *Service) GetAssociationRevision(ctx context.Context, id model.ID, tenantID model.ID) (*model.Revision, error) {
+	const op errors.Op = "graph/Service.GetAssociationRevision"
+
+	key := NewCacheKey(s.cachePrefix, id, tenantID)
+	values, err := s.cache.HMGet(ctx, key, "version", "updated_at", "atype").Result()
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	if version, ok := values[0].(string); ok {
+		atype, _ := values[2].(string)
+		if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, atype); err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		updatedAt, _ := values[1].(string)
+		rev, err := model.ParseRevision(version, updatedAt)
+		if err != nil {
+			return nil, errors.E(op, errors.Internal, err)
+		}
+
+		return rev, nil
+	}
+
+	// Cache miss
+	assoc, err := s.GetAssociation(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Revision{Version: assoc.Version, UpdatedAt: assoc.UpdatedAt}, nil
+}
+
+// GetOutgoingAssociations returns the associations leaving the entity in, filtered by atype.
+// Associations of every type are returned when atype is empty.
+func (s *Service) GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*Association], error) {
+	const op errors.Op = "graph/Service.GetOutgoingAssociations"
+	s.logger.Infof("%s: in=%s, atype=%s, tenant=%s", op, in, atype, tenantID)
+
+	if in == "" {
+		return nil, errors.E(op, errors.Invalid, "ID is required")
+	}
+
+	if tenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if atype == "" {
+		atype = AnyType
+	} else if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, atype); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	typeKey := NewCacheKey(s.cachePrefix, NewAssociationTypeID(in, atype), tenantID)
+	page, err := s.getAssociationsFromCache(ctx, typeKey, p)
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	return s.readable(ctx, page), nil
+}
+
+// GetIncomingAssociations returns the associations pointing to the entity out, filtered by atype.
+// Associations of every type are returned when atype is empty.
+func (s *Service) GetIncomingAssociations(ctx context.Context, out model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*Association], error) {
+	const op errors.Op = "graph/Service.GetIncomingAssociations"
+	s.logger.Infof("%s: out=%s, atype=%s, tenant=%s", op, out, atype, tenantID)
+
+	if out == "" {
+		return nil, errors.E(op, errors.Invalid, "ID is required")
+	}
+
+	if tenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if atype == "" {
+		atype = AnyType
+	} else if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, atype); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if err := s.backfillInverse(ctx, tenantID); err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	typeKey := NewCacheKey(s.cachePrefix, NewInverseAssociationTypeID(out, atype), tenantID)
+	page, err := s.getAssociationsFromCache(ctx, typeKey, p)
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	return s.readable(ctx, page), nil
+}
+
+// backfillInverse indexes the live associations of the tenant by their target, once, as those
+// created before the inverse indexes were maintained are missing from them. Deleted associations
+// indexed meanwhile are skipped on read, their cached state being deleted.
+func (s *Service) backfillInverse(ctx context.Context, tenantID model.ID) error {
+	key := NewCacheKey(s.cachePrefix, NewInverseIndexedID(), tenantID)
+	indexed := func() (bool, error) {
+		n, err := s.cache.Exists(ctx, key).Result()
+		return n > 0, err
+	}
+
+	if ok, err := indexed(); err != nil || ok {
+		return err
+	}
+
+	mu, _ := s.backfilling.LoadOrStore(tenantID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	if ok, err := indexed(); err != nil || ok {
+		return err
+	}
+
+	s.logger.Infof("backfilling the inverse indexes of tenant %s", tenantID)
+	err := s.associations.Scan(ctx, tenantID, func(aggregate eventstore.Aggregate, history eventstore.History) error {
+		assoc := aggregate.(*Association)
+		if assoc.DeletedAt != nil {
+			return nil
+		}
+
+		z := redis.Z{
+			Member: NewCacheKey(s.cachePrefix, assoc.ID, assoc.TenantID),
+			Score:  float64(assoc.UpdatedAt.Unix()),
+		}
+
+		for _, atype := range []string{assoc.Type, AnyType} {
+			typeKey := NewCacheKey(s.cachePrefix, NewInverseAssociationTypeID(assoc.Out, atype), assoc.TenantID)
+			if err := s.cache.ZAddNX(ctx, typeKey, z).Err(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, key, time.Now().UTC().Format(time.RFC3339), DefaultExpiration).Err()
+}
+
+// GetAssociationHistory returns every event of an association, oldest first.
+func (s *Service) GetAssociationHistory(ctx context.Context, id model.ID, tenantID model.ID) ([]model.Event, error) {
+	const op errors.Op = "graph/Service.GetAssociationHistory"
+	s.logger.Infof("%s: id=%s, tenant=%s", op, id, tenantID)
+
+	if _, err := s.GetAssociation(ctx, id, tenantID); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	events, err := s.associations.History(ctx, id, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return events, nil
+}
+
+// CreateAssociation enqueues the creation of an association. The association ID is derived from
+// (in, atype, out) when omitted. Creating an association between two entities already linked by
+// the same type is rejected, unless cmd.OnDuplicate is UpsertDuplicate: the data of the existing
+// association is then updated when it differs. In both cases cmd.ID is set to the ID of the association.
+func (s *Service) CreateAssociation(ctx context.Context, cmd *InsertAssociation) (*operation.Operation, error) {
+	_, o, err := s.createAssociation(ctx, cmd, applyAsync)
+	return o, err
+}
+
+// CreateAssociationAndWait is CreateAssociation waiting for the association to be created or updated.
+func (s *Service) CreateAssociationAndWait(ctx context.Context, cmd *InsertAssociation) (*Association, error) {
+	res, _, err := s.createAssociation(ctx, cmd, applyAwait)
+	return res, err
+}
+
+func (s *Service) createAssociation(ctx context.Context, cmd *InsertAssociation, mode applyMode) (*Association, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.CreateAssociation"
-	s.logger.Infof("%s: tenant=%s in=%s, out=%s, %atype=%s", op, cmd.TenantID, cmd.In, cmd.Out, cmd.Type)
+	s.logger.Infof("%s: tenant=%s in=%s, out=%s, atype=%s", op, cmd.TenantID, cmd.In, cmd.Out, cmd.Type)
 
-	cmd.ID = NewAssociationID(cmd.In, cmd.Type, cmd.Out)
-	old, err := s.GetAssociation(ctx, cmd.ID, cmd.TenantID)
-	if err != nil && !errors.Is(errors.NotFound, err) {
+	if err := s.authorize(ctx, cmd.TenantID, auth.ResourceAssociations, auth.VerbWrite, cmd.Type); err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+
+	switch cmd.OnDuplicate {
+	case "", RejectDuplicate, UpsertDuplicate:
+	default:
+		return nil, nil, errors.E(op, errors.Invalid, fmt.Sprintf("invalid on_duplicate %q", cmd.OnDuplicate))
+	}
+
+	if cmd.ID == "" {
+		cmd.ID = NewAssociationID(cmd.In, cmd.Type, cmd.Out)
+	}
+
+	if reservedID(cmd.ID) {
+		return nil, nil, errors.E(op, errors.Invalid, fmt.Sprintf("association IDs cannot start with %q", definitionIDPrefix))
+	}
+
+	owner, err := s.claimEdge(ctx, cmd)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+
+	existing, err := s.getAliveAssociation(ctx, owner, cmd.TenantID)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+
+	// The ID may be taken by an association linking other entities.
+	if existing != nil && (existing.In != cmd.In || existing.Type != cmd.Type || existing.Out != cmd.Out) {
+		s.releaseClaims(ctx, cmd)
+		return nil, nil, errors.E(op, errors.Duplicate, fmt.Sprintf("association %s already exists", cmd.ID))
+	}
+
+	if existing != nil {
+		cmd.ID = existing.ID
+		if cmd.OnDuplicate != UpsertDuplicate {
+			return nil, nil, errors.E(op, errors.Duplicate, fmt.Sprintf("association %s already exists", existing.ID))
+		}
+
+		if reflect.DeepEqual(existing.Data, cmd.Data) {
+			return existing, nil, nil
+		}
+
+		update := &UpdateAssociation{CommandModel: cmd.CommandModel, Data: cmd.Data}
+		return s.enqueue(ctx, fmt.Sprintf("update-%s", cmd.ID), update, mode)
+	}
+
+	if err := s.checkDefinition(ctx, cmd); err != nil {
+		s.releaseClaims(ctx, cmd)
+		return nil, nil, errors.E(op, err)
+	}
+
+	assoc, o, err := s.enqueue(ctx, fmt.Sprintf("create-%s", cmd.ID), cmd, mode)
+	if err != nil && !operation.IsPending(err) {
+		s.releaseClaims(ctx, cmd)
+	}
+
+	return assoc, o, err
+}
+
+// getAliveAssociation returns nil when the association does not exist or was deleted.
+func (s *Service) getAliveAssociation(ctx context.Context, id model.ID, tenantID model.ID) (*Association, error) {
+	assoc, err := s.getAssociation(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(errors.NotFound, err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if assoc.DeletedAt != nil {
+		return nil, nil
+	}
+
+	return assoc, nil
+}
+
+// claimEdge atomically reserves the edge (in, atype, out) for cmd.ID and returns the ID
+// of the association owning the edge. Claims left by associations that were deleted or
+// never applied are taken over.
+func (s *Service) claimEdge(ctx context.Context, cmd *InsertAssociation) (model.ID, error) {
+	key := NewCacheKey(s.cachePrefix, NewEdgeID(cmd.In, cmd.Type, cmd.Out), cmd.TenantID)
+
+	claimed, err := s.cache.SetNX(ctx, key, string(cmd.ID), DefaultExpiration).Result()
+	if err != nil {
+		return "", errors.E(errors.IO, err)
+	}
+
+	if claimed {
+		// Associations created before edges were claimed use the derived ID.
+		derived := NewAssociationID(cmd.In, cmd.Type, cmd.Out)
+		if derived != cmd.ID {
+			if assoc, err := s.getAliveAssociation(ctx, derived, cmd.TenantID); err != nil {
+				return "", err
+			} else if assoc != nil {
+				return derived, s.cache.Set(ctx, key, string(derived), DefaultExpiration).Err()
+			}
+		}
+
+		return cmd.ID, nil
+	}
+
+	owner, err := s.cache.Get(ctx, key).Result()
+	if err != nil {
+		return "", errors.E(errors.IO, err)
+	}
+
+	if model.ID(owner) == cmd.ID {
+		return cmd.ID, nil
+	}
+
+	assoc, err := s.getAliveAssociation(ctx, model.ID(owner), cmd.TenantID)
+	if err != nil {
+		return "", err
+	}
+
+	if assoc != nil {
+		return assoc.ID, nil
+	}
+
+	if _, err := s.cache.Set(ctx, key, string(cmd.ID), DefaultExpiration).Result(); err != nil {
+		return "", errors.E(errors.IO, err)
+	}
+
+	return cmd.ID, nil
+}
+
+// releaseClaims removes the claims of cmd.ID over its edge and its slots, those it still holds.
+func (s *Service) releaseClaims(ctx context.Context, cmd *InsertAssociation) {
+	for _, key := range s.claimKeys(cmd.In, cmd.Type, cmd.Out, cmd.TenantID) {
+		if err := dropClaimScript.Run(ctx, s.cache, []string{key}, string(cmd.ID)).Err(); err != nil {
+			s.logger.Error(err)
+		}
+	}
+}
+
+// claimKeys returns the keys of the edge and of the slots an association may claim.
+func (s *Service) claimKeys(in model.ID, atype string, out model.ID, tenantID model.ID) []string {
+	return []string{
+		NewCacheKey(s.cachePrefix, NewEdgeID(in, atype, out), tenantID),
+		NewCacheKey(s.cachePrefix, NewSourceSlotID(in, atype), tenantID),
+		NewCacheKey(s.cachePrefix, NewTargetSlotID(out, atype), tenantID),
+	}
+}
+
+// PendingClaimTTL bounds the time an association not applied yet, such as one whose command was
+// lost with its master, holds the slots of its ends.
+var PendingClaimTTL = 10 * time.Minute
+
+// keepClaimScript makes the claim of ARGV[1] over KEYS[1] permanent, if it still holds it.
+var keepClaimScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PERSIST', KEYS[1])
+end
+return 0
+`)
+
+// swapClaimScript hands KEYS[1] over from ARGV[1] to ARGV[2] for ARGV[3] milliseconds, if ARGV[1]
+// still holds it.
+var swapClaimScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// dropClaimScript deletes KEYS[1] if ARGV[1] holds it.
+var dropClaimScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// claimSlot atomically reserves the slot key for cmd.ID, failing with Conflict while another
+// association, applied or pending, holds it. Slots of deleted associations are taken over. index is
+// the adjacency index of the slot, checked for associations created before slots were claimed.
+func (s *Service) claimSlot(ctx context.Context, key string, index string, cmd *InsertAssociation, conflict string) error {
+	claimed, err := s.cache.SetNX(ctx, key, string(cmd.ID), PendingClaimTTL).Result()
+	if err != nil {
+		return errors.E(errors.IO, err)
+	}
+
+	if claimed {
+		count, err := s.cache.ZCard(ctx, index).Result()
+		if err != nil {
+			return errors.E(errors.IO, err)
+		}
+
+		if count > 0 {
+			return errors.E(errors.Conflict, conflict)
+		}
+
+		return nil
+	}
+
+	owner, err := s.cache.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// The claim expired since SetNX.
+		return s.claimSlot(ctx, key, index, cmd, conflict)
+	}
+
+	if err != nil {
+		return errors.E(errors.IO, err)
+	}
+
+	if model.ID(owner) == cmd.ID {
+		return nil
+	}
+
+	// The owner is pending until it is found.
+	assoc, err := s.getAssociation(ctx, model.ID(owner), cmd.TenantID)
+	if err != nil {
+		if errors.Is(errors.NotFound, err) {
+			return errors.E(errors.Conflict, conflict)
+		}
 		return err
 	}
 
-	if old != nil {
-		return errors.E(op, errors.Duplicate, fmt.Sprintf("association %s already exists", cmd.ID))
+	if assoc.DeletedAt == nil {
+		return errors.E(errors.Conflict, conflict)
 	}
 
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("create-%s", cmd.ID), NewApplyAssociationHandler(cmd, s))
+	swapped, err := swapClaimScript.Run(ctx, s.cache, []string{key}, owner, string(cmd.ID), PendingClaimTTL.Milliseconds()).Int()
+	if err != nil {
+		return errors.E(errors.IO, err)
+	}
+
+	if swapped == 0 {
+		return errors.E(errors.Conflict, conflict)
+	}
 
 	return nil
 }
 
-func (s *Service) UpdateAssociation(ctx context.Context, cmd *UpdateAssociation) error {
+// authorizeAssociation checks that the caller of ctx may apply verb to an existing association.
+func (s *Service) authorizeAssociation(ctx context.Context, id model.ID, tenantID model.ID, verb string) error {
+	assoc, err := s.getAssociation(ctx, id, tenantID)
+	if err != nil {
+		return err
+	}
+
+	return s.authorize(ctx, tenantID, auth.ResourceAssociations, verb, assoc.Type)
+}
+
+func (s *Service) UpdateAssociation(ctx context.Context, cmd *UpdateAssociation) (*operation.Operation, error) {
+	_, o, err := s.updateAssociation(ctx, cmd, applyAsync)
+	return o, err
+}
+
+// UpdateAssociationAndWait is UpdateAssociation waiting for the association to be updated.
+func (s *Service) UpdateAssociationAndWait(ctx context.Context, cmd *UpdateAssociation) (*Association, error) {
+	res, _, err := s.updateAssociation(ctx, cmd, applyAwait)
+	return res, err
+}
+
+func (s *Service) updateAssociation(ctx context.Context, cmd *UpdateAssociation, mode applyMode) (*Association, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.UpdateAssociation"
 	s.logger.Infof("%s: id=%s, tenant=%s", op, cmd.ID, cmd.TenantID)
 
-	if _, err := s.GetAssociation(ctx, cmd.ID, cmd.TenantID); err != nil {
-		return err
+	if err := s.authorizeAssociation(ctx, cmd.ID, cmd.TenantID, auth.VerbWrite); err != nil {
+		return nil, nil, err
 	}
 
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("update-%s", cmd.ID), NewApplyAssociationHandler(cmd, s))
+	return s.enqueue(ctx, fmt.Sprintf("update-%s", cmd.ID), cmd, mode)
+}
 
-	return nil
+func (s *Service) DeleteAssociation(ctx context.Context, cmd *DeleteAssociation) (*operation.Operation, error) {
+	_, o, err := s.deleteAssociation(ctx, cmd, applyAsync)
+	return o, err
 }
 
-func (s *Service) DeleteAssociation(ctx context.Context, cmd *DeleteAssociation) error {
+// DeleteAssociationAndWait is DeleteAssociation waiting for the association to be deleted.
+func (s *Service) DeleteAssociationAndWait(ctx context.Context, cmd *DeleteAssociation) (*Association, error) {
+	res, _, err := s.deleteAssociation(ctx, cmd, applyAwait)
+	return res, err
+}
+
+func (s *Service) deleteAssociation(ctx context.Context, cmd *DeleteAssociation, mode applyMode) (*Association, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.DeleteAssociation"
 	s.logger.Infof("%s: id=%s, tenant=%s", op, cmd.ID, cmd.TenantID)
 
-	if _, err := s.GetAssociation(ctx, cmd.ID, cmd.TenantID); err != nil {
+	if err := s.authorizeAssociation(ctx, cmd.ID, cmd.TenantID, auth.VerbWrite); err != nil {
+		return nil, nil, err
+	}
+
+	return s.enqueue(ctx, fmt.Sprintf("delete-%s", cmd.ID), cmd, mode)
+}
+
+// checkDefinition enforces the definition registered for the association type, if any.
+func (s *Service) checkDefinition(ctx context.Context, cmd *InsertAssociation) error {
+	// The definition and the ends of the association are looked up on behalf of the service.
+	ctx = auth.Internal(ctx)
+
+	def, err := s.GetDefinition(ctx, cmd.Type, cmd.TenantID)
+	if err != nil {
+		if errors.Is(errors.NotFound, err) {
+			return nil
+		}
+
 		return err
 	}
 
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("delete-%s", cmd.ID), NewApplyAssociationHandler(cmd, s))
+	if len(def.SourceTypes) > 0 || len(def.TargetTypes) > 0 {
+		source, err := s.entities.GetEntity(ctx, cmd.In, cmd.TenantID)
+		if err != nil {
+			if errors.Is(errors.NotFound, err) {
+				return errors.E(errors.Invalid, fmt.Sprintf("source entity %s not found", cmd.In))
+			}
+			return err
+		}
+
+		if !def.acceptSource(source.Type) {
+			return errors.E(errors.Invalid, fmt.Sprintf("association type %s does not accept source entities of type %s", def.Type, source.Type))
+		}
+
+		target, err := s.entities.GetEntity(ctx, cmd.Out, cmd.TenantID)
+		if err != nil {
+			if errors.Is(errors.NotFound, err) {
+				return errors.E(errors.Invalid, fmt.Sprintf("target entity %s not found", cmd.Out))
+			}
+			return err
+		}
+
+		if !def.acceptTarget(target.Type) {
+			return errors.E(errors.Invalid, fmt.Sprintf("association type %s does not accept target entities of type %s", def.Type, target.Type))
+		}
+	}
+
+	// Single ends are claimed by the association, as its edge is, so that concurrent creations
+	// cannot both pass. The caller releases the claims when it fails.
+	if def.Cardinality.singleSource() {
+		key := NewCacheKey(s.cachePrefix, NewSourceSlotID(cmd.In, cmd.Type), cmd.TenantID)
+		index := NewCacheKey(s.cachePrefix, NewAssociationTypeID(cmd.In, cmd.Type), cmd.TenantID)
+		conflict := fmt.Sprintf("association type %s is %s and entity %s already has an outgoing association", def.Type, def.Cardinality, cmd.In)
+		if err := s.claimSlot(ctx, key, index, cmd, conflict); err != nil {
+			return err
+		}
+	}
+
+	if def.Cardinality.singleTarget() {
+		key := NewCacheKey(s.cachePrefix, NewTargetSlotID(cmd.Out, cmd.Type), cmd.TenantID)
+		index := NewCacheKey(s.cachePrefix, NewInverseAssociationTypeID(cmd.Out, cmd.Type), cmd.TenantID)
+		conflict := fmt.Sprintf("association type %s is %s and entity %s already has an incoming association", def.Type, def.Cardinality, cmd.Out)
+		if err := s.claimSlot(ctx, key, index, cmd, conflict); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// GetDefinition returns the definition of an association type. Deleted definitions are reported as not found.
+func (s *Service) GetDefinition(ctx context.Context, atype string, tenantID model.ID) (*Definition, error) {
+	const op errors.Op = "graph/Service.GetDefinition"
+
+	if err := s.authorize(ctx, tenantID, auth.ResourceDefinitions, auth.VerbRead, atype); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	agg, err := s.definitions.Load(ctx, NewDefinitionID(atype), tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	def := agg.(*Definition)
+	if def.DeletedAt != nil {
+		return nil, errors.E(op, errors.NotFound, fmt.Sprintf("association type %s not found", atype))
+	}
+
+	return def, nil
+}
+
+// applyDefinition applies a definition command synchronously, so that it is enforced
+// for every association created after it returns.
+func (s *Service) applyDefinition(ctx context.Context, cmd model.Command) (*Definition, error) {
+	if _, err := s.definitions.Apply(ctx, cmd); err != nil {
+		return nil, err
+	}
+
+	agg, err := s.definitions.Load(ctx, cmd.CommandID(), cmd.CommandTenantID())
+	if err != nil {
+		return nil, err
+	}
+
+	return agg.(*Definition), nil
+}
+
+func (s *Service) CreateDefinition(ctx context.Context, cmd *InsertDefinition) (*Definition, error) {
+	const op errors.Op = "graph/Service.CreateDefinition"
+	s.logger.Infof("%s: tenant=%s, atype=%s, cardinality=%s", op, cmd.TenantID, cmd.Type, cmd.Cardinality)
+
+	if cmd.Type == "" || cmd.Type == AnyType {
+		return nil, errors.E(op, errors.Invalid, "invalid type")
+	}
+
+	if err := s.authorize(ctx, cmd.TenantID, auth.ResourceDefinitions, auth.VerbWrite, cmd.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	cmd.ID = NewDefinitionID(cmd.Type)
+	if _, err := s.GetDefinition(auth.Internal(ctx), cmd.Type, cmd.TenantID); err == nil {
+		return nil, errors.E(op, errors.Duplicate, fmt.Sprintf("association type %s already exists", cmd.Type))
+	} else if !errors.Is(errors.NotFound, err) {
+		return nil, err
+	}
+
+	def, err := s.applyDefinition(ctx, cmd)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return def, nil
+}
+
+func (s *Service) UpdateDefinition(ctx context.Context, atype string, cmd *UpdateDefinition) (*Definition, error) {
+	const op errors.Op = "graph/Service.UpdateDefinition"
+	s.logger.Infof("%s: tenant=%s, atype=%s, cardinality=%s", op, cmd.TenantID, atype, cmd.Cardinality)
+
+	if err := s.authorize(ctx, cmd.TenantID, auth.ResourceDefinitions, auth.VerbWrite, atype); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if _, err := s.GetDefinition(auth.Internal(ctx), atype, cmd.TenantID); err != nil {
+		return nil, err
+	}
+
+	cmd.ID = NewDefinitionID(atype)
+	def, err := s.applyDefinition(ctx, cmd)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return def, nil
+}
+
+func (s *Service) DeleteDefinition(ctx context.Context, atype string, cmd *DeleteDefinition) error {
+	const op errors.Op = "graph/Service.DeleteDefinition"
+	s.logger.Infof("%s: tenant=%s, atype=%s", op, cmd.TenantID, atype)
+
+	if err := s.authorize(ctx, cmd.TenantID, auth.ResourceDefinitions, auth.VerbWrite, atype); err != nil {
+		return errors.E(op, err)
+	}
+
+	if _, err := s.GetDefinition(auth.Internal(ctx), atype, cmd.TenantID); err != nil {
+		return err
+	}
+
+	cmd.ID = NewDefinitionID(atype)
+	if _, err := s.applyDefinition(ctx, cmd); err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// ExportAssociations calls fn with every association of the tenant, deleted ones included, along
+// with its history. Associations of types the caller may not read are skipped.
+func (s *Service) ExportAssociations(ctx context.Context, tenantID model.ID, fn func(assoc *Association, history eventstore.History) error) error {
+	const op errors.Op = "graph/Service.ExportAssociations"
+	s.logger.Infof("%s: tenant=%s", op, tenantID)
+
+	if tenantID == "" {
+		return errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	readable := map[string]bool{}
+	err := s.associations.Scan(ctx, tenantID, func(aggregate eventstore.Aggregate, history eventstore.History) error {
+		assoc := aggregate.(*Association)
+		ok, seen := readable[assoc.Type]
+		if !seen {
+			ok = s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbRead, assoc.Type) == nil
+			readable[assoc.Type] = ok
+		}
+
+		if !ok {
+			return nil
+		}
+
+		return fn(assoc, history)
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// ImportAssociation is CreateAssociationAndWait applying the command in the calling goroutine, for
+// imports creating associations concurrently.
+func (s *Service) ImportAssociation(ctx context.Context, cmd *InsertAssociation) (*Association, error) {
+	res, _, err := s.createAssociation(ctx, cmd, applyInline)
+	return res, err
+}
+
+// ImportAssociationHistory creates an association from its history, such as one exported by
+// ExportAssociations. The events must belong to the association and the tenant.
+func (s *Service) ImportAssociationHistory(ctx context.Context, id model.ID, tenantID model.ID, history eventstore.History) (*Association, error) {
+	const op errors.Op = "graph/Service.ImportAssociationHistory"
+	s.logger.Infof("%s: id=%s, tenant=%s, events=%d", op, id, tenantID, len(history))
+
+	if id == "" {
+		return nil, errors.E(op, errors.Invalid, "ID is required")
+	}
+
+	if reservedID(id) {
+		return nil, errors.E(op, errors.Invalid, fmt.Sprintf("association IDs cannot start with %q", definitionIDPrefix))
+	}
+
+	verified, err := s.associations.Verify(id, tenantID, history)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	assoc := verified.(*Association)
+	if err := s.authorize(ctx, tenantID, auth.ResourceAssociations, auth.VerbWrite, assoc.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// The edge is claimed by the association when it is alive, as on creation.
+	cmd := &InsertAssociation{CommandModel: model.CommandModel{ID: id, TenantID: tenantID}, In: assoc.In, Out: assoc.Out, Type: assoc.Type}
+	if assoc.DeletedAt == nil {
+		owner, err := s.claimEdge(ctx, cmd)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		if owner != id {
+			return nil, errors.E(op, errors.Duplicate, fmt.Sprintf("association %s already links %s to %s", owner, assoc.In, assoc.Out))
+		}
+
+		if err := s.checkDefinition(ctx, cmd); err != nil {
+			s.releaseClaims(ctx, cmd)
+			return nil, errors.E(op, err)
+		}
+	}
+
+	u := limit.Usage{}
+	for _, record := range history {
+		u.EventBytes += int64(len(record.Data))
+	}
+
+	if s.quotas != nil {
+		if err := s.quotas.Reserve(ctx, tenantID, u); err != nil {
+			s.releaseClaims(ctx, cmd)
+			return nil, errors.E(op, err)
+		}
+	}
+
+	if _, err := s.associations.Import(ctx, id, tenantID, history); err != nil {
+		s.releaseClaims(ctx, cmd)
+		if s.quotas != nil {
+			if err := s.quotas.Record(context.Background(), tenantID, u.Negate()); err != nil {
+				s.logger.Error(err)
+			}
+		}
+
+		return nil, errors.E(op, err)
+	}
+
+	if err := s.setAssociationToCache(ctx, assoc); err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	return assoc, nil
+}
+
+// QueueLen returns the number of jobs waiting for a worker of the service and the capacity of
+// their queue, past which writes block.
+func (s *Service) QueueLen() (length int, capacity int) {
+	return len(s.jobQueue), cap(s.jobQueue)
+}
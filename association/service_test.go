@@ -0,0 +1,349 @@
+package association
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTenant = model.ID("anonymous")
+
+// stubEntities is an EntityGetter of the entities it maps to their type.
+type stubEntities map[model.ID]string
+
+func (e stubEntities) GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error) {
+	otype, ok := e[id]
+	if !ok {
+		return nil, errors.E(errors.NotFound, fmt.Sprintf("entity %s not found", id))
+	}
+
+	return &entity.Entity{ID: id, TenantID: tenantID, Type: otype}, nil
+}
+
+// denyTypes is an Authorizer denying access to the types it holds.
+type denyTypes map[string]bool
+
+func (d denyTypes) Authorize(ctx context.Context, tenantID model.ID, resource string, verb string, typ string) error {
+	if d[typ] {
+		return errors.E(errors.Permission, "no access to "+typ)
+	}
+
+	return nil
+}
+
+func newTestService(t *testing.T, entities stubEntities) (*Service, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cache.Close() })
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	return New(&Config{
+		Cache:    cache,
+		Entities: entities,
+		Logger:   logger,
+		Store:    eventstore.NewInMemory(logger),
+	}), mr
+}
+
+func link(in model.ID, atype string, out model.ID) *InsertAssociation {
+	return &InsertAssociation{CommandModel: model.CommandModel{TenantID: testTenant}, In: in, Out: out, Type: atype}
+}
+
+func TestService_CheckDefinition_Types(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{"alice": "user", "devs": "group", "alice@example.com": "email"})
+
+	_, err := svc.CreateDefinition(ctx, &InsertDefinition{
+		CommandModel: model.CommandModel{TenantID: testTenant},
+		Type:         "primary_email",
+		SourceTypes:  []string{"user"},
+		TargetTypes:  []string{"email"},
+		Cardinality:  ManyToMany,
+	})
+	require.Nil(t, err)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "primary_email", "alice@example.com"))
+	assert.Nil(t, err)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("devs", "primary_email", "alice@example.com"))
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "primary_email", "devs"))
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "primary_email", "bob@example.com"))
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	// Types without a definition accept any entities.
+	_, err = svc.CreateAssociationAndWait(ctx, link("devs", "follows", "alice@example.com"))
+	assert.Nil(t, err)
+}
+
+func TestService_CheckDefinition_Cardinality(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{})
+
+	_, err := svc.CreateDefinition(ctx, &InsertDefinition{
+		CommandModel: model.CommandModel{TenantID: testTenant},
+		Type:         "spouse",
+		Cardinality:  OneToOne,
+	})
+	require.Nil(t, err)
+
+	first, err := svc.CreateAssociationAndWait(ctx, link("alice", "spouse", "bob"))
+	require.Nil(t, err)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "spouse", "carol"))
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("dave", "spouse", "bob"))
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	// Rejected associations release their claims.
+	_, err = svc.CreateAssociationAndWait(ctx, link("dave", "spouse", "carol"))
+	assert.Nil(t, err)
+
+	// Deleted associations free their ends.
+	_, err = svc.DeleteAssociationAndWait(ctx, &DeleteAssociation{CommandModel: model.CommandModel{ID: first.ID, TenantID: testTenant}})
+	require.Nil(t, err)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "spouse", "erin"))
+	assert.Nil(t, err)
+}
+
+func TestService_CheckDefinition_PendingOwner(t *testing.T) {
+	ctx := context.Background()
+	svc, mr := newTestService(t, stubEntities{})
+
+	_, err := svc.CreateDefinition(ctx, &InsertDefinition{
+		CommandModel: model.CommandModel{TenantID: testTenant},
+		Type:         "manager",
+		Cardinality:  ManyToOne,
+	})
+	require.Nil(t, err)
+
+	// An association not applied yet holds the slot of its source.
+	key := NewCacheKey("", NewSourceSlotID("alice", "manager"), testTenant)
+	require.Nil(t, mr.Set(key, "pending"))
+	mr.SetTTL(key, PendingClaimTTL)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "manager", "bob"))
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	// Its claim expires when it is never applied.
+	mr.FastForward(PendingClaimTTL)
+
+	assoc, err := svc.CreateAssociationAndWait(ctx, link("alice", "manager", "bob"))
+	require.Nil(t, err)
+
+	// Applied associations hold their slots for good.
+	owner, err := mr.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, string(assoc.ID), owner)
+	assert.Zero(t, mr.TTL(key))
+}
+
+func TestService_CheckDefinition_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{})
+
+	_, err := svc.CreateDefinition(ctx, &InsertDefinition{
+		CommandModel: model.CommandModel{TenantID: testTenant},
+		Type:         "manager",
+		Cardinality:  ManyToOne,
+	})
+	require.Nil(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mux       sync.Mutex
+		created   int
+		conflicts int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.ImportAssociation(ctx, link("alice", "manager", model.ID(fmt.Sprintf("boss-%d", i))))
+
+			mux.Lock()
+			defer mux.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(errors.Conflict, err):
+				conflicts++
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 7, conflicts)
+}
+
+func TestService_CreateAssociation_ReservedID(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{})
+
+	cmd := link("alice", "follows", "bob")
+	cmd.ID = NewDefinitionID("follows")
+	_, err := svc.CreateAssociationAndWait(ctx, cmd)
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	// Derived IDs are reserved as well.
+	_, err = svc.CreateAssociationAndWait(ctx, link("atype", "follows", "bob"))
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = svc.ImportAssociationHistory(ctx, NewDefinitionID("follows"), testTenant, eventstore.History{})
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
+
+func TestService_CreateAssociation_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{})
+
+	// IDs are derived from the linked entities and the type.
+	cmd := link("alice", "follows", "bob")
+	cmd.Data = model.Data{"since": "2020"}
+	created, err := svc.CreateAssociationAndWait(ctx, cmd)
+	require.Nil(t, err)
+	assert.Equal(t, NewAssociationID("alice", "follows", "bob"), created.ID)
+	assert.Equal(t, created.ID, cmd.ID)
+
+	_, err = svc.CreateAssociationAndWait(ctx, link("alice", "follows", "bob"))
+	assert.True(t, errors.Is(errors.Duplicate, err))
+
+	cmd = link("alice", "follows", "bob")
+	cmd.OnDuplicate = RejectDuplicate
+	_, err = svc.CreateAssociationAndWait(ctx, cmd)
+	assert.True(t, errors.Is(errors.Duplicate, err))
+
+	// An explicit ID taken by an association linking other entities is rejected, even on upsert.
+	cmd = link("alice", "follows", "carol")
+	cmd.ID = created.ID
+	cmd.OnDuplicate = UpsertDuplicate
+	_, err = svc.CreateAssociationAndWait(ctx, cmd)
+	assert.True(t, errors.Is(errors.Duplicate, err))
+
+	cmd = link("alice", "follows", "bob")
+	cmd.OnDuplicate = "ignore"
+	_, err = svc.CreateAssociationAndWait(ctx, cmd)
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	// Upserts leave the association untouched when its data is unchanged.
+	cmd = link("alice", "follows", "bob")
+	cmd.Data = model.Data{"since": "2020"}
+	cmd.OnDuplicate = UpsertDuplicate
+	unchanged, err := svc.CreateAssociationAndWait(ctx, cmd)
+	require.Nil(t, err)
+	assert.Equal(t, created.Version, unchanged.Version)
+
+	cmd = link("alice", "follows", "bob")
+	cmd.Data = model.Data{"since": "2021"}
+	cmd.OnDuplicate = UpsertDuplicate
+	updated, err := svc.CreateAssociationAndWait(ctx, cmd)
+	require.Nil(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, created.Version+1, updated.Version)
+	assert.Equal(t, model.Data{"since": "2021"}, updated.Data)
+
+	got, err := svc.GetAssociation(ctx, created.ID, testTenant)
+	require.Nil(t, err)
+	assert.Equal(t, updated.Version, got.Version)
+	assert.Equal(t, model.Data{"since": "2021"}, got.Data)
+}
+
+func TestService_GetAssociations(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, stubEntities{})
+
+	var ids []model.ID
+	for _, l := range []*InsertAssociation{link("alice", "follows", "bob"), link("alice", "blocks", "carol")} {
+		assoc, err := svc.CreateAssociationAndWait(ctx, l)
+		require.Nil(t, err)
+		ids = append(ids, assoc.ID)
+	}
+
+	svc.authorizer = denyTypes{"blocks": true}
+
+	// Associations the caller may not read are returned as nil and reported, instead of failing
+	// the others.
+	assocs, forbidden, err := svc.GetAssociations(ctx, append(ids, "missing"), testTenant)
+	require.Nil(t, err)
+	require.Len(t, assocs, 3)
+	assert.Equal(t, ids[0], assocs[0].ID)
+	assert.Nil(t, assocs[1])
+	assert.Nil(t, assocs[2])
+	assert.Equal(t, []model.ID{ids[1]}, forbidden)
+}
+
+func TestService_GetIncomingAssociations(t *testing.T) {
+	ctx := context.Background()
+	svc, mr := newTestService(t, stubEntities{})
+
+	for _, l := range []*InsertAssociation{
+		link("alice", "follows", "carol"),
+		link("bob", "follows", "carol"),
+		link("bob", "likes", "carol"),
+		link("carol", "follows", "alice"),
+	} {
+		_, err := svc.CreateAssociationAndWait(ctx, l)
+		require.Nil(t, err)
+	}
+
+	deleted, err := svc.CreateAssociationAndWait(ctx, link("dave", "follows", "carol"))
+	require.Nil(t, err)
+	_, err = svc.DeleteAssociationAndWait(ctx, &DeleteAssociation{CommandModel: model.CommandModel{ID: deleted.ID, TenantID: testTenant}})
+	require.Nil(t, err)
+
+	incoming := func(atype string, limit int) []model.ID {
+		page, err := svc.GetIncomingAssociations(ctx, "carol", atype, testTenant, model.NewPagination(limit, nil))
+		require.Nil(t, err)
+
+		var ins []model.ID
+		for _, assoc := range page.Items {
+			assert.Equal(t, model.ID("carol"), assoc.Out)
+			ins = append(ins, assoc.In)
+		}
+
+		return ins
+	}
+
+	assert.ElementsMatch(t, []model.ID{"alice", "bob"}, incoming("follows", 10))
+	assert.ElementsMatch(t, []model.ID{"alice", "bob", "bob"}, incoming("", 10))
+	assert.Len(t, incoming("", 2), 2)
+
+	// Associations created before the inverse indexes were maintained are backfilled from the
+	// event store, once.
+	for _, key := range mr.Keys() {
+		if strings.Contains(key, "inverse:") || strings.Contains(key, string(NewInverseIndexedID())) {
+			mr.Del(key)
+		}
+	}
+
+	assert.ElementsMatch(t, []model.ID{"alice", "bob"}, incoming("follows", 10))
+	assert.ElementsMatch(t, []model.ID{"bob"}, incoming("likes", 10))
+	assert.True(t, mr.Exists(NewCacheKey("", NewInverseIndexedID(), testTenant)))
+
+	_, err = svc.GetIncomingAssociations(ctx, "", "", testTenant, model.NewPagination(10, nil))
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
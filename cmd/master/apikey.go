@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/master"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+// commandAPIKey manages API keys directly in the cache, to issue the first key of a tenant.
+func commandAPIKey() *cobra.Command {
+	var (
+		cache  string
+		name   string
+		roles  []string
+		tenant string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage the API keys of tenants",
+	}
+	cmd.PersistentFlags().StringVar(&cache, "cache", "localhost:6379", "Redis address")
+	cmd.PersistentFlags().StringVar(&tenant, "tenant", "", "Tenant ID")
+	cmd.MarkPersistentFlagRequired("tenant")
+
+	keys := func() *auth.Keys {
+		opts, err := newCacheOptions(cache)
+		if err != nil || opts == nil {
+			fmt.Fprintln(os.Stderr, "invalid cache address:", cache)
+			os.Exit(2)
+		}
+
+		return auth.NewKeys(auth.NewRedis(redis.NewClient(opts), master.CacheKeyPrefix))
+	}
+
+	output := func(v interface{}, err error) {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+	}
+
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Issue an API key and print its token",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(roles) == 0 {
+				roles = []string{auth.RoleAdmin}
+			}
+
+			output(keys().Create(context.Background(), model.ID(tenant), name, roles))
+		},
+	}
+	create.Flags().StringVar(&name, "name", "", "Holder of the key")
+	create.Flags().StringSliceVar(&roles, "role", nil, "Role granted to the key, admin when omitted")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the API keys of a tenant",
+		Run: func(cmd *cobra.Command, args []string) {
+			output(keys().List(context.Background(), model.ID(tenant)))
+		},
+	}
+
+	revoke := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			output(keys().Revoke(context.Background(), model.ID(args[0]), model.ID(tenant)))
+		},
+	}
+
+	cmd.AddCommand(create, list, revoke)
+	return cmd
+}
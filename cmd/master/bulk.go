@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/server"
+	"github.com/edgestore/edgestore/master"
+	"github.com/spf13/cobra"
+)
+
+// lineCounter counts the lines read or written through it, for progress reports.
+type lineCounter struct {
+	r     io.Reader
+	w     io.Writer
+	lines int64
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.lines, int64(bytes.Count(p[:n], []byte{'\n'})))
+	return n, err
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(&c.lines, int64(bytes.Count(p[:n], []byte{'\n'})))
+	return n, err
+}
+
+// report prints the rows counted by c to stderr every second, until the returned func is called.
+func report(verb string, c *lineCounter) func() {
+	start := time.Now()
+	print := func() {
+		rows := atomic.LoadInt64(&c.lines)
+		fmt.Fprintf(os.Stderr, "%s %d rows (%.0f rows/s)\n", verb, rows, float64(rows)/time.Since(start).Seconds())
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				print()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		print()
+	}
+}
+
+// commandBulk imports and exports the NDJSON rows of a tenant through the API of a master.
+func commandBulk() (*cobra.Command, *cobra.Command) {
+	var (
+		addr      string
+		apiKey    string
+		errorsOut string
+		file      string
+		history   bool
+		resources []string
+		tenant    string
+	)
+
+	request := func(method string, path string, query url.Values, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, strings.TrimSuffix(addr, "/")+master.Prefix+path+"?"+query.Encode(), body)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		req.Header.Set("Content-Type", master.NDJSONContentType)
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		if tenant != "" {
+			req.Header.Set(master.TenantHeader, tenant)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(res.Body)
+			fmt.Fprintf(os.Stderr, "%s %s: %s: %s\n", method, path, res.Status, bytes.TrimSpace(msg))
+			os.Exit(1)
+		}
+
+		return res
+	}
+
+	flags := func(cmd *cobra.Command) {
+		cmd.Flags().StringVar(&addr, "url", "http://localhost:8080", "Address of the master")
+		cmd.Flags().StringVar(&apiKey, "api-key", "", "API key of the tenant")
+		cmd.Flags().StringVar(&tenant, "tenant", "", "Tenant ID, when the master trusts the tenant header")
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import NDJSON entities and associations into a tenant",
+		Run: func(cmd *cobra.Command, args []string) {
+			in := io.Reader(os.Stdin)
+			if file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			c := &lineCounter{r: in}
+			stop := report("sent", c)
+			res := request(http.MethodPost, "/import", nil, c)
+			defer res.Body.Close()
+
+			var result master.ImportResult
+			err := json.NewDecoder(res.Body).Decode(&result)
+			stop()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			if errorsOut != "" && len(result.Errors) > 0 {
+				f, err := os.Create(errorsOut)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+
+				enc := json.NewEncoder(f)
+				for _, e := range result.Errors {
+					enc.Encode(e)
+				}
+				f.Close()
+			}
+
+			fmt.Fprintf(os.Stderr, "processed %d rows: %d imported, %d failed\n", result.Processed, result.Imported, result.Failed)
+			if result.Truncated {
+				fmt.Fprintf(os.Stderr, "only the first %d failed rows were returned\n", len(result.Errors))
+			}
+			if result.Failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	flags(importCmd)
+	importCmd.Flags().StringVar(&file, "file", "-", "NDJSON file to import, - for stdin")
+	importCmd.Flags().StringVar(&errorsOut, "errors", "", "NDJSON file receiving the rows that failed to import")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the entities and associations of a tenant as NDJSON",
+		Run: func(cmd *cobra.Command, args []string) {
+			out := io.Writer(os.Stdout)
+			if file != "-" {
+				f, err := os.Create(file)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			query := url.Values{}
+			if history {
+				query.Set("history", "true")
+			}
+			if len(resources) > 0 {
+				query.Set("resources", strings.Join(resources, ","))
+			}
+
+			res := request(http.MethodGet, "/export", query, nil)
+			defer res.Body.Close()
+
+			// Rows are checked for the error ending exports failing midway.
+			c := &lineCounter{w: out}
+			stop := report("exported", c)
+			dec := json.NewDecoder(res.Body)
+			enc := json.NewEncoder(c)
+			var err error
+			for dec.More() {
+				var row json.RawMessage
+				if err = dec.Decode(&row); err != nil {
+					break
+				}
+
+				var failure struct {
+					Error *server.Problem `json:"error"`
+				}
+				if json.Unmarshal(row, &failure) == nil && failure.Error != nil {
+					err = fmt.Errorf("%s: %s", failure.Error.Title, failure.Error.Detail)
+					break
+				}
+
+				if err = enc.Encode(row); err != nil {
+					break
+				}
+			}
+			stop()
+
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "export failed:", err)
+				os.Exit(1)
+			}
+		},
+	}
+	flags(exportCmd)
+	exportCmd.Flags().StringVar(&file, "output", "-", "NDJSON file receiving the export, - for stdout")
+	exportCmd.Flags().BoolVar(&history, "history", false, "Export the events of every resource, deleted ones included")
+	exportCmd.Flags().StringSliceVar(&resources, "resources", nil, "Resources to export, entities or associations")
+
+	return importCmd, exportCmd
+}
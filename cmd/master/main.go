@@ -26,6 +26,8 @@ func commandRoot() *cobra.Command {
 	viper.SetEnvPrefix("edgestore_master")
 	viper.AutomaticEnv()
 
+	rootCmd.AddCommand(commandAPIKey())
+	rootCmd.AddCommand(commandBulk())
 	rootCmd.AddCommand(commandServe())
 	rootCmd.AddCommand(version.NewCommand(LongDescription))
 
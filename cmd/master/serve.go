@@ -5,8 +5,11 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/edgestore/edgestore/internal/auth"
 	"github.com/edgestore/edgestore/internal/guid"
+	"github.com/edgestore/edgestore/internal/limit"
 	"github.com/edgestore/edgestore/internal/server"
 	"github.com/edgestore/edgestore/master"
 	"github.com/go-pg/pg/v10"
@@ -17,15 +20,30 @@ import (
 
 func commandServe() *cobra.Command {
 	var (
-		cache     string
-		database  string
-		logFormat string
-		logLevel  string
-		port      int
+		adminPort    int
+		cache        string
+		cursorSecret string
+		database     string
+		enablePProf  bool
+		insecure     bool
+		keyRetention time.Duration
+		jwks         []string
+		jwtAudience  string
+		jwtIssuer    string
+		limits       string
+		limitsStore  string
+		rolesClaim   string
+		tenantClaim  string
+		logFormat    string
+		logLevel     string
+		policyTTL    time.Duration
+		port         int
+		rpcPort      int
+		retention    time.Duration
 	)
 	cmd := cobra.Command{
 		Use:     "serve",
-		Short:   "Start HTTP server",
+		Short:   "Start HTTP and gRPC servers",
 		Example: ShortDescription,
 		Run: func(cmd *cobra.Command, args []string) {
 			var db *pg.Options
@@ -45,19 +63,10 @@ func commandServe() *cobra.Command {
 				}
 			}
 
-			var cacheOpts *redis.Options
-			if viper.GetString("cache") != "" {
-				conn, err := url.Parse(viper.GetString("cache"))
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					os.Exit(2)
-				}
-
-				pwd, _ := conn.User.Password()
-				cacheOpts = &redis.Options{
-					Addr:     conn.Host,
-					Password: pwd,
-				}
+			cacheOpts, err := newCacheOptions(viper.GetString("cache"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
 			}
 
 			machineID, err := guid.DefaultMachineID()
@@ -74,6 +83,40 @@ func commandServe() *cobra.Command {
 			}
 
 			cfg.Server.HTTPPort = viper.GetInt("port")
+			cfg.Server.RPCPort = viper.GetInt("rpc_port")
+			cfg.Server.AdminPort = viper.GetInt("admin_port")
+			cfg.Server.EnablePProf = viper.GetBool("enable_pprof")
+			cfg.OperationRetention = viper.GetDuration("operation_retention")
+			cfg.CursorSecret = viper.GetString("cursor_secret")
+			cfg.IdempotencyRetention = viper.GetDuration("idempotency_retention")
+			cfg.Auth = master.AuthConfig{
+				JWKS:                 viper.GetStringSlice("jwks"),
+				Issuer:               viper.GetString("jwt_issuer"),
+				Audience:             viper.GetString("jwt_audience"),
+				TenantClaim:          viper.GetString("jwt_tenant_claim"),
+				RolesClaim:           viper.GetString("jwt_roles_claim"),
+				PolicyTTL:            viper.GetDuration("policy_ttl"),
+				InsecureTenantHeader: viper.GetBool("insecure_tenant_header"),
+			}
+			if path := viper.GetString("limits"); path != "" {
+				table, err := limit.LoadTable(path)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+
+				cfg.Limits.Table = table
+			}
+
+			switch viper.GetString("limits_store") {
+			case "memory":
+			case "redis":
+				cfg.Limits.Shared = true
+			default:
+				fmt.Fprintf(os.Stderr, "invalid limits store %q\n", viper.GetString("limits_store"))
+				os.Exit(2)
+			}
+
 			cfg.Server.LoggerFormat = viper.GetString("log_format")
 			cfg.Server.LoggerLevel = viper.GetString("log_level")
 
@@ -84,21 +127,66 @@ func commandServe() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().IntVar(&adminPort, "admin-port", 0, "Port of the debug endpoints, not served when 0")
+	viper.BindPFlag("admin_port", cmd.Flags().Lookup("admin-port"))
+
 	cmd.Flags().StringVar(&cache, "cache", "localhost:6379", "Redis address")
 	viper.BindPFlag("cache", cmd.Flags().Lookup("cache"))
 
+	cmd.Flags().StringVar(&cursorSecret, "cursor-secret", "", "Secret signing pagination cursors and consistency tokens, shared by every node (random when empty)")
+	viper.BindPFlag("cursor_secret", cmd.Flags().Lookup("cursor-secret"))
+
 	cmd.Flags().StringVar(&database, "database", "", "Database connection string")
 	viper.BindPFlag("database", cmd.Flags().Lookup("database"))
 
+	cmd.Flags().BoolVar(&enablePProf, "enable-pprof", false, "Serve pprof profiles under /debug/pprof on the admin port")
+	viper.BindPFlag("enable_pprof", cmd.Flags().Lookup("enable-pprof"))
+
+	cmd.Flags().DurationVar(&keyRetention, "idempotency-retention", 24*time.Hour, "Retention of idempotency keys and their responses")
+	viper.BindPFlag("idempotency_retention", cmd.Flags().Lookup("idempotency-retention"))
+
+	cmd.Flags().BoolVar(&insecure, "insecure-tenant-header", false, "Trust the Edgestore-Tenant header of requests without credentials (development only)")
+	viper.BindPFlag("insecure_tenant_header", cmd.Flags().Lookup("insecure-tenant-header"))
+
+	cmd.Flags().StringSliceVar(&jwks, "jwks", nil, "JSON Web Key Set files of the keys signing accepted JWTs")
+	viper.BindPFlag("jwks", cmd.Flags().Lookup("jwks"))
+
+	cmd.Flags().StringVar(&jwtAudience, "jwt-audience", "", "Required audience of JWTs")
+	viper.BindPFlag("jwt_audience", cmd.Flags().Lookup("jwt-audience"))
+
+	cmd.Flags().StringVar(&jwtIssuer, "jwt-issuer", "", "Required issuer of JWTs")
+	viper.BindPFlag("jwt_issuer", cmd.Flags().Lookup("jwt-issuer"))
+
+	cmd.Flags().StringVar(&rolesClaim, "jwt-roles-claim", auth.DefaultRolesClaim, "JWT claim holding the roles")
+	viper.BindPFlag("jwt_roles_claim", cmd.Flags().Lookup("jwt-roles-claim"))
+
+	cmd.Flags().StringVar(&tenantClaim, "jwt-tenant-claim", auth.DefaultTenantClaim, "JWT claim holding the tenant")
+	viper.BindPFlag("jwt_tenant_claim", cmd.Flags().Lookup("jwt-tenant-claim"))
+
+	cmd.Flags().StringVar(&limits, "limits", "", "JSON file of the rate limits and quotas of tenants, read at startup (unlimited when empty)")
+	viper.BindPFlag("limits", cmd.Flags().Lookup("limits"))
+
+	cmd.Flags().StringVar(&limitsStore, "limits-store", "memory", "Store of rate limit buckets and usage: memory, or redis to share them between masters")
+	viper.BindPFlag("limits_store", cmd.Flags().Lookup("limits-store"))
+
 	cmd.Flags().StringVar(&logFormat, "log-format", "json", "Logger format")
 	viper.BindPFlag("log_format", cmd.Flags().Lookup("log-format"))
 
 	cmd.Flags().StringVar(&logLevel, "log-level", "info", "Logger level")
 	viper.BindPFlag("log_level", cmd.Flags().Lookup("log-level"))
 
+	cmd.Flags().DurationVar(&policyTTL, "policy-ttl", auth.DefaultPolicyTTL, "How long the policies of a tenant are cached")
+	viper.BindPFlag("policy_ttl", cmd.Flags().Lookup("policy-ttl"))
+
 	cmd.Flags().IntVar(&port, "port", 8080, "HTTP port")
 	viper.BindPFlag("port", cmd.Flags().Lookup("port"))
 
+	cmd.Flags().IntVar(&rpcPort, "rpc-port", 8081, "gRPC port")
+	viper.BindPFlag("rpc_port", cmd.Flags().Lookup("rpc-port"))
+
+	cmd.Flags().DurationVar(&retention, "operation-retention", 24*time.Hour, "Retention of asynchronous operations")
+	viper.BindPFlag("operation_retention", cmd.Flags().Lookup("operation-retention"))
+
 	return &cmd
 }
 
@@ -106,3 +194,21 @@ func serve(cfg master.Config) error {
 	svc := master.New(cfg)
 	return svc.Run()
 }
+
+// newCacheOptions parses the Redis connection string of the cache flag.
+func newCacheOptions(cache string) (*redis.Options, error) {
+	if cache == "" {
+		return nil, nil
+	}
+
+	conn, err := url.Parse(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	pwd, _ := conn.User.Password()
+	return &redis.Options{
+		Addr:     conn.Host,
+		Password: pwd,
+	}, nil
+}
@@ -0,0 +1,535 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: edgestorepb/edgestore.proto
+
+package edgestorepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Edgestore_GetEntity_FullMethodName                = "/edgestore.v1.Edgestore/GetEntity"
+	Edgestore_CreateEntity_FullMethodName             = "/edgestore.v1.Edgestore/CreateEntity"
+	Edgestore_UpdateEntity_FullMethodName             = "/edgestore.v1.Edgestore/UpdateEntity"
+	Edgestore_DeleteEntity_FullMethodName             = "/edgestore.v1.Edgestore/DeleteEntity"
+	Edgestore_StreamEntityHistory_FullMethodName      = "/edgestore.v1.Edgestore/StreamEntityHistory"
+	Edgestore_GetAssociation_FullMethodName           = "/edgestore.v1.Edgestore/GetAssociation"
+	Edgestore_CreateAssociation_FullMethodName        = "/edgestore.v1.Edgestore/CreateAssociation"
+	Edgestore_UpdateAssociation_FullMethodName        = "/edgestore.v1.Edgestore/UpdateAssociation"
+	Edgestore_DeleteAssociation_FullMethodName        = "/edgestore.v1.Edgestore/DeleteAssociation"
+	Edgestore_StreamAssociationHistory_FullMethodName = "/edgestore.v1.Edgestore/StreamAssociationHistory"
+	Edgestore_CreateGUID_FullMethodName               = "/edgestore.v1.Edgestore/CreateGUID"
+)
+
+// EdgestoreClient is the client API for Edgestore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EdgestoreClient interface {
+	GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*Entity, error)
+	CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	UpdateEntity(ctx context.Context, in *UpdateEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	StreamEntityHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (Edgestore_StreamEntityHistoryClient, error)
+	GetAssociation(ctx context.Context, in *GetAssociationRequest, opts ...grpc.CallOption) (*Association, error)
+	CreateAssociation(ctx context.Context, in *CreateAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	UpdateAssociation(ctx context.Context, in *UpdateAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	DeleteAssociation(ctx context.Context, in *DeleteAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	StreamAssociationHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (Edgestore_StreamAssociationHistoryClient, error)
+	CreateGUID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GUID, error)
+}
+
+type edgestoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEdgestoreClient(cc grpc.ClientConnInterface) EdgestoreClient {
+	return &edgestoreClient{cc}
+}
+
+func (c *edgestoreClient) GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*Entity, error) {
+	out := new(Entity)
+	err := c.cc.Invoke(ctx, Edgestore_GetEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_CreateEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) UpdateEntity(ctx context.Context, in *UpdateEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_UpdateEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_DeleteEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) StreamEntityHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (Edgestore_StreamEntityHistoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Edgestore_ServiceDesc.Streams[0], Edgestore_StreamEntityHistory_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &edgestoreStreamEntityHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Edgestore_StreamEntityHistoryClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type edgestoreStreamEntityHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *edgestoreStreamEntityHistoryClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *edgestoreClient) GetAssociation(ctx context.Context, in *GetAssociationRequest, opts ...grpc.CallOption) (*Association, error) {
+	out := new(Association)
+	err := c.cc.Invoke(ctx, Edgestore_GetAssociation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) CreateAssociation(ctx context.Context, in *CreateAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_CreateAssociation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) UpdateAssociation(ctx context.Context, in *UpdateAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_UpdateAssociation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) DeleteAssociation(ctx context.Context, in *DeleteAssociationRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, Edgestore_DeleteAssociation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *edgestoreClient) StreamAssociationHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (Edgestore_StreamAssociationHistoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Edgestore_ServiceDesc.Streams[1], Edgestore_StreamAssociationHistory_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &edgestoreStreamAssociationHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Edgestore_StreamAssociationHistoryClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type edgestoreStreamAssociationHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *edgestoreStreamAssociationHistoryClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *edgestoreClient) CreateGUID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GUID, error) {
+	out := new(GUID)
+	err := c.cc.Invoke(ctx, Edgestore_CreateGUID_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EdgestoreServer is the server API for Edgestore service.
+// All implementations must embed UnimplementedEdgestoreServer
+// for forward compatibility
+type EdgestoreServer interface {
+	GetEntity(context.Context, *GetEntityRequest) (*Entity, error)
+	CreateEntity(context.Context, *CreateEntityRequest) (*WriteResponse, error)
+	UpdateEntity(context.Context, *UpdateEntityRequest) (*WriteResponse, error)
+	DeleteEntity(context.Context, *DeleteEntityRequest) (*WriteResponse, error)
+	StreamEntityHistory(*HistoryRequest, Edgestore_StreamEntityHistoryServer) error
+	GetAssociation(context.Context, *GetAssociationRequest) (*Association, error)
+	CreateAssociation(context.Context, *CreateAssociationRequest) (*WriteResponse, error)
+	UpdateAssociation(context.Context, *UpdateAssociationRequest) (*WriteResponse, error)
+	DeleteAssociation(context.Context, *DeleteAssociationRequest) (*WriteResponse, error)
+	StreamAssociationHistory(*HistoryRequest, Edgestore_StreamAssociationHistoryServer) error
+	CreateGUID(context.Context, *emptypb.Empty) (*GUID, error)
+	mustEmbedUnimplementedEdgestoreServer()
+}
+
+// UnimplementedEdgestoreServer must be embedded to have forward compatible implementations.
+type UnimplementedEdgestoreServer struct {
+}
+
+func (UnimplementedEdgestoreServer) GetEntity(context.Context, *GetEntityRequest) (*Entity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntity not implemented")
+}
+func (UnimplementedEdgestoreServer) CreateEntity(context.Context, *CreateEntityRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEntity not implemented")
+}
+func (UnimplementedEdgestoreServer) UpdateEntity(context.Context, *UpdateEntityRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateEntity not implemented")
+}
+func (UnimplementedEdgestoreServer) DeleteEntity(context.Context, *DeleteEntityRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEntity not implemented")
+}
+func (UnimplementedEdgestoreServer) StreamEntityHistory(*HistoryRequest, Edgestore_StreamEntityHistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEntityHistory not implemented")
+}
+func (UnimplementedEdgestoreServer) GetAssociation(context.Context, *GetAssociationRequest) (*Association, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAssociation not implemented")
+}
+func (UnimplementedEdgestoreServer) CreateAssociation(context.Context, *CreateAssociationRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAssociation not implemented")
+}
+func (UnimplementedEdgestoreServer) UpdateAssociation(context.Context, *UpdateAssociationRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAssociation not implemented")
+}
+func (UnimplementedEdgestoreServer) DeleteAssociation(context.Context, *DeleteAssociationRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAssociation not implemented")
+}
+func (UnimplementedEdgestoreServer) StreamAssociationHistory(*HistoryRequest, Edgestore_StreamAssociationHistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamAssociationHistory not implemented")
+}
+func (UnimplementedEdgestoreServer) CreateGUID(context.Context, *emptypb.Empty) (*GUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateGUID not implemented")
+}
+func (UnimplementedEdgestoreServer) mustEmbedUnimplementedEdgestoreServer() {}
+
+// UnsafeEdgestoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EdgestoreServer will
+// result in compilation errors.
+type UnsafeEdgestoreServer interface {
+	mustEmbedUnimplementedEdgestoreServer()
+}
+
+func RegisterEdgestoreServer(s grpc.ServiceRegistrar, srv EdgestoreServer) {
+	s.RegisterService(&Edgestore_ServiceDesc, srv)
+}
+
+func _Edgestore_GetEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).GetEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_GetEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).GetEntity(ctx, req.(*GetEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_CreateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).CreateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_CreateEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).CreateEntity(ctx, req.(*CreateEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_UpdateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).UpdateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_UpdateEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).UpdateEntity(ctx, req.(*UpdateEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_DeleteEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).DeleteEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_DeleteEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).DeleteEntity(ctx, req.(*DeleteEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_StreamEntityHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EdgestoreServer).StreamEntityHistory(m, &edgestoreStreamEntityHistoryServer{stream})
+}
+
+type Edgestore_StreamEntityHistoryServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type edgestoreStreamEntityHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *edgestoreStreamEntityHistoryServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Edgestore_GetAssociation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAssociationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).GetAssociation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_GetAssociation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).GetAssociation(ctx, req.(*GetAssociationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_CreateAssociation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAssociationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).CreateAssociation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_CreateAssociation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).CreateAssociation(ctx, req.(*CreateAssociationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_UpdateAssociation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAssociationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).UpdateAssociation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_UpdateAssociation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).UpdateAssociation(ctx, req.(*UpdateAssociationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_DeleteAssociation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAssociationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).DeleteAssociation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_DeleteAssociation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).DeleteAssociation(ctx, req.(*DeleteAssociationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Edgestore_StreamAssociationHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EdgestoreServer).StreamAssociationHistory(m, &edgestoreStreamAssociationHistoryServer{stream})
+}
+
+type Edgestore_StreamAssociationHistoryServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type edgestoreStreamAssociationHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *edgestoreStreamAssociationHistoryServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Edgestore_CreateGUID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EdgestoreServer).CreateGUID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Edgestore_CreateGUID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EdgestoreServer).CreateGUID(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Edgestore_ServiceDesc is the grpc.ServiceDesc for Edgestore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Edgestore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "edgestore.v1.Edgestore",
+	HandlerType: (*EdgestoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetEntity",
+			Handler:    _Edgestore_GetEntity_Handler,
+		},
+		{
+			MethodName: "CreateEntity",
+			Handler:    _Edgestore_CreateEntity_Handler,
+		},
+		{
+			MethodName: "UpdateEntity",
+			Handler:    _Edgestore_UpdateEntity_Handler,
+		},
+		{
+			MethodName: "DeleteEntity",
+			Handler:    _Edgestore_DeleteEntity_Handler,
+		},
+		{
+			MethodName: "GetAssociation",
+			Handler:    _Edgestore_GetAssociation_Handler,
+		},
+		{
+			MethodName: "CreateAssociation",
+			Handler:    _Edgestore_CreateAssociation_Handler,
+		},
+		{
+			MethodName: "UpdateAssociation",
+			Handler:    _Edgestore_UpdateAssociation_Handler,
+		},
+		{
+			MethodName: "DeleteAssociation",
+			Handler:    _Edgestore_DeleteAssociation_Handler,
+		},
+		{
+			MethodName: "CreateGUID",
+			Handler:    _Edgestore_CreateGUID_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEntityHistory",
+			Handler:       _Edgestore_StreamEntityHistory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamAssociationHistory",
+			Handler:       _Edgestore_StreamAssociationHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "edgestorepb/edgestore.proto",
+}
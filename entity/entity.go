@@ -12,6 +12,17 @@ import (
 	"github.com/edgestore/edgestore/internal/errors"
 )
 
+// NewEntityTypeID returns the ID of the index of entities of a given type.
+func NewEntityTypeID(otype string) model.ID {
+	return model.ID(fmt.Sprintf("otype:%s", otype))
+}
+
+// NewTypeIndexedID returns the ID of the key marking the type indexes of a tenant as backfilled
+// from the event store.
+func NewTypeIndexedID() model.ID {
+	return model.ID("backfill:otype")
+}
+
 type Entity struct {
 	CreatedAt *time.Time    `json:"created_at"`
 	DeletedAt *time.Time    `json:"deleted_at,omitempty"`
@@ -47,11 +58,13 @@ type EntityInserted struct {
 type EntityUpdated struct {
 	model.EventModel
 	Data model.Data `json:"data"`
+	Type string     `json:"otype,omitempty"`
 }
 
 type EntityDeleted struct {
 	model.EventModel
 	DeletedAt *time.Time
+	Type      string `json:"otype,omitempty"`
 }
 
 func (e *Entity) On(event model.Event) error {
@@ -153,12 +166,17 @@ func (e *Entity) applyUpdate(cmd *UpdateEntity) (model.Event, error) {
 			At:       &now,
 		},
 		Data: cmd.Data,
+		Type: e.Type,
 	}
 
 	return updated, nil
 }
 
 func (e *Entity) applyDelete(cmd *DeleteEntity) (model.Event, error) {
+	if e.DeletedAt != nil {
+		return nil, errors.E(errors.NotFound, fmt.Sprintf("entity %s is deleted", cmd.CommandID()))
+	}
+
 	now := time.Now()
 	deleted := &EntityDeleted{
 		EventModel: model.EventModel{
@@ -168,6 +186,7 @@ func (e *Entity) applyDelete(cmd *DeleteEntity) (model.Event, error) {
 			At:       &now,
 		},
 		DeletedAt: &now,
+		Type:      e.Type,
 	}
 
 	return deleted, nil
@@ -18,17 +18,7 @@ func NewSetEntityToCacheHandler(entity *Entity, svc *Service) func() error {
 
 func NewApplyEntityHandler(cmd model.Command, svc *Service) func() error {
 	return func() error {
-		ctx := context.Background()
-		entity, err := svc.applyEntityToDatabase(ctx, cmd)
-		if err != nil {
-			return err
-		}
-
-		// Set aside cache
-		if err := svc.setEntityToCache(ctx, entity); err != nil {
-			return err
-		}
-
-		return nil
+		_, err := svc.applyEntity(context.Background(), cmd)
+		return err
 	}
 }
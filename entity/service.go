@@ -2,13 +2,19 @@ package entity
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"runtime"
+	"sync"
 	"time"
 
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/cache/rediscache"
 	"github.com/edgestore/edgestore/internal/errors"
 	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/limit"
 	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
 	"github.com/edgestore/edgestore/internal/worker"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -39,20 +45,31 @@ func NewSerializer() *eventstore.JSONSerializer {
 }
 
 type Service struct {
+	authorizer    auth.Authorizer
 	cache         *redis.Client
 	cachePrefix   string
 	entities      *eventstore.Repository
 	jobDispatcher *worker.Dispatcher
 	jobQueue      chan worker.Job
 	logger        logrus.FieldLogger
+	operations    *operation.Tracker
+	quotas        limit.Quotas
+
+	// backfilling holds a mutex per tenant, serializing the backfills of its type indexes.
+	backfilling sync.Map
 }
 
 type Config struct {
+	// Authorizer, when set, authorizes every call by the principal of its context.
+	Authorizer     auth.Authorizer
 	Cache          *redis.Client
 	CacheKeyPrefix string
 	Logger         logrus.FieldLogger
 	Observers      []eventstore.Observer
-	Store          eventstore.Store
+	Operations     *operation.Tracker
+	// Quotas, when set, reserves the storage of every command before it is enqueued.
+	Quotas limit.Quotas
+	Store  eventstore.Store
 }
 
 func New(cfg *Config) *Service {
@@ -61,13 +78,25 @@ func New(cfg *Config) *Service {
 	dispatcher.Run()
 
 	return &Service{
+		authorizer:    cfg.Authorizer,
 		cache:         cfg.Cache,
 		cachePrefix:   cfg.CacheKeyPrefix,
 		entities:      eventstore.NewRepository(&Entity{}, cfg.Store, NewSerializer(), cfg.Logger, cfg.Observers...),
 		jobDispatcher: dispatcher,
 		jobQueue:      jobQueue,
 		logger:        cfg.Logger.WithField("component", "entity-service"),
+		operations:    cfg.Operations,
+		quotas:        cfg.Quotas,
+	}
+}
+
+// authorize checks that the caller of ctx may apply verb to the entities of type otype.
+func (s *Service) authorize(ctx context.Context, tenantID model.ID, verb string, otype string) error {
+	if s.authorizer == nil {
+		return nil
 	}
+
+	return s.authorizer.Authorize(ctx, tenantID, auth.ResourceEntities, verb, otype)
 }
 
 func (s *Service) getEntityFromCache(ctx context.Context, id model.ID, tenantID model.ID) (*Entity, error) {
@@ -99,9 +128,70 @@ func (s *Service) setEntityToCache(ctx context.Context, entity *Entity) error {
 		return err
 	}
 
+	// Deleted entities are kept in cache but removed from the type index.
+	typeKey := NewCacheKey(s.cachePrefix, NewEntityTypeID(entity.Type), entity.TenantID)
+	if entity.DeletedAt != nil {
+		if _, err := s.cache.ZRem(ctx, typeKey, key).Result(); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	z := redis.Z{
+		Member: key,
+		Score:  float64(entity.UpdatedAt.Unix()),
+	}
+
+	if _, err := s.cache.ZAdd(ctx, typeKey, z).Result(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// getEntitiesFromCache loads the entities referenced by an index key, most recently updated first.
+// Members that are no longer cached are skipped.
+func (s *Service) getEntitiesFromCache(ctx context.Context, indexKey string, p *model.Pagination) (*model.Page[*Entity], error) {
+	keys, next, err := rediscache.RevRangeAfter(ctx, s.cache, indexKey, p)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, 0, len(keys))
+	pipe := s.cache.Pipeline()
+	for _, key := range keys {
+		cmds = append(cmds, pipe.HGetAll(ctx, key))
+	}
+
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	entities := make([]*Entity, 0, len(cmds))
+	for i, cmd := range cmds {
+		m := cmd.Val()
+		if len(m) == 0 {
+			continue
+		}
+
+		entity, err := convertMapStringToEntity(m)
+		if err != nil {
+			return nil, errors.E(err, errors.Internal, fmt.Sprintf("unable to parse cached entity %s", keys[i]))
+		}
+
+		if entity.DeletedAt != nil {
+			continue
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return model.NewPage(entities, next), nil
+}
+
 func (s *Service) getEntityFromDatabase(ctx context.Context, id model.ID, tenantID model.ID) (*Entity, error) {
 	agg, err := s.entities.Load(ctx, id, tenantID)
 	if err != nil {
@@ -111,10 +201,27 @@ func (s *Service) getEntityFromDatabase(ctx context.Context, id model.ID, tenant
 	return agg.(*Entity), err
 }
 
+// getEntitiesFromDatabase loads the entities with the given IDs by ID, in a single query when the
+// event store allows it.
+func (s *Service) getEntitiesFromDatabase(ctx context.Context, ids []model.ID, tenantID model.ID) (map[model.ID]*Entity, error) {
+	aggregates, err := s.entities.LoadAll(ctx, ids, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make(map[model.ID]*Entity, len(aggregates))
+	for id, agg := range aggregates {
+		entities[id] = agg.(*Entity)
+	}
+
+	return entities, nil
+}
+
 func (s *Service) applyEntityToDatabase(ctx context.Context, cmd model.Command) (*Entity, error) {
 	// Create new aggregate
 	if _, err := s.entities.Apply(ctx, cmd); err != nil {
 		s.logger.Error(err)
+		s.release(cmd)
 		return nil, err
 	}
 
@@ -127,10 +234,39 @@ func (s *Service) applyEntityToDatabase(ctx context.Context, cmd model.Command)
 	return agg.(*Entity), nil
 }
 
+// applyEntity applies cmd and caches the resulting entity.
+func (s *Service) applyEntity(ctx context.Context, cmd model.Command) (*Entity, error) {
+	entity, err := s.applyEntityToDatabase(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set aside cache
+	if err := s.setEntityToCache(ctx, entity); err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
 func (s *Service) GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*Entity, error) {
 	const op errors.Op = "graph/Service.GetEntity"
 	s.logger.Infof("%s: id=%s, tenant=%s", op, id, tenantID)
 
+	entity, err := s.getEntity(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, tenantID, auth.VerbRead, entity.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return entity, nil
+}
+
+// getEntity is GetEntity without authorization.
+func (s *Service) getEntity(ctx context.Context, id model.ID, tenantID model.ID) (*Entity, error) {
 	if id == "" {
 		return nil, errors.E(errors.Invalid, "ID is required")
 	}
@@ -154,55 +290,536 @@ func (s *Service) GetEntity(ctx context.Context, id model.ID, tenantID model.ID)
 		return nil, err
 	}
 
-	// Set aside cache
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("set-entity-cache-%s", NewCacheKey(s.cachePrefix, entity.ID, entity.TenantID)), NewSetEntityToCacheHandler(entity, s))
-
+	s.setAside(entity)
 	return entity, nil
 }
 
-func (s *Service) CreateEntity(ctx context.Context, cmd *InsertEntity) error {
+// setAside caches an entity read from the event store in the background. Reads do not wait for a
+// full queue: the entity is cached by a later read instead.
+func (s *Service) setAside(entity *Entity) {
+	job := worker.NewJob(fmt.Sprintf("set-entity-cache-%s", NewCacheKey(s.cachePrefix, entity.ID, entity.TenantID)), NewSetEntityToCacheHandler(entity, s))
+	select {
+	case s.jobQueue <- job:
+	default:
+	}
+}
+
+// GetEntityRevision returns the version and update time of an entity. Cached entities are looked up
+// without decoding their data.
+func (s *Service) GetEntityRevision(ctx context.Context, id model.ID, tenantID model.ID) (*model.Revision, error) {
+	const op errors.Op = "graph/Service.GetEntityRevision"
+
+	key := NewCacheKey(s.cachePrefix, id, tenantID)
+	values, err := s.cache.HMGet(ctx, key, "version", "updated_at", "otype").Result()
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	if version, ok := values[0].(string); ok {
+		otype, _ := values[2].(string)
+		if err := s.authorize(ctx, tenantID, auth.VerbRead, otype); err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		updatedAt, _ := values[1].(string)
+		rev, err := model.ParseRevision(version, updatedAt)
+		if err != nil {
+			return nil, errors.E(op, errors.Internal, err)
+		}
+
+		return rev, nil
+	}
+
+	// Cache miss
+	entity, err := s.GetEntity(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Revision{Version: entity.Version, UpdatedAt: entity.UpdatedAt}, nil
+}
+
+// GetEntities returns the entities with the given IDs, in the same order, fetching cached entities in a single
+// round trip. Entities that do not exist, and those the caller may not read, are returned as nil; the IDs of the
+// latter are returned as forbidden.
+func (s *Service) GetEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*Entity, []model.ID, error) {
+	const op errors.Op = "graph/Service.GetEntities"
+	s.logger.Infof("%s: ids=%d, tenant=%s", op, len(ids), tenantID)
+
+	entities, err := s.getEntities(ctx, ids, tenantID)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+
+	var forbidden []model.ID
+	for i, entity := range entities {
+		if entity == nil {
+			continue
+		}
+
+		if err := s.authorize(ctx, tenantID, auth.VerbRead, entity.Type); err != nil {
+			if !errors.Is(errors.Permission, err) {
+				return nil, nil, errors.E(op, err)
+			}
+
+			entities[i] = nil
+			forbidden = append(forbidden, ids[i])
+		}
+	}
+
+	return entities, forbidden, nil
+}
+
+// GetReadableEntities is GetEntities without the IDs of the entities the caller may not read.
+func (s *Service) GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*Entity, error) {
+	entities, _, err := s.GetEntities(ctx, ids, tenantID)
+	return entities, err
+}
+
+// getEntities is GetEntities without authorization.
+func (s *Service) getEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*Entity, error) {
+	if tenantID == "" {
+		return nil, errors.E(errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	pipe := s.cache.Pipeline()
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, NewCacheKey(s.cachePrefix, id, tenantID)))
+	}
+
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, errors.E(errors.IO, err)
+		}
+	}
+
+	entities := make([]*Entity, len(ids))
+	var misses []model.ID
+	for i, cmd := range cmds {
+		if m := cmd.Val(); len(m) > 0 {
+			entity, err := convertMapStringToEntity(m)
+			if err != nil {
+				return nil, errors.E(errors.Internal, err)
+			}
+
+			entities[i] = entity
+			continue
+		}
+
+		if ids[i] != "" {
+			misses = append(misses, ids[i])
+		}
+	}
+
+	// Cache misses are loaded at once.
+	if len(misses) > 0 {
+		loaded, err := s.getEntitiesFromDatabase(ctx, misses, tenantID)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, id := range ids {
+			if entities[i] == nil {
+				entities[i] = loaded[id]
+			}
+		}
+
+		// Set aside cache
+		for _, entity := range loaded {
+			s.setAside(entity)
+		}
+	}
+
+	return entities, nil
+}
+
+// GetEntitiesByType returns the entities of a given type, most recently updated first.
+func (s *Service) GetEntitiesByType(ctx context.Context, otype string, tenantID model.ID, p *model.Pagination) (*model.Page[*Entity], error) {
+	const op errors.Op = "graph/Service.GetEntitiesByType"
+	s.logger.Infof("%s: otype=%s, tenant=%s", op, otype, tenantID)
+
+	if otype == "" {
+		return nil, errors.E(op, errors.Invalid, "type is required")
+	}
+
+	if tenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if err := s.authorize(ctx, tenantID, auth.VerbRead, otype); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if err := s.backfillTypes(ctx, tenantID); err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	typeKey := NewCacheKey(s.cachePrefix, NewEntityTypeID(otype), tenantID)
+	page, err := s.getEntitiesFromCache(ctx, typeKey, p)
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	return page, nil
+}
+
+// backfillTypes indexes the live entities of the tenant by their type, once, as those created
+// before the type indexes were maintained are missing from them. Deleted entities indexed
+// meanwhile are skipped on read, their cached state being deleted.
+func (s *Service) backfillTypes(ctx context.Context, tenantID model.ID) error {
+	key := NewCacheKey(s.cachePrefix, NewTypeIndexedID(), tenantID)
+	indexed := func() (bool, error) {
+		n, err := s.cache.Exists(ctx, key).Result()
+		return n > 0, err
+	}
+
+	if ok, err := indexed(); err != nil || ok {
+		return err
+	}
+
+	mu, _ := s.backfilling.LoadOrStore(tenantID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	if ok, err := indexed(); err != nil || ok {
+		return err
+	}
+
+	s.logger.Infof("backfilling the type indexes of tenant %s", tenantID)
+	err := s.entities.Scan(ctx, tenantID, func(aggregate eventstore.Aggregate, history eventstore.History) error {
+		entity := aggregate.(*Entity)
+		if entity.DeletedAt != nil {
+			return nil
+		}
+
+		z := redis.Z{
+			Member: NewCacheKey(s.cachePrefix, entity.ID, entity.TenantID),
+			Score:  float64(entity.UpdatedAt.Unix()),
+		}
+
+		typeKey := NewCacheKey(s.cachePrefix, NewEntityTypeID(entity.Type), entity.TenantID)
+		return s.cache.ZAddNX(ctx, typeKey, z).Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, key, time.Now().UTC().Format(time.RFC3339), DefaultExpiration).Err()
+}
+
+// GetEntityHistory returns every event of an entity, oldest first.
+func (s *Service) GetEntityHistory(ctx context.Context, id model.ID, tenantID model.ID) ([]model.Event, error) {
+	const op errors.Op = "graph/Service.GetEntityHistory"
+	s.logger.Infof("%s: id=%s, tenant=%s", op, id, tenantID)
+
+	if _, err := s.GetEntity(ctx, id, tenantID); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	events, err := s.entities.History(ctx, id, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return events, nil
+}
+
+// authorizeEntity checks that the caller of ctx may apply verb to an existing entity.
+func (s *Service) authorizeEntity(ctx context.Context, id model.ID, tenantID model.ID, verb string) error {
+	entity, err := s.getEntity(ctx, id, tenantID)
+	if err != nil {
+		return err
+	}
+
+	return s.authorize(ctx, tenantID, verb, entity.Type)
+}
+
+// usage returns the storage cmd takes: its event and, for creates and deletes, an entity.
+func usage(cmd model.Command) limit.Usage {
+	b, _ := json.Marshal(cmd)
+	u := limit.Usage{EventBytes: int64(len(b))}
+	switch cmd.(type) {
+	case *InsertEntity:
+		u.Entities = 1
+	case *DeleteEntity:
+		u.Entities = -1
+	}
+
+	return u
+}
+
+// reserve reserves the storage of cmd. Deletes are recorded unchecked, so that tenants over their
+// quota can still free entities.
+func (s *Service) reserve(ctx context.Context, cmd model.Command) error {
+	if s.quotas == nil {
+		return nil
+	}
+
+	u := usage(cmd)
+	if u.Entities < 0 {
+		return s.quotas.Record(ctx, cmd.CommandTenantID(), u)
+	}
+
+	return s.quotas.Reserve(ctx, cmd.CommandTenantID(), u)
+}
+
+// release undoes the reservation of a command which was not applied.
+func (s *Service) release(cmd model.Command) {
+	if s.quotas == nil {
+		return
+	}
+
+	if err := s.quotas.Record(context.Background(), cmd.CommandTenantID(), usage(cmd).Negate()); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// applyMode is how enqueue applies a command.
+type applyMode int
+
+const (
+	// applyAsync applies the command in the background, tracked by an operation.
+	applyAsync applyMode = iota
+
+	// applyAwait applies the command in the background and waits for it.
+	applyAwait
+
+	// applyInline applies the command in the calling goroutine, for imports which have their own
+	// concurrency.
+	applyInline
+)
+
+// enqueue applies cmd in the background and returns the operation tracking it. With applyAwait,
+// it blocks until cmd is applied and returns the resulting entity instead, as applyInline does
+// without going through the job queue; when the wait ends first, it returns the operation along
+// with a PendingError.
+func (s *Service) enqueue(ctx context.Context, name string, cmd model.Command, mode applyMode) (*Entity, *operation.Operation, error) {
+	if err := s.reserve(ctx, cmd); err != nil {
+		return nil, nil, err
+	}
+
+	switch mode {
+	case applyInline:
+		entity, err := s.applyEntity(ctx, cmd)
+		return entity, nil, err
+	case applyAsync:
+		o, job, err := s.operations.Track(ctx, cmd.CommandTenantID(), worker.NewJob(name, NewApplyEntityHandler(cmd, s)))
+		if err != nil {
+			s.release(cmd)
+			return nil, nil, err
+		}
+
+		s.jobQueue <- job
+		return nil, o, nil
+	}
+
+	// The command is tracked as asynchronous ones are, for the client to follow it when the wait
+	// ends before it is applied.
+	var entity *Entity
+	o, job, err := s.operations.Track(ctx, cmd.CommandTenantID(), worker.NewJob(name, func() (err error) {
+		entity, err = s.applyEntity(context.Background(), cmd)
+		return err
+	}))
+	if err != nil {
+		s.release(cmd)
+		return nil, nil, err
+	}
+
+	err = worker.Await(ctx, s.jobQueue, name, job.Do)
+	switch err {
+	case worker.ErrPending:
+		return nil, o, errors.E(errors.Transient, &operation.PendingError{Operation: o})
+	case context.DeadlineExceeded, context.Canceled:
+		// The command was never queued.
+		s.release(cmd)
+		return nil, nil, errors.E(errors.Transient, fmt.Sprintf("%s was not queued: %v", name, err))
+	}
+
+	return entity, nil, err
+}
+
+func (s *Service) CreateEntity(ctx context.Context, cmd *InsertEntity) (*operation.Operation, error) {
+	_, o, err := s.createEntity(ctx, cmd, applyAsync)
+	return o, err
+}
+
+// CreateEntityAndWait is CreateEntity waiting for the entity to be created.
+func (s *Service) CreateEntityAndWait(ctx context.Context, cmd *InsertEntity) (*Entity, error) {
+	res, _, err := s.createEntity(ctx, cmd, applyAwait)
+	return res, err
+}
+
+func (s *Service) createEntity(ctx context.Context, cmd *InsertEntity, mode applyMode) (*Entity, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.CreateEntity"
 	s.logger.Infof("%s: id=%s, tenant=%s, type=%s", op, cmd.ID, cmd.TenantID, cmd.Type)
 
-	old, err := s.GetEntity(ctx, cmd.ID, cmd.TenantID)
+	if err := s.authorize(ctx, cmd.TenantID, auth.VerbWrite, cmd.Type); err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+
+	old, err := s.getEntity(ctx, cmd.ID, cmd.TenantID)
 	if err != nil && !errors.Is(errors.NotFound, err) {
-		return err
+		return nil, nil, err
 	}
 
 	key := NewCacheKey(s.cachePrefix, cmd.ID, cmd.TenantID)
 	if old != nil {
-		return errors.E(op, errors.Duplicate, fmt.Sprintf("entity %s already exists", key))
+		return nil, nil, errors.E(op, errors.Duplicate, fmt.Sprintf("entity %s already exists", key))
 	}
 
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("create-%s", key), NewApplyEntityHandler(cmd, s))
+	return s.enqueue(ctx, fmt.Sprintf("create-%s", key), cmd, mode)
+}
 
-	return nil
+func (s *Service) UpdateEntity(ctx context.Context, cmd *UpdateEntity) (*operation.Operation, error) {
+	_, o, err := s.updateEntity(ctx, cmd, applyAsync)
+	return o, err
 }
 
-func (s *Service) UpdateEntity(ctx context.Context, cmd *UpdateEntity) error {
+// UpdateEntityAndWait is UpdateEntity waiting for the entity to be updated.
+func (s *Service) UpdateEntityAndWait(ctx context.Context, cmd *UpdateEntity) (*Entity, error) {
+	res, _, err := s.updateEntity(ctx, cmd, applyAwait)
+	return res, err
+}
+
+func (s *Service) updateEntity(ctx context.Context, cmd *UpdateEntity, mode applyMode) (*Entity, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.UpdateEntity"
 	s.logger.Infof("%s: id=%s, tenant=%s", op, cmd.ID, cmd.TenantID)
 
-	if _, err := s.GetEntity(ctx, cmd.ID, cmd.TenantID); err != nil {
-		return err
+	if err := s.authorizeEntity(ctx, cmd.ID, cmd.TenantID, auth.VerbWrite); err != nil {
+		return nil, nil, err
 	}
 
 	key := NewCacheKey(s.cachePrefix, cmd.ID, cmd.TenantID)
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("update-%s", key), NewApplyEntityHandler(cmd, s))
+	return s.enqueue(ctx, fmt.Sprintf("update-%s", key), cmd, mode)
+}
 
-	return nil
+func (s *Service) DeleteEntity(ctx context.Context, cmd *DeleteEntity) (*operation.Operation, error) {
+	_, o, err := s.deleteEntity(ctx, cmd, applyAsync)
+	return o, err
+}
+
+// DeleteEntityAndWait is DeleteEntity waiting for the entity to be deleted.
+func (s *Service) DeleteEntityAndWait(ctx context.Context, cmd *DeleteEntity) (*Entity, error) {
+	res, _, err := s.deleteEntity(ctx, cmd, applyAwait)
+	return res, err
 }
 
-func (s *Service) DeleteEntity(ctx context.Context, cmd *DeleteEntity) error {
+func (s *Service) deleteEntity(ctx context.Context, cmd *DeleteEntity, mode applyMode) (*Entity, *operation.Operation, error) {
 	const op errors.Op = "graph/Service.DeleteEntity"
 	s.logger.Infof("%s: id=%s, tenant=%s", op, cmd.ID, cmd.TenantID)
 
-	if _, err := s.GetEntity(ctx, cmd.ID, cmd.TenantID); err != nil {
-		return err
+	old, err := s.getEntity(ctx, cmd.ID, cmd.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.authorize(ctx, cmd.TenantID, auth.VerbWrite, old.Type); err != nil {
+		return nil, nil, errors.E(op, err)
 	}
 
+	// Entities are deleted once, so that their deletion is counted once against the quota.
 	key := NewCacheKey(s.cachePrefix, cmd.ID, cmd.TenantID)
-	s.jobQueue <- worker.NewJob(fmt.Sprintf("delete-%s", key), NewApplyEntityHandler(cmd, s))
+	if old.DeletedAt != nil {
+		return nil, nil, errors.E(op, errors.NotFound, fmt.Sprintf("entity %s is deleted", key))
+	}
+
+	return s.enqueue(ctx, fmt.Sprintf("delete-%s", key), cmd, mode)
+}
+
+// ExportEntities calls fn with every entity of the tenant, deleted ones included, along with its
+// history. Entities of types the caller may not read are skipped.
+func (s *Service) ExportEntities(ctx context.Context, tenantID model.ID, fn func(entity *Entity, history eventstore.History) error) error {
+	const op errors.Op = "graph/Service.ExportEntities"
+	s.logger.Infof("%s: tenant=%s", op, tenantID)
+
+	if tenantID == "" {
+		return errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	readable := map[string]bool{}
+	err := s.entities.Scan(ctx, tenantID, func(aggregate eventstore.Aggregate, history eventstore.History) error {
+		entity := aggregate.(*Entity)
+		ok, seen := readable[entity.Type]
+		if !seen {
+			ok = s.authorize(ctx, tenantID, auth.VerbRead, entity.Type) == nil
+			readable[entity.Type] = ok
+		}
+
+		if !ok {
+			return nil
+		}
+
+		return fn(entity, history)
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
 
 	return nil
 }
+
+// ImportEntity is CreateEntityAndWait applying the command in the calling goroutine, for imports
+// creating entities concurrently.
+func (s *Service) ImportEntity(ctx context.Context, cmd *InsertEntity) (*Entity, error) {
+	res, _, err := s.createEntity(ctx, cmd, applyInline)
+	return res, err
+}
+
+// ImportEntityHistory creates an entity from its history, such as one exported by ExportEntities.
+// The events must belong to the entity and the tenant.
+func (s *Service) ImportEntityHistory(ctx context.Context, id model.ID, tenantID model.ID, history eventstore.History) (*Entity, error) {
+	const op errors.Op = "graph/Service.ImportEntityHistory"
+	s.logger.Infof("%s: id=%s, tenant=%s, events=%d", op, id, tenantID, len(history))
+
+	if id == "" {
+		return nil, errors.E(op, errors.Invalid, "ID is required")
+	}
+
+	verified, err := s.entities.Verify(id, tenantID, history)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	entity := verified.(*Entity)
+	if err := s.authorize(ctx, tenantID, auth.VerbWrite, entity.Type); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	u := limit.Usage{}
+	if entity.DeletedAt == nil {
+		u.Entities = 1
+	}
+
+	for _, record := range history {
+		u.EventBytes += int64(len(record.Data))
+	}
+
+	if s.quotas != nil {
+		if err := s.quotas.Reserve(ctx, tenantID, u); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	if _, err := s.entities.Import(ctx, id, tenantID, history); err != nil {
+		if s.quotas != nil {
+			if err := s.quotas.Record(context.Background(), tenantID, u.Negate()); err != nil {
+				s.logger.Error(err)
+			}
+		}
+
+		return nil, errors.E(op, err)
+	}
+
+	if err := s.setEntityToCache(ctx, entity); err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+
+	return entity, nil
+}
+
+// QueueLen returns the number of jobs waiting for a worker of the service and the capacity of
+// their queue, past which writes block.
+func (s *Service) QueueLen() (length int, capacity int) {
+	return len(s.jobQueue), cap(s.jobQueue)
+}
@@ -0,0 +1,185 @@
+package entity
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/limit"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTenant = model.ID("anonymous")
+
+// denyTypes is an Authorizer denying access to the types it holds.
+type denyTypes map[string]bool
+
+func (d denyTypes) Authorize(ctx context.Context, tenantID model.ID, resource string, verb string, typ string) error {
+	if d[typ] {
+		return errors.E(errors.Permission, "no access to "+typ)
+	}
+
+	return nil
+}
+
+// failingAuthorizer is an Authorizer whose policy store is unavailable.
+type failingAuthorizer struct{}
+
+func (failingAuthorizer) Authorize(ctx context.Context, tenantID model.ID, resource string, verb string, typ string) error {
+	return errors.E(errors.IO, "policy store unavailable")
+}
+
+// blockingStore is an event store whose saves wait for release to be closed.
+type blockingStore struct {
+	eventstore.Store
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(ctx context.Context, aggregateID model.ID, tenantID model.ID, records []*eventstore.Record) error {
+	<-s.release
+	return s.Store.Save(ctx, aggregateID, tenantID, records)
+}
+
+func newTestService(t *testing.T, cfg *Config) (*Service, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cache.Close() })
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg.Cache = cache
+	cfg.Logger = logger
+	if cfg.Store == nil {
+		cfg.Store = eventstore.NewInMemory(logger)
+	}
+
+	return New(cfg), mr
+}
+
+func TestService_DeleteEntity_Quota(t *testing.T) {
+	ctx := context.Background()
+	quotas := limit.New(limit.NewInMemory(), &limit.Table{Default: limit.Limits{MaxEntities: 10}})
+	svc, _ := newTestService(t, &Config{Quotas: quotas})
+
+	for _, id := range []model.ID{"alice", "bob"} {
+		_, err := svc.CreateEntityAndWait(ctx, &InsertEntity{CommandModel: model.CommandModel{ID: id, TenantID: testTenant}, Type: "user"})
+		require.NoError(t, err)
+	}
+
+	deleted, err := svc.DeleteEntityAndWait(ctx, &DeleteEntity{CommandModel: model.CommandModel{ID: "alice", TenantID: testTenant}})
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	// Deleting the entity again fails without freeing another entity.
+	_, err = svc.DeleteEntityAndWait(ctx, &DeleteEntity{CommandModel: model.CommandModel{ID: "alice", TenantID: testTenant}})
+	assert.True(t, errors.Is(errors.NotFound, err))
+
+	// Neither does a delete racing another one, rejected when applied.
+	_, _, err = svc.enqueue(ctx, "delete-alice", &DeleteEntity{CommandModel: model.CommandModel{ID: "alice", TenantID: testTenant}}, applyInline)
+	assert.True(t, errors.Is(errors.NotFound, err))
+
+	usage, err := quotas.Usage(ctx, testTenant)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Entities)
+}
+
+func TestService_GetEntitiesByType_Backfill(t *testing.T) {
+	ctx := context.Background()
+	svc, mr := newTestService(t, &Config{})
+
+	for _, id := range []model.ID{"alice", "bob", "carol"} {
+		_, err := svc.CreateEntityAndWait(ctx, &InsertEntity{CommandModel: model.CommandModel{ID: id, TenantID: testTenant}, Type: "user"})
+		require.NoError(t, err)
+	}
+
+	_, err := svc.DeleteEntityAndWait(ctx, &DeleteEntity{CommandModel: model.CommandModel{ID: "carol", TenantID: testTenant}})
+	require.NoError(t, err)
+
+	users := func() []model.ID {
+		page, err := svc.GetEntitiesByType(ctx, "user", testTenant, model.NewPagination(10, nil))
+		require.NoError(t, err)
+
+		var ids []model.ID
+		for _, e := range page.Items {
+			ids = append(ids, e.ID)
+		}
+
+		return ids
+	}
+
+	assert.ElementsMatch(t, []model.ID{"alice", "bob"}, users())
+
+	// Entities created before the type indexes were maintained are backfilled from the event
+	// store, once.
+	for _, key := range mr.Keys() {
+		if strings.Contains(key, "otype:") || strings.Contains(key, string(NewTypeIndexedID())) {
+			mr.Del(key)
+		}
+	}
+
+	assert.ElementsMatch(t, []model.ID{"alice", "bob"}, users())
+	assert.True(t, mr.Exists(NewCacheKey("", NewTypeIndexedID(), testTenant)))
+}
+
+func TestService_GetEntities(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, &Config{})
+
+	for id, otype := range map[model.ID]string{"alice": "user", "secret": "vault"} {
+		_, err := svc.CreateEntityAndWait(ctx, &InsertEntity{CommandModel: model.CommandModel{ID: id, TenantID: testTenant}, Type: otype})
+		require.NoError(t, err)
+	}
+
+	svc.authorizer = denyTypes{"vault": true}
+
+	// Entities the caller may not read are returned as nil and reported, instead of failing the
+	// others.
+	entities, forbidden, err := svc.GetEntities(ctx, []model.ID{"alice", "secret", "ghost"}, testTenant)
+	require.NoError(t, err)
+	require.Len(t, entities, 3)
+	assert.Equal(t, model.ID("alice"), entities[0].ID)
+	assert.Nil(t, entities[1])
+	assert.Nil(t, entities[2])
+	assert.Equal(t, []model.ID{"secret"}, forbidden)
+
+	svc.authorizer = failingAuthorizer{}
+	_, _, err = svc.GetEntities(ctx, []model.ID{"alice"}, testTenant)
+	assert.True(t, errors.Is(errors.IO, err))
+}
+
+func TestService_CreateEntityAndWait_Pending(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	store := &blockingStore{Store: eventstore.NewInMemory(logger), release: make(chan struct{})}
+	tracker := operation.NewTracker(operation.NewInMemory(time.Hour), logger)
+	svc, _ := newTestService(t, &Config{Operations: tracker, Store: store})
+
+	// A wait ending before the command is applied returns the operation tracking it.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := svc.CreateEntityAndWait(ctx, &InsertEntity{CommandModel: model.CommandModel{ID: "alice", TenantID: testTenant}, Type: "user"})
+	require.True(t, operation.IsPending(err))
+
+	o := operation.PendingOperation(err)
+	require.NotNil(t, o)
+
+	close(store.release)
+	assert.Eventually(t, func() bool {
+		got, err := tracker.Get(context.Background(), o.ID, testTenant)
+		return err == nil && got.State == operation.Succeeded
+	}, time.Second, 10*time.Millisecond)
+}
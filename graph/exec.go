@@ -0,0 +1,261 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph/query"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// scanBatchSize is the number of entities read from the type index at once.
+const scanBatchSize = 100
+
+// Row binds the returned variables to entities or associations.
+type Row map[string]interface{}
+
+// executor matches a pattern depth-first, binding nodes[i] and edges[i] as it goes.
+type executor struct {
+	svc      *Service
+	plan     *Plan
+	tenantID model.ID
+
+	cache map[model.ID]*entity.Entity
+	nodes []*entity.Entity
+	edges []*association.Association
+
+	emit func(row Row) bool
+
+	// truncated is set when an entity had more associations than the fan-out limit, which were
+	// not matched.
+	truncated bool
+}
+
+func newExecutor(svc *Service, plan *Plan, tenantID model.ID, emit func(row Row) bool) *executor {
+	pattern := plan.query.Pattern
+	return &executor{
+		svc:      svc,
+		plan:     plan,
+		tenantID: tenantID,
+		cache:    map[model.ID]*entity.Entity{},
+		nodes:    make([]*entity.Entity, len(pattern.Nodes)),
+		edges:    make([]*association.Association, len(pattern.Edges)),
+		emit:     emit,
+	}
+}
+
+// run executes the plan until every match is emitted or emit returns false.
+func (x *executor) run(ctx context.Context) error {
+	if x.plan.anchorID != "" {
+		anchor, err := x.entity(ctx, x.plan.anchorID)
+		if err != nil {
+			if errors.Is(errors.NotFound, err) {
+				return nil
+			}
+			return err
+		}
+
+		_, err = x.visit(ctx, 0, anchor)
+		return err
+	}
+
+	otype := x.plan.query.Pattern.Nodes[0].Type
+	var after *model.Cursor
+	for scanned := 0; scanned < x.svc.maxNodes; scanned += scanBatchSize {
+		page, err := x.svc.entities.GetEntitiesByType(ctx, otype, x.tenantID, model.NewPagination(scanBatchSize, after))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range page.Items {
+			x.cache[e.ID] = e
+			if next, err := x.visit(ctx, 0, e); err != nil || !next {
+				return err
+			}
+		}
+
+		if !page.HasMore {
+			return nil
+		}
+		after = page.Next
+	}
+
+	return errors.E(errors.Transient, "type scan limit reached")
+}
+
+// visit binds e to the i-th node and expands the pattern from it.
+// It returns false once the executor should stop.
+func (x *executor) visit(ctx context.Context, i int, e *entity.Entity) (bool, error) {
+	if !x.matchNode(i, e) {
+		return true, nil
+	}
+
+	x.nodes[i] = e
+	pattern := x.plan.query.Pattern
+	if i == len(pattern.Edges) {
+		if !query.Eval(x.plan.query.Where, x.resolve) {
+			return true, nil
+		}
+
+		return x.emit(x.row()), nil
+	}
+
+	edge := pattern.Edges[i]
+	types := edge.Types
+	if len(types) == 0 {
+		types = []string{association.AnyType}
+	}
+
+	for _, atype := range types {
+		page, err := x.svc.associations.GetOutgoingAssociations(ctx, e.ID, atype, x.tenantID, model.NewPagination(x.svc.maxFanOut, nil))
+		if err != nil {
+			return false, err
+		}
+
+		if page.HasMore {
+			x.truncated = true
+		}
+
+		for _, assoc := range page.Items {
+			if !matchProperties(edge.Properties, assoc) {
+				continue
+			}
+
+			target, err := x.entity(ctx, assoc.Out)
+			if err != nil {
+				if errors.Is(errors.NotFound, err) {
+					continue
+				}
+				return false, err
+			}
+
+			x.edges[i] = assoc
+			if next, err := x.visit(ctx, i+1, target); err != nil || !next {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (x *executor) entity(ctx context.Context, id model.ID) (*entity.Entity, error) {
+	if e, ok := x.cache[id]; ok {
+		return e, nil
+	}
+
+	e, err := x.svc.getAlive(ctx, id, x.tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	x.cache[id] = e
+	return e, nil
+}
+
+func (x *executor) matchNode(i int, e *entity.Entity) bool {
+	nodes := x.plan.query.Pattern.Nodes
+	n := nodes[i]
+	if n.Type != "" && n.Type != e.Type {
+		return false
+	}
+
+	// A variable used more than once must be bound to the same entity.
+	if n.Var != "" {
+		for j := 0; j < i; j++ {
+			if nodes[j].Var == n.Var && x.nodes[j].ID != e.ID {
+				return false
+			}
+		}
+	}
+
+	return matchProperties(n.Properties, e)
+}
+
+func (x *executor) resolve(p *query.Property) interface{} {
+	pattern := x.plan.query.Pattern
+	for i, n := range pattern.Nodes {
+		if n.Var == p.Var {
+			return attribute(x.nodes[i], p.Path)
+		}
+	}
+
+	for i, edge := range pattern.Edges {
+		if edge.Var == p.Var {
+			return attribute(x.edges[i], p.Path)
+		}
+	}
+
+	return nil
+}
+
+func (x *executor) row() Row {
+	row := Row{}
+	pattern := x.plan.query.Pattern
+	for _, column := range x.plan.columns {
+		for i, n := range pattern.Nodes {
+			if n.Var == column {
+				row[column] = x.nodes[i]
+			}
+		}
+
+		for i, edge := range pattern.Edges {
+			if edge.Var == column {
+				row[column] = x.edges[i]
+			}
+		}
+	}
+
+	return row
+}
+
+func matchProperties(props query.Properties, v interface{}) bool {
+	for key, expected := range props {
+		if !query.Compare("=", attribute(v, []string{key}), expected) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attribute resolves id, otype, atype, in and out to the attributes of an entity or association,
+// and any other path to its data.
+func attribute(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+
+	switch v := v.(type) {
+	case *entity.Entity:
+		if len(path) == 1 {
+			switch path[0] {
+			case "id":
+				return string(v.ID)
+			case "otype":
+				return v.Type
+			}
+		}
+
+		return query.Lookup(v.Data, path)
+	case *association.Association:
+		if len(path) == 1 {
+			switch path[0] {
+			case "id":
+				return string(v.ID)
+			case "atype":
+				return v.Type
+			case "in":
+				return string(v.In)
+			case "out":
+				return string(v.Out)
+			}
+		}
+
+		return query.Lookup(v.Data, path)
+	}
+
+	return nil
+}
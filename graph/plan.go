@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/edgestore/edgestore/graph/query"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+const (
+	// OperationNodeByID loads the anchor entity by its ID.
+	OperationNodeByID = "NodeByID"
+
+	// OperationNodeByTypeScan scans the type index for anchor entities.
+	OperationNodeByTypeScan = "NodeByTypeScan"
+
+	// OperationExpand follows outgoing associations through the adjacency index.
+	OperationExpand = "Expand"
+
+	// OperationFilter discards rows not matching a predicate.
+	OperationFilter = "Filter"
+
+	// OperationProject selects the returned variables.
+	OperationProject = "Project"
+
+	// OperationPaginate skips and limits rows.
+	OperationPaginate = "Paginate"
+)
+
+// Step is a single operation of a query plan.
+type Step struct {
+	Operation string `json:"operation"`
+	Detail    string `json:"detail"`
+}
+
+// Plan describes how a query is executed.
+type Plan struct {
+	Steps []*Step `json:"steps"`
+
+	anchorID model.ID
+	columns  []string
+	query    *query.Query
+}
+
+func (p *Plan) add(operation, format string, args ...interface{}) {
+	p.Steps = append(p.Steps, &Step{Operation: operation, Detail: fmt.Sprintf(format, args...)})
+}
+
+// plan anchors the query at its first node, preferring a lookup by ID over a type scan.
+func (s *Service) plan(q *query.Query, p *model.Pagination) (*Plan, error) {
+	plan := &Plan{query: q, columns: q.Return}
+	if len(plan.columns) == 0 {
+		plan.columns = q.Variables()
+	}
+
+	anchor := q.Pattern.Nodes[0]
+	if id, ok := anchor.Properties["id"].(string); ok {
+		plan.anchorID = model.ID(id)
+		plan.add(OperationNodeByID, "%s id=%q", anchor, id)
+	} else if anchor.Type != "" {
+		plan.add(OperationNodeByTypeScan, "%s scan up to %d entities", anchor, s.maxNodes)
+	} else {
+		return nil, fmt.Errorf("the first node of the pattern requires an otype or an id")
+	}
+
+	for i, edge := range q.Pattern.Edges {
+		from, to := q.Pattern.Nodes[i], q.Pattern.Nodes[i+1]
+		plan.add(OperationExpand, "(%s)%s(%s) fan-out %d", from.Var, edge, to.Var, s.maxFanOut)
+		if to.Type != "" || len(to.Properties) > 0 {
+			plan.add(OperationFilter, "%s", to)
+		}
+	}
+
+	if q.Where != nil {
+		plan.add(OperationFilter, "%s", q.Where)
+	}
+
+	plan.add(OperationProject, "%s", strings.Join(plan.columns, ", "))
+	offset := 0
+	if p.After != nil {
+		offset = p.After.Offset
+	}
+	plan.add(OperationPaginate, "offset %d limit %d", offset, p.Limit)
+
+	return plan, nil
+}
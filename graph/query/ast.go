@@ -0,0 +1,176 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Query is a parsed statement such as:
+//
+//	MATCH (u:user)-[:member_of]->(g:group {public: true}) WHERE u.age >= 18 RETURN u, g
+type Query struct {
+	Pattern *Pattern
+	Where   Expr
+	Return  []string
+}
+
+// Pattern is a chain of nodes linked by outgoing edges. Edges[i] links Nodes[i] to Nodes[i+1].
+type Pattern struct {
+	Nodes []*NodePattern
+	Edges []*EdgePattern
+}
+
+// NodePattern matches entities.
+type NodePattern struct {
+	Var        string
+	Type       string
+	Properties Properties
+}
+
+// EdgePattern matches associations.
+type EdgePattern struct {
+	Var        string
+	Types      []string
+	Properties Properties
+}
+
+// Properties are matched for equality against the attributes of an entity or association.
+type Properties map[string]interface{}
+
+// Expr is a boolean expression of a WHERE clause.
+type Expr interface {
+	fmt.Stringer
+	expr()
+}
+
+// Operand is either a Property or a Literal.
+type Operand interface {
+	fmt.Stringer
+	operand()
+}
+
+// Logical combines two expressions with AND or OR.
+type Logical struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Not negates an expression.
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares two operands with one of =, !=, <, <=, > or >=.
+type Comparison struct {
+	Op    string
+	Left  Operand
+	Right Operand
+}
+
+// Property references an attribute of a variable, e.g. u.address.city.
+type Property struct {
+	Var  string
+	Path []string
+}
+
+// Literal is a string, float64, bool or nil value.
+type Literal struct {
+	Value interface{}
+}
+
+func (*Logical) expr()     {}
+func (*Not) expr()         {}
+func (*Comparison) expr()  {}
+func (*Property) operand() {}
+func (*Literal) operand()  {}
+
+func (e *Logical) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right)
+}
+
+func (e *Not) String() string {
+	return fmt.Sprintf("NOT %s", e.Expr)
+}
+
+func (e *Comparison) String() string {
+	return fmt.Sprintf("%s %s %s", e.Left, e.Op, e.Right)
+}
+
+func (p *Property) String() string {
+	return p.Var + "." + strings.Join(p.Path, ".")
+}
+
+func (l *Literal) String() string {
+	return formatValue(l.Value)
+}
+
+func (p Properties) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s: %s", k, formatValue(p[k])))
+	}
+
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+func (n *NodePattern) String() string {
+	b := strings.Builder{}
+	b.WriteString("(")
+	b.WriteString(n.Var)
+	if n.Type != "" {
+		b.WriteString(":" + n.Type)
+	}
+	if len(n.Properties) > 0 {
+		b.WriteString(" " + n.Properties.String())
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func (e *EdgePattern) String() string {
+	b := strings.Builder{}
+	b.WriteString("-[")
+	b.WriteString(e.Var)
+	if len(e.Types) > 0 {
+		b.WriteString(":" + strings.Join(e.Types, "|"))
+	}
+	if len(e.Properties) > 0 {
+		b.WriteString(" " + e.Properties.String())
+	}
+	b.WriteString("]->")
+	return b.String()
+}
+
+func (p *Pattern) String() string {
+	b := strings.Builder{}
+	for i, n := range p.Nodes {
+		if i > 0 {
+			b.WriteString(p.Edges[i-1].String())
+		}
+		b.WriteString(n.String())
+	}
+	return b.String()
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
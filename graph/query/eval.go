@@ -0,0 +1,216 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Resolver returns the value of a property, or nil when it is not set.
+type Resolver func(p *Property) interface{}
+
+// Eval evaluates a boolean expression. Comparisons involving unset values are false,
+// except for equality against null.
+func Eval(e Expr, resolve Resolver) bool {
+	switch e := e.(type) {
+	case nil:
+		return true
+	case *Logical:
+		if e.Op == "AND" {
+			return Eval(e.Left, resolve) && Eval(e.Right, resolve)
+		}
+		return Eval(e.Left, resolve) || Eval(e.Right, resolve)
+	case *Not:
+		return !Eval(e.Expr, resolve)
+	case *Comparison:
+		return Compare(e.Op, value(e.Left, resolve), value(e.Right, resolve))
+	}
+
+	return false
+}
+
+func value(o Operand, resolve Resolver) interface{} {
+	switch o := o.(type) {
+	case *Literal:
+		return o.Value
+	case *Property:
+		return resolve(o)
+	}
+
+	return nil
+}
+
+// Lookup walks a nested map following path.
+func Lookup(data map[string]interface{}, path []string) interface{} {
+	var current interface{} = data
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		if current, ok = m[key]; !ok {
+			return nil
+		}
+	}
+
+	return current
+}
+
+// Compare applies a comparison operator to two values decoded from JSON.
+// Ordering operators only apply to pairs of numbers or pairs of strings.
+func Compare(op string, a, b interface{}) bool {
+	switch op {
+	case "=":
+		return equal(a, b)
+	case "!=":
+		return !equal(a, b)
+	}
+
+	c, ok := order(a, b)
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if c, ok := order(a, b); ok {
+		return c == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func order(a, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+// Variables returns the variables declared by the pattern, in order of appearance.
+func (q *Query) Variables() []string {
+	var vars []string
+	seen := map[string]bool{}
+	for i, n := range q.Pattern.Nodes {
+		if i > 0 {
+			if v := q.Pattern.Edges[i-1].Var; v != "" && !seen[v] {
+				seen[v] = true
+				vars = append(vars, v)
+			}
+		}
+
+		if n.Var != "" && !seen[n.Var] {
+			seen[n.Var] = true
+			vars = append(vars, n.Var)
+		}
+	}
+
+	return vars
+}
+
+// validate checks that variables are used consistently.
+func (q *Query) validate() error {
+	nodes := map[string]bool{}
+	for _, n := range q.Pattern.Nodes {
+		if n.Var != "" {
+			nodes[n.Var] = true
+		}
+	}
+
+	edges := map[string]bool{}
+	for _, e := range q.Pattern.Edges {
+		if e.Var == "" {
+			continue
+		}
+
+		if nodes[e.Var] || edges[e.Var] {
+			return fmt.Errorf("variable %s is declared more than once", e.Var)
+		}
+		edges[e.Var] = true
+	}
+
+	var check func(e Expr) error
+	check = func(e Expr) error {
+		switch e := e.(type) {
+		case *Logical:
+			if err := check(e.Left); err != nil {
+				return err
+			}
+			return check(e.Right)
+		case *Not:
+			return check(e.Expr)
+		case *Comparison:
+			for _, o := range []Operand{e.Left, e.Right} {
+				if p, ok := o.(*Property); ok && !nodes[p.Var] && !edges[p.Var] {
+					return fmt.Errorf("undefined variable %s", p.Var)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check(q.Where); err != nil {
+		return err
+	}
+
+	for _, v := range q.Return {
+		if !nodes[v] && !edges[v] {
+			return fmt.Errorf("undefined variable %s", v)
+		}
+	}
+
+	return nil
+}
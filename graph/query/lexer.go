@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("%q", t.value)
+	default:
+		return fmt.Sprintf("'%s'", t.value)
+	}
+}
+
+// is reports whether the token is the given punctuation or keyword, ignoring case for keywords.
+func (t token) is(value string) bool {
+	switch t.kind {
+	case tokenPunct:
+		return t.value == value
+	case tokenIdent:
+		return strings.EqualFold(t.value, value)
+	}
+
+	return false
+}
+
+var punctuations = []string{"->", "<=", ">=", "!=", "<>", "(", ")", "[", "]", "{", "}", ":", ",", ".", "-", "|", "=", "<", ">", "*"}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			b := strings.Builder{}
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated string"}
+			}
+
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[start:i]), pos: start})
+		case r == '`':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '`' {
+				i++
+			}
+
+			if i >= len(runes) {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated identifier"}
+			}
+
+			i++
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[start+1 : i-1]), pos: start})
+		default:
+			matched := ""
+			for _, p := range punctuations {
+				if strings.HasPrefix(string(runes[i:]), p) {
+					matched = p
+					break
+				}
+			}
+
+			if matched == "" {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+
+			tokens = append(tokens, token{kind: tokenPunct, value: matched, pos: i})
+			i += len([]rune(matched))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
@@ -0,0 +1,418 @@
+// Package query implements a small pattern matching language over entities and associations.
+//
+// A query is a single path pattern, an optional WHERE clause and an optional RETURN clause:
+//
+//	MATCH (u:user {country: "BR"})-[:member_of]->(g:group) WHERE g.public = true AND NOT u.banned = true RETURN u, g
+//
+// Nodes match entities by otype, edges match associations by atype (alternatives are separated by |)
+// and the properties between braces are compared for equality. Properties resolve to the
+// attributes id, otype and atype, and otherwise to nested keys of the data field.
+package query
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SyntaxError reports the position of an invalid token.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// MaxDepth bounds the nesting of parentheses and NOT operators in a WHERE clause.
+const MaxDepth = 32
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse parses a query.
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(value string) bool {
+	if p.peek().is(value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) error {
+	if t := p.next(); !t.is(value) {
+		return p.errorf(t, "expected '%s', found %s", value, t)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return "", p.errorf(t, "expected identifier, found %s", t)
+	}
+	return t.value, nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+
+	p.accept("MATCH")
+
+	pattern, err := p.parsePattern()
+	if err != nil {
+		return nil, err
+	}
+	q.Pattern = pattern
+
+	if p.accept("WHERE") {
+		if q.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("RETURN") {
+		if !p.accept("*") {
+			for {
+				v, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+
+				q.Return = append(q.Return, v)
+				if !p.accept(",") {
+					break
+				}
+			}
+		}
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+
+	return q, nil
+}
+
+func (p *parser) parsePattern() (*Pattern, error) {
+	pattern := &Pattern{}
+
+	node, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	pattern.Nodes = append(pattern.Nodes, node)
+
+	for p.peek().is("-") {
+		edge, err := p.parseEdge()
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+
+		pattern.Edges = append(pattern.Edges, edge)
+		pattern.Nodes = append(pattern.Nodes, node)
+	}
+
+	return pattern, nil
+}
+
+func (p *parser) parseNode() (*NodePattern, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	node := &NodePattern{}
+	if p.peek().kind == tokenIdent {
+		node.Var = p.next().value
+	}
+
+	if p.accept(":") {
+		otype, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		node.Type = otype
+	}
+
+	if p.peek().is("{") {
+		props, err := p.parseProperties()
+		if err != nil {
+			return nil, err
+		}
+		node.Properties = props
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// parseEdge parses either -[var:type|type {props}]-> or -->.
+func (p *parser) parseEdge() (*EdgePattern, error) {
+	if err := p.expect("-"); err != nil {
+		return nil, err
+	}
+
+	edge := &EdgePattern{}
+	if p.accept("->") {
+		return edge, nil
+	}
+
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokenIdent {
+		edge.Var = p.next().value
+	}
+
+	if p.accept(":") {
+		for {
+			atype, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+
+			edge.Types = append(edge.Types, atype)
+			if !p.accept("|") {
+				break
+			}
+		}
+	}
+
+	if p.peek().is("{") {
+		props, err := p.parseProperties()
+		if err != nil {
+			return nil, err
+		}
+		edge.Properties = props
+	}
+
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("->"); err != nil {
+		return nil, err
+	}
+
+	return edge, nil
+}
+
+func (p *parser) parseProperties() (Properties, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	props := Properties{}
+	if p.accept("}") {
+		return props, nil
+	}
+
+	for {
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+
+		props[key] = value.Value
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	return props, nil
+}
+
+func (p *parser) parseLiteral() (*Literal, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenString:
+		return &Literal{Value: t.value}, nil
+	case t.kind == tokenNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t)
+		}
+		return &Literal{Value: f}, nil
+	case t.is("true"):
+		return &Literal{Value: true}, nil
+	case t.is("false"):
+		return &Literal{Value: false}, nil
+	case t.is("null"):
+		return &Literal{Value: nil}, nil
+	}
+
+	return nil, p.errorf(t, "expected literal, found %s", t)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "OR", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "AND", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if t := p.peek(); t.is("NOT") || t.is("(") {
+		if p.depth == MaxDepth {
+			return nil, p.errorf(t, "expressions are nested deeper than %d levels", MaxDepth)
+		}
+
+		p.depth++
+		defer func() { p.depth-- }()
+	}
+
+	if p.accept("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	op := t.value
+	switch {
+	case t.is("="), t.is("!="), t.is("<"), t.is("<="), t.is(">"), t.is(">="):
+	case t.is("<>"):
+		op = "!="
+	default:
+		return nil, p.errorf(t, "expected comparison operator, found %s", t)
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{Op: op, Left: left, Right: right}, nil
+}
+
+func (p *parser) parseOperand() (Operand, error) {
+	t := p.peek()
+	if t.kind != tokenIdent || t.is("true") || t.is("false") || t.is("null") {
+		return p.parseLiteral()
+	}
+
+	prop := &Property{Var: p.next().value}
+	for p.accept(".") {
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		prop.Path = append(prop.Path, key)
+	}
+
+	if len(prop.Path) == 0 {
+		return nil, p.errorf(t, "expected property of %s", t)
+	}
+
+	return prop, nil
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`MATCH (u:user)-[m:member_of|owner_of {role: 'admin'}]->(g:group {public: true, size: 3}) WHERE u.age >= 18 AND NOT g.name = "x" RETURN u, g`)
+	assert.Nil(t, err)
+
+	assert.Len(t, q.Pattern.Nodes, 2)
+	assert.Equal(t, "u", q.Pattern.Nodes[0].Var)
+	assert.Equal(t, "user", q.Pattern.Nodes[0].Type)
+	assert.Equal(t, Properties{"public": true, "size": float64(3)}, q.Pattern.Nodes[1].Properties)
+
+	assert.Len(t, q.Pattern.Edges, 1)
+	assert.Equal(t, "m", q.Pattern.Edges[0].Var)
+	assert.Equal(t, []string{"member_of", "owner_of"}, q.Pattern.Edges[0].Types)
+	assert.Equal(t, Properties{"role": "admin"}, q.Pattern.Edges[0].Properties)
+
+	assert.Equal(t, `(u.age >= 18 AND NOT g.name = "x")`, q.Where.String())
+	assert.Equal(t, []string{"u", "g"}, q.Return)
+}
+
+func TestParse_AnonymousEdge(t *testing.T) {
+	q, err := Parse(`(a)-->(b)-[:x]->()`)
+	assert.Nil(t, err)
+	assert.Equal(t, `(a)-[]->(b)-[:x]->()`, q.Pattern.String())
+	assert.Equal(t, []string{"a", "b"}, q.Variables())
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{`(u:user`, 7},
+		{`(u)-[:x]-(v)`, 8},
+		{`(u) WHERE u.x ~ 1`, 14},
+		{`(u {name: "x)`, 10},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if assert.IsType(t, &SyntaxError{}, err, tt.input) {
+			assert.Equal(t, tt.pos, err.(*SyntaxError).Pos, tt.input)
+		}
+	}
+}
+
+func TestParse_Depth(t *testing.T) {
+	_, err := Parse(`(u) WHERE ` + strings.Repeat("NOT ", MaxDepth) + `u.x = 1`)
+	assert.Nil(t, err)
+
+	_, err = Parse(`(u) WHERE ` + strings.Repeat("(", MaxDepth) + `NOT u.x = 1` + strings.Repeat(")", MaxDepth))
+	if assert.IsType(t, &SyntaxError{}, err) {
+		assert.Equal(t, 10+MaxDepth, err.(*SyntaxError).Pos)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	assert.True(t, Compare("=", float64(1), float64(1)))
+	assert.True(t, Compare("<", "a", "b"))
+	assert.True(t, Compare("=", nil, nil))
+	assert.False(t, Compare(">", nil, float64(1)))
+	assert.False(t, Compare("<", "a", float64(1)))
+	assert.True(t, Compare("!=", true, false))
+}
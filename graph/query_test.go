@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph/query"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func rowIDs(rows []Row, column string) []model.ID {
+	var res []model.ID
+	for _, row := range rows {
+		res = append(res, row[column].(*entity.Entity).ID)
+	}
+	return res
+}
+
+func TestService_Query(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	tests := []struct {
+		name      string
+		statement string
+		column    string
+		want      []model.ID
+	}{
+		{"type scan", `MATCH (u:user) RETURN u`, "u", []model.ID{"alice", "bob", "carol"}},
+		{"lookup by id", `(u:user {id: "bob"})-[:friend]->(f) RETURN f`, "f", []model.ID{"carol"}},
+		{"target properties", `(u:user)-[:member_of]->(g:group {public: true}) RETURN u`, "u", []model.ID{"alice", "carol"}},
+		{"where", `(u:user)-[:friend]->(f:user) WHERE f.age >= 18 RETURN u`, "u", []model.ID{"bob"}},
+		{"nested data", `(u:user) WHERE u.address.city = "Lisbon" RETURN u`, "u", []model.ID{"carol"}},
+		{"not", `(u:user) WHERE NOT (u.age < 18 OR u.age > 40)`, "u", []model.ID{"alice"}},
+		{"multiple hops", `(a:user)-[:friend]->()-[:friend]->(c) RETURN c`, "c", []model.ID{"carol"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := svc.Query(context.Background(), &Query{TenantID: tenantID, Statement: tt.statement})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, rowIDs(res.Items, tt.column))
+		})
+	}
+}
+
+func TestService_Query_Pagination(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	res, err := svc.Query(context.Background(), &Query{
+		TenantID:   tenantID,
+		Statement:  `MATCH (u:user) RETURN u`,
+		Pagination: model.NewPagination(2, nil),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ID{"alice", "bob"}, rowIDs(res.Items, "u"))
+	assert.True(t, res.HasMore)
+
+	res, err = svc.Query(context.Background(), &Query{
+		TenantID:   tenantID,
+		Statement:  `MATCH (u:user) RETURN u`,
+		Pagination: model.NewPagination(2, res.Next),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ID{"carol"}, rowIDs(res.Items, "u"))
+	assert.False(t, res.HasMore)
+	assert.Nil(t, res.Next)
+}
+
+func TestService_Query_FanOut(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{MaxFanOut: 1})
+
+	res, err := svc.Query(context.Background(), &Query{TenantID: tenantID, Statement: `(u:user {id: "alice"})-->(x) RETURN x`})
+	assert.Nil(t, err)
+	assert.Len(t, res.Items, 1)
+	assert.True(t, res.Truncated)
+
+	res, err = svc.Query(context.Background(), &Query{TenantID: tenantID, Statement: `(u:user {id: "bob"})-->(x) RETURN x`})
+	assert.Nil(t, err)
+	assert.Len(t, res.Items, 1)
+	assert.False(t, res.Truncated)
+}
+
+func TestService_Query_Explain(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	res, err := svc.Query(context.Background(), &Query{
+		TenantID:  tenantID,
+		Statement: `MATCH (u:user)-[m:member_of]->(g:group {public: true}) WHERE u.age > 30`,
+		Explain:   true,
+	})
+
+	assert.Nil(t, err)
+	assert.Empty(t, res.Items)
+	assert.Equal(t, []string{"u", "m", "g"}, res.Columns)
+
+	var operations []string
+	for _, step := range res.Plan.Steps {
+		operations = append(operations, step.Operation)
+	}
+	assert.Equal(t, []string{OperationNodeByTypeScan, OperationExpand, OperationFilter, OperationFilter, OperationProject, OperationPaginate}, operations)
+}
+
+func TestService_Query_Invalid(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	for _, statement := range []string{
+		`MATCH (u:user`,
+		`MATCH (u)-[:friend]->(f)`,
+		`MATCH (u:user) RETURN x`,
+		`MATCH (u:user) WHERE ` + strings.Repeat("NOT ", query.MaxDepth+1) + `u.age > 1`,
+	} {
+		_, err := svc.Query(context.Background(), &Query{TenantID: tenantID, Statement: statement})
+		assert.True(t, errors.Is(errors.Invalid, err), statement)
+	}
+}
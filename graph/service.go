@@ -0,0 +1,404 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph/query"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxDepth is the maximum number of hops of a single traversal.
+	DefaultMaxDepth = 4
+
+	// DefaultMaxFanOut is the maximum number of associations followed from a single entity.
+	DefaultMaxFanOut = 100
+
+	// DefaultMaxNodes is the maximum number of entities visited by a single traversal.
+	DefaultMaxNodes = 1000
+
+	// DefaultTimeout is the maximum duration of a single traversal or query.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultQueryLimit is the number of rows returned by a query when no pagination is given.
+	DefaultQueryLimit = 10
+)
+
+// EntityGetter is implemented by entity.Service.
+type EntityGetter interface {
+	GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error)
+	GetEntitiesByType(ctx context.Context, otype string, tenantID model.ID, p *model.Pagination) (*model.Page[*entity.Entity], error)
+}
+
+// AssociationGetter is implemented by association.Service.
+type AssociationGetter interface {
+	GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error)
+	GetIncomingAssociations(ctx context.Context, out model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error)
+}
+
+type Service struct {
+	associations AssociationGetter
+	entities     EntityGetter
+	logger       logrus.FieldLogger
+	maxDepth     int
+	maxFanOut    int
+	maxNodes     int
+	timeout      time.Duration
+}
+
+type Config struct {
+	Associations AssociationGetter
+	Entities     EntityGetter
+	Logger       logrus.FieldLogger
+	MaxDepth     int
+	MaxFanOut    int
+	MaxNodes     int
+	Timeout      time.Duration
+}
+
+func New(cfg *Config) *Service {
+	svc := &Service{
+		associations: cfg.Associations,
+		entities:     cfg.Entities,
+		logger:       cfg.Logger.WithField("component", "graph-service"),
+		maxDepth:     cfg.MaxDepth,
+		maxFanOut:    cfg.MaxFanOut,
+		maxNodes:     cfg.MaxNodes,
+		timeout:      cfg.Timeout,
+	}
+
+	if svc.maxDepth <= 0 {
+		svc.maxDepth = DefaultMaxDepth
+	}
+
+	if svc.maxFanOut <= 0 {
+		svc.maxFanOut = DefaultMaxFanOut
+	}
+
+	if svc.maxNodes <= 0 {
+		svc.maxNodes = DefaultMaxNodes
+	}
+
+	if svc.timeout <= 0 {
+		svc.timeout = DefaultTimeout
+	}
+
+	return svc
+}
+
+// limit returns value capped by max, or max when value is not set.
+func limit(value, max int) int {
+	if value <= 0 || value > max {
+		return max
+	}
+
+	return value
+}
+
+// getAlive loads an entity, reporting deleted entities as not found.
+func (s *Service) getAlive(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error) {
+	e, err := s.entities.GetEntity(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.DeletedAt != nil {
+		return nil, errors.E(errors.NotFound, fmt.Sprintf("entity %s was deleted", id))
+	}
+
+	return e, nil
+}
+
+// neighbors returns up to fanOut associations of the entity id in the given direction that match one of the given types.
+func (s *Service) neighbors(ctx context.Context, id model.ID, tenantID model.ID, direction string, types []string, fanOut int) ([]*association.Association, error) {
+	get := s.associations.GetOutgoingAssociations
+	if direction == DirectionIn {
+		get = s.associations.GetIncomingAssociations
+	}
+
+	var assocs []*association.Association
+	for _, atype := range types {
+		remaining := fanOut - len(assocs)
+		if remaining <= 0 {
+			break
+		}
+
+		page, err := get(ctx, id, atype, tenantID, model.NewPagination(remaining, nil))
+		if err != nil {
+			return nil, err
+		}
+
+		assocs = append(assocs, page.Items...)
+	}
+
+	return assocs, nil
+}
+
+// Traverse visits the entities reachable from q.Start, following q.Hops in order.
+// Limits and the service timeout interrupt the traversal and mark the result as truncated.
+func (s *Service) Traverse(ctx context.Context, q *Traverse) (*Result, error) {
+	const op errors.Op = "graph/Service.Traverse"
+	s.logger.Infof("%s: start=%s, tenant=%s, hops=%d", op, q.Start, q.TenantID, len(q.Hops))
+
+	if q.Start == "" {
+		return nil, errors.E(op, errors.Invalid, "start ID is required")
+	}
+
+	if q.TenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if len(q.Hops) == 0 {
+		return nil, errors.E(op, errors.Invalid, "at least one hop is required")
+	}
+
+	if len(q.Hops) > s.maxDepth {
+		return nil, errors.E(op, errors.Invalid, fmt.Sprintf("traversals are limited to %d hops", s.maxDepth))
+	}
+
+	for _, hop := range q.Hops {
+		if hop.Direction != "" && hop.Direction != DirectionOut && hop.Direction != DirectionIn {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("invalid direction %q, expected in or out", hop.Direction))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start, err := s.getAlive(ctx, q.Start, q.TenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	maxNodes := limit(q.MaxNodes, s.maxNodes)
+	res := &Result{
+		Entities:     []*Node{{Entity: start}},
+		Associations: []*association.Association{},
+	}
+
+	visited := map[model.ID]bool{start.ID: true}
+	frontier := []*entity.Entity{start}
+	for depth, hop := range q.Hops {
+		var next []*entity.Entity
+		for _, node := range frontier {
+			assocs, err := s.neighbors(ctx, node.ID, q.TenantID, hop.Direction, hop.types(), limit(hop.Limit, s.maxFanOut))
+			if err != nil {
+				if ctx.Err() != nil {
+					res.Truncated = true
+					return res, nil
+				}
+
+				return nil, errors.E(op, err)
+			}
+
+			for _, assoc := range assocs {
+				if visited[hop.next(assoc)] {
+					res.Associations = append(res.Associations, assoc)
+					continue
+				}
+
+				if len(res.Entities) >= maxNodes {
+					res.Truncated = true
+					return res, nil
+				}
+
+				target, err := s.getAlive(ctx, hop.next(assoc), q.TenantID)
+				if err != nil {
+					if ctx.Err() != nil {
+						res.Truncated = true
+						return res, nil
+					}
+
+					if errors.Is(errors.NotFound, err) {
+						continue
+					}
+
+					return nil, errors.E(op, err)
+				}
+
+				if !hop.acceptEntity(target) {
+					continue
+				}
+
+				visited[target.ID] = true
+				res.Entities = append(res.Entities, &Node{Entity: target, Depth: depth + 1})
+				res.Associations = append(res.Associations, assoc)
+				next = append(next, target)
+			}
+		}
+
+		frontier = next
+	}
+
+	return res, nil
+}
+
+// ShortestPath runs a breadth-first search over outgoing associations from q.From to q.To.
+// Entities are only loaded once a path is found.
+func (s *Service) ShortestPath(ctx context.Context, q *ShortestPath) (*Path, error) {
+	const op errors.Op = "graph/Service.ShortestPath"
+	s.logger.Infof("%s: from=%s, to=%s, tenant=%s", op, q.From, q.To, q.TenantID)
+
+	if q.From == "" || q.To == "" {
+		return nil, errors.E(op, errors.Invalid, "from and to IDs are required")
+	}
+
+	if q.TenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	hop := Hop{Types: q.Types}
+	types := hop.types()
+	maxDepth := limit(q.MaxDepth, s.maxDepth)
+
+	parents := map[model.ID]*association.Association{}
+	visited := map[model.ID]bool{q.From: true}
+	frontier := []model.ID{q.From}
+	found := q.From == q.To
+	for depth := 0; depth < maxDepth && len(frontier) > 0 && !found; depth++ {
+		var next []model.ID
+		for _, id := range frontier {
+			assocs, err := s.neighbors(ctx, id, q.TenantID, DirectionOut, types, s.maxFanOut)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, errors.E(op, errors.Transient, "shortest path search timed out")
+				}
+
+				return nil, errors.E(op, err)
+			}
+
+			for _, assoc := range assocs {
+				if visited[assoc.Out] {
+					continue
+				}
+
+				visited[assoc.Out] = true
+				parents[assoc.Out] = assoc
+				next = append(next, assoc.Out)
+
+				if assoc.Out == q.To {
+					found = true
+					break
+				}
+
+				if len(visited) >= s.maxNodes {
+					return nil, errors.E(op, errors.NotFound, fmt.Sprintf("no path found within %d entities", s.maxNodes))
+				}
+			}
+
+			if found {
+				break
+			}
+		}
+
+		frontier = next
+	}
+
+	if !found {
+		return nil, errors.E(op, errors.NotFound, fmt.Sprintf("no path found within %d hops", maxDepth))
+	}
+
+	assocs := []*association.Association{}
+	for id := q.To; id != q.From; id = parents[id].In {
+		assocs = append([]*association.Association{parents[id]}, assocs...)
+	}
+
+	path := &Path{Associations: assocs}
+	ids := []model.ID{q.From}
+	for _, assoc := range assocs {
+		ids = append(ids, assoc.Out)
+	}
+
+	for _, id := range ids {
+		e, err := s.getAlive(ctx, id, q.TenantID)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		path.Entities = append(path.Entities, e)
+	}
+
+	return path, nil
+}
+
+// Query parses, plans and executes a query statement. With q.Explain set, only the plan is returned.
+// Rows are paged according to q.Pagination, the cursor of a page being its offset; reaching the timeout, the scan limit
+// or the fan-out limit returns the rows found so far marked as truncated.
+func (s *Service) Query(ctx context.Context, q *Query) (*QueryResult, error) {
+	const op errors.Op = "graph/Service.Query"
+	s.logger.Infof("%s: tenant=%s, query=%q", op, q.TenantID, q.Statement)
+
+	if q.TenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	stmt, err := query.Parse(q.Statement)
+	if err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+
+	p := q.Pagination
+	if p == nil {
+		p = model.NewPagination(DefaultQueryLimit, nil)
+	}
+
+	offset := 0
+	if p.After != nil {
+		offset = p.After.Offset
+	}
+
+	plan, err := s.plan(stmt, p)
+	if err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+
+	res := &QueryResult{Columns: plan.columns, Page: model.NewPage[Row](nil, nil)}
+	if q.Explain {
+		res.Plan = plan
+		return res, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	skipped := 0
+	x := newExecutor(s, plan, q.TenantID, func(row Row) bool {
+		if skipped < offset {
+			skipped++
+			return true
+		}
+
+		// A row beyond the limit only tells that another page follows.
+		if len(res.Items) == p.Limit {
+			res.HasMore = true
+			res.Next = &model.Cursor{Offset: offset + p.Limit}
+			return false
+		}
+
+		res.Items = append(res.Items, row)
+		return true
+	})
+
+	if err := x.run(ctx); err != nil {
+		if ctx.Err() == nil && !errors.Is(errors.Transient, err) {
+			return nil, errors.E(op, err)
+		}
+
+		res.Truncated = true
+	}
+
+	if x.truncated {
+		res.Truncated = true
+	}
+
+	return res, nil
+}
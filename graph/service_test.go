@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const tenantID = model.ID("anonymous")
+
+type fakeGraph struct {
+	entities     map[model.ID]*entity.Entity
+	associations []*association.Association
+}
+
+func (g *fakeGraph) GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error) {
+	if e, ok := g.entities[id]; ok {
+		return e, nil
+	}
+
+	return nil, errors.E(errors.NotFound)
+}
+
+func (g *fakeGraph) GetEntitiesByType(ctx context.Context, otype string, tenantID model.ID, p *model.Pagination) (*model.Page[*entity.Entity], error) {
+	var found []*entity.Entity
+	for _, id := range []model.ID{"alice", "bob", "carol", "admins"} {
+		if e, ok := g.entities[id]; ok && e.Type == otype {
+			found = append(found, e)
+		}
+	}
+
+	return page(found, p), nil
+}
+
+// page returns the items of a page, the cursor being the offset of the next one.
+func page[T any](found []T, p *model.Pagination) *model.Page[T] {
+	offset := 0
+	if p.After != nil {
+		offset = p.After.Offset
+	}
+
+	if offset >= len(found) {
+		return model.NewPage[T](nil, nil)
+	}
+
+	found = found[offset:]
+	if len(found) > p.Limit {
+		return model.NewPage(found[:p.Limit], &model.Cursor{Offset: offset + p.Limit})
+	}
+
+	return model.NewPage(found, nil)
+}
+
+func (g *fakeGraph) GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error) {
+	var found []*association.Association
+	for _, assoc := range g.associations {
+		if assoc.In == in && (atype == association.AnyType || assoc.Type == atype) {
+			found = append(found, assoc)
+		}
+	}
+
+	return page(found, p), nil
+}
+
+func (g *fakeGraph) GetIncomingAssociations(ctx context.Context, out model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error) {
+	var found []*association.Association
+	for _, assoc := range g.associations {
+		if assoc.Out == out && (atype == association.AnyType || assoc.Type == atype) {
+			found = append(found, assoc)
+		}
+	}
+
+	return page(found, p), nil
+}
+
+func (g *fakeGraph) entity(id model.ID, otype string, data model.Data) {
+	g.entities[id] = &entity.Entity{ID: id, TenantID: tenantID, Type: otype, Data: data}
+}
+
+func (g *fakeGraph) associate(in model.ID, atype string, out model.ID) {
+	g.associations = append(g.associations, &association.Association{
+		ID:       association.NewAssociationID(in, atype, out),
+		In:       in,
+		Out:      out,
+		TenantID: tenantID,
+		Type:     atype,
+	})
+}
+
+// newFakeGraph returns alice -friend-> bob -friend-> carol -member_of-> admins
+// with alice -member_of-> admins as a shortcut.
+func newFakeGraph() *fakeGraph {
+	g := &fakeGraph{entities: map[model.ID]*entity.Entity{}}
+	g.entity("alice", "user", model.Data{"age": float64(31)})
+	g.entity("bob", "user", model.Data{"age": float64(17)})
+	g.entity("carol", "user", model.Data{"age": float64(45), "address": map[string]interface{}{"city": "Lisbon"}})
+	g.entity("admins", "group", model.Data{"public": true})
+	g.associate("alice", "friend", "bob")
+	g.associate("bob", "friend", "carol")
+	g.associate("carol", "member_of", "admins")
+	g.associate("alice", "member_of", "admins")
+	return g
+}
+
+func newService(g *fakeGraph, cfg Config) *Service {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	cfg.Associations = g
+	cfg.Entities = g
+	cfg.Logger = logger
+	return New(&cfg)
+}
+
+func ids(nodes []*Node) []model.ID {
+	var res []model.ID
+	for _, n := range nodes {
+		res = append(res, n.ID)
+	}
+	return res
+}
+
+func TestService_Traverse(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	res, err := svc.Traverse(context.Background(), &Traverse{
+		TenantID: tenantID,
+		Start:    "alice",
+		Hops: []Hop{
+			{Types: []string{"friend"}},
+			{Types: []string{"friend"}},
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, []model.ID{"alice", "bob", "carol"}, ids(res.Entities))
+	assert.Equal(t, 2, res.Entities[2].Depth)
+	assert.Len(t, res.Associations, 2)
+}
+
+func TestService_Traverse_EntityTypes(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	res, err := svc.Traverse(context.Background(), &Traverse{
+		TenantID: tenantID,
+		Start:    "alice",
+		Hops:     []Hop{{EntityTypes: []string{"group"}}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ID{"alice", "admins"}, ids(res.Entities))
+}
+
+func TestService_Traverse_Incoming(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	res, err := svc.Traverse(context.Background(), &Traverse{
+		TenantID: tenantID,
+		Start:    "admins",
+		Hops:     []Hop{{Types: []string{"member_of"}, Direction: DirectionIn}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ID{"admins", "carol", "alice"}, ids(res.Entities))
+}
+
+func TestService_Traverse_Limits(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{MaxDepth: 1})
+
+	_, err := svc.Traverse(context.Background(), &Traverse{
+		TenantID: tenantID,
+		Start:    "alice",
+		Hops:     []Hop{{}, {}},
+	})
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	res, err := svc.Traverse(context.Background(), &Traverse{
+		TenantID: tenantID,
+		Start:    "alice",
+		Hops:     []Hop{{}},
+		MaxNodes: 2,
+	})
+	assert.Nil(t, err)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Entities, 2)
+}
+
+func TestService_ShortestPath(t *testing.T) {
+	svc := newService(newFakeGraph(), Config{})
+
+	path, err := svc.ShortestPath(context.Background(), &ShortestPath{
+		TenantID: tenantID,
+		From:     "alice",
+		To:       "carol",
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, path.Entities, 3)
+	assert.Len(t, path.Associations, 2)
+	assert.EqualValues(t, "carol", path.Entities[2].ID)
+
+	_, err = svc.ShortestPath(context.Background(), &ShortestPath{
+		TenantID: tenantID,
+		From:     "alice",
+		To:       "carol",
+		MaxDepth: 1,
+	})
+	assert.True(t, errors.Is(errors.NotFound, err))
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+const (
+	DirectionOut = "out"
+	DirectionIn  = "in"
+)
+
+// Hop describes a single step of a traversal.
+type Hop struct {
+	// Types restricts the association types followed by this hop. Every type is followed when empty.
+	Types []string `json:"atypes"`
+
+	// EntityTypes restricts the entities reached by this hop. Every type is accepted when empty.
+	EntityTypes []string `json:"otypes"`
+
+	// Direction is either DirectionOut (default), following outgoing associations,
+	// or DirectionIn, following incoming associations.
+	Direction string `json:"direction"`
+
+	// Limit caps the number of associations followed from a single entity.
+	Limit int `json:"limit"`
+}
+
+// Traverse is a breadth-first traversal starting at a single entity.
+type Traverse struct {
+	TenantID model.ID `json:"tenant_id"`
+	Start    model.ID `json:"start" binding:"required"`
+	Hops     []Hop    `json:"hops" binding:"required"`
+
+	// MaxNodes caps the total of entities visited.
+	MaxNodes int `json:"max_nodes"`
+}
+
+// ShortestPath looks for the shortest path of outgoing associations between two entities.
+type ShortestPath struct {
+	TenantID model.ID `json:"tenant_id"`
+	From     model.ID `json:"from" binding:"required"`
+	To       model.ID `json:"to" binding:"required"`
+	Types    []string `json:"atypes"`
+	MaxDepth int      `json:"max_depth"`
+}
+
+// Node is an entity visited during a traversal.
+type Node struct {
+	*entity.Entity
+	Depth int `json:"depth"`
+}
+
+// Result holds the entities and associations visited during a traversal.
+type Result struct {
+	Entities     []*Node                    `json:"entities"`
+	Associations []*association.Association `json:"associations"`
+
+	// Truncated is set when a limit or the timeout interrupted the traversal.
+	Truncated bool `json:"truncated"`
+}
+
+// Path is an ordered list of entities and the associations linking them.
+type Path struct {
+	Entities     []*entity.Entity           `json:"entities"`
+	Associations []*association.Association `json:"associations"`
+}
+
+// Query runs a statement of the query language, see package graph/query.
+type Query struct {
+	TenantID   model.ID          `json:"tenant_id"`
+	Statement  string            `json:"query" binding:"required"`
+	Explain    bool              `json:"explain"`
+	Pagination *model.Pagination `json:"-"`
+}
+
+// QueryResult holds the rows matched by a query, or its plan when explained.
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Plan    *Plan    `json:"plan,omitempty"`
+
+	// Rows are the items of the page.
+	*model.Page[Row]
+
+	// Truncated is set when the timeout or the scan limit interrupted the query, or when an
+	// entity had more associations than the fan-out limit.
+	Truncated bool `json:"truncated"`
+}
+
+func (h *Hop) acceptEntity(e *entity.Entity) bool {
+	if len(h.EntityTypes) == 0 {
+		return true
+	}
+
+	for _, otype := range h.EntityTypes {
+		if otype == e.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+// next returns the entity reached by following assoc in the direction of the hop.
+func (h *Hop) next(assoc *association.Association) model.ID {
+	if h.Direction == DirectionIn {
+		return assoc.In
+	}
+
+	return assoc.Out
+}
+
+func (h *Hop) types() []string {
+	if len(h.Types) == 0 {
+		return []string{association.AnyType}
+	}
+
+	return h.Types
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// KeyPrefix starts every API key, telling them apart from JWTs.
+const KeyPrefix = "esk_"
+
+// Key is an API key of a tenant. Only the SHA-256 hash of its secret is stored; the secret is
+// returned once, when the key is created or rotated.
+type Key struct {
+	ID        model.ID   `json:"id"`
+	TenantID  model.ID   `json:"tenant_id"`
+	Name      string     `json:"name"`
+	Roles     []string   `json:"roles"`
+	Hash      string     `json:"-"`
+	CreatedAt *time.Time `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Token is the credential of the key, "esk_<id>_<secret>", only set when it is issued.
+	Token string `json:"token,omitempty"`
+}
+
+// KeyStore persists API keys. Keys are looked up by ID alone to authenticate requests, the tenant
+// being derived from the key.
+type KeyStore interface {
+	GetKey(ctx context.Context, id model.ID) (*Key, error)
+	ListKeys(ctx context.Context, tenantID model.ID) ([]*Key, error)
+	PutKey(ctx context.Context, k *Key) error
+}
+
+// Keys manages the API keys of tenants.
+type Keys struct {
+	store KeyStore
+	now   func() time.Time
+}
+
+func NewKeys(store KeyStore) *Keys {
+	return &Keys{store: store, now: time.Now}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hash(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// GrantedRoles returns the roles of the key. Keys issued before roles existed keep the full access
+// they had.
+func (k *Key) GrantedRoles() []string {
+	if k.Roles == nil {
+		return []string{RoleAdmin}
+	}
+
+	return k.Roles
+}
+
+// issue sets a new secret to k and returns its token.
+func (k *Key) issue() {
+	secret := randomHex(32)
+	k.Hash = hash(secret)
+	k.Token = fmt.Sprintf("%s%s_%s", KeyPrefix, k.ID, secret)
+}
+
+// Create issues a new API key of the tenant holding roles, at least one. The returned key holds
+// its token. Callers check that the roles may be granted, see Policies.AuthorizeGrant.
+func (m *Keys) Create(ctx context.Context, tenantID model.ID, name string, roles []string) (*Key, error) {
+	const op errors.Op = "auth/Keys.Create"
+
+	if tenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if len(roles) == 0 {
+		return nil, errors.E(op, errors.Invalid, "an API key needs at least one role")
+	}
+
+	for _, role := range roles {
+		if role == "" {
+			return nil, errors.E(op, errors.Invalid, "roles cannot be empty")
+		}
+	}
+
+	now := m.now().UTC()
+	k := &Key{
+		ID:        model.ID(randomHex(8)),
+		TenantID:  tenantID,
+		Name:      name,
+		Roles:     roles,
+		CreatedAt: &now,
+	}
+	k.issue()
+
+	if err := m.store.PutKey(ctx, k); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return k, nil
+}
+
+// Get returns an API key of the tenant, without its token.
+func (m *Keys) Get(ctx context.Context, id model.ID, tenantID model.ID) (*Key, error) {
+	const op errors.Op = "auth/Keys.Get"
+
+	k, err := m.store.GetKey(ctx, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// Keys of other tenants are not disclosed.
+	if k.TenantID != tenantID {
+		return nil, errors.E(op, errors.NotFound, fmt.Sprintf("API key %s not found", id))
+	}
+
+	return k, nil
+}
+
+// List returns the API keys of the tenant, without their tokens.
+func (m *Keys) List(ctx context.Context, tenantID model.ID) ([]*Key, error) {
+	const op errors.Op = "auth/Keys.List"
+
+	keys, err := m.store.ListKeys(ctx, tenantID)
+	if err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return keys, nil
+}
+
+// Rotate replaces the secret of an API key, invalidating the previous token. The returned key
+// holds the new token.
+func (m *Keys) Rotate(ctx context.Context, id model.ID, tenantID model.ID) (*Key, error) {
+	const op errors.Op = "auth/Keys.Rotate"
+
+	k, err := m.Get(ctx, id, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if k.RevokedAt != nil {
+		return nil, errors.E(op, errors.Conflict, fmt.Sprintf("API key %s is revoked", id))
+	}
+
+	now := m.now().UTC()
+	k.RotatedAt = &now
+	k.issue()
+
+	if err := m.store.PutKey(ctx, k); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return k, nil
+}
+
+// Revoke disables an API key for good.
+func (m *Keys) Revoke(ctx context.Context, id model.ID, tenantID model.ID) (*Key, error) {
+	const op errors.Op = "auth/Keys.Revoke"
+
+	k, err := m.Get(ctx, id, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if k.RevokedAt == nil {
+		now := m.now().UTC()
+		k.RevokedAt = &now
+
+		if err := m.store.PutKey(ctx, k); err != nil {
+			return nil, errors.E(op, errors.Transient, err)
+		}
+	}
+
+	return k, nil
+}
+
+// Authenticate returns the principal of an API key token.
+func (m *Keys) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	const op errors.Op = "auth/Keys.Authenticate"
+
+	parts := strings.SplitN(strings.TrimPrefix(token, KeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.E(op, errors.Permission, "malformed API key")
+	}
+
+	k, err := m.store.GetKey(ctx, model.ID(parts[0]))
+	if errors.Is(errors.NotFound, err) {
+		return nil, errors.E(op, errors.Permission, "invalid API key")
+	}
+
+	if err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(parts[1])), []byte(k.Hash)) != 1 {
+		return nil, errors.E(op, errors.Permission, "invalid API key")
+	}
+
+	if k.RevokedAt != nil {
+		return nil, errors.E(op, errors.Permission, "revoked API key")
+	}
+
+	return &Principal{TenantID: k.TenantID, Subject: string(k.ID), Method: MethodAPIKey, Roles: k.GrantedRoles()}, nil
+}
+
+func notFound(id model.ID) error {
+	return errors.E(errors.NotFound, fmt.Sprintf("API key %s not found", id))
+}
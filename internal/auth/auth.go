@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Methods of authentication.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	TenantID model.ID `json:"tenant_id"`
+	Subject  string   `json:"subject"`
+	Method   string   `json:"method"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or nil when the request is not authenticated.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+type Config struct {
+	Keys   KeyStore
+	Logger logrus.FieldLogger
+
+	// JWKS are paths of JSON Web Key Set files holding the keys that sign accepted JWTs.
+	// JWTs are rejected when empty.
+	JWKS []string
+
+	// Issuer and Audience, when set, must match the iss and aud claims of JWTs.
+	Issuer   string
+	Audience string
+
+	// TenantClaim names the JWT claim holding the tenant. Defaults to DefaultTenantClaim.
+	TenantClaim string
+
+	// RolesClaim names the JWT claim holding the roles of the caller. Defaults to DefaultRolesClaim.
+	RolesClaim string
+}
+
+// Authenticator authenticates the bearer credentials of requests, either API keys or JWTs.
+type Authenticator struct {
+	keys   *Keys
+	jwt    *JWTVerifier
+	logger logrus.FieldLogger
+}
+
+func New(cfg *Config) (*Authenticator, error) {
+	const op errors.Op = "auth/New"
+
+	a := &Authenticator{
+		keys:   NewKeys(cfg.Keys),
+		logger: cfg.Logger.WithField("component", "authenticator"),
+	}
+
+	if len(cfg.JWKS) > 0 {
+		set, err := LoadJWKS(cfg.JWKS...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		a.jwt = NewJWTVerifier(set, cfg.Issuer, cfg.Audience, cfg.TenantClaim, cfg.RolesClaim)
+	}
+
+	return a, nil
+}
+
+// Keys returns the API key manager.
+func (a *Authenticator) Keys() *Keys {
+	return a.keys
+}
+
+// Authenticate returns the principal of a bearer credential. Unknown, revoked, expired or
+// malformed credentials return a Permission error.
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	const op errors.Op = "auth/Authenticator.Authenticate"
+
+	if credential == "" {
+		return nil, errors.E(op, errors.Permission, "missing credential")
+	}
+
+	if strings.HasPrefix(credential, KeyPrefix) {
+		return a.keys.Authenticate(ctx, credential)
+	}
+
+	if a.jwt == nil {
+		return nil, errors.E(op, errors.Permission, "JWT authentication is not configured")
+	}
+
+	return a.jwt.Verify(credential)
+}
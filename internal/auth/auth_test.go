@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+func TestKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeys(NewInMemory())
+
+	_, err := keys.Create(ctx, "tenant", "ci", nil)
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	k, err := keys.Create(ctx, "tenant", "ci", []string{RoleAdmin})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(k.Token, KeyPrefix))
+	assert.NotContains(t, k.Hash, strings.Split(k.Token, "_")[2])
+
+	p, err := keys.Authenticate(ctx, k.Token)
+	require.NoError(t, err)
+	assert.Equal(t, model.ID("tenant"), p.TenantID)
+	assert.Equal(t, MethodAPIKey, p.Method)
+	assert.Equal(t, []string{RoleAdmin}, p.Roles)
+
+	listed, err := keys.List(ctx, "tenant")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Token)
+
+	_, err = keys.Get(ctx, k.ID, "other")
+	assert.True(t, errors.Is(errors.NotFound, err))
+
+	rotated, err := keys.Rotate(ctx, k.ID, "tenant")
+	require.NoError(t, err)
+	assert.NotEqual(t, k.Token, rotated.Token)
+
+	_, err = keys.Authenticate(ctx, k.Token)
+	assert.True(t, errors.Is(errors.Permission, err))
+
+	_, err = keys.Authenticate(ctx, rotated.Token)
+	require.NoError(t, err)
+
+	_, err = keys.Revoke(ctx, k.ID, "tenant")
+	require.NoError(t, err)
+
+	_, err = keys.Authenticate(ctx, rotated.Token)
+	assert.True(t, errors.Is(errors.Permission, err))
+
+	_, err = keys.Rotate(ctx, k.ID, "tenant")
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	for _, token := range []string{"esk_", "esk_unknown_secret", KeyPrefix + string(k.ID)} {
+		_, err = keys.Authenticate(ctx, token)
+		assert.True(t, errors.Is(errors.Permission, err), token)
+	}
+}
+
+func encode(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// writeJWKS writes the public keys of rsaKey and ecKey to a JWKS file.
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		},
+	}
+
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, b, 0600))
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestAuthenticator_JWT(t *testing.T) {
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	a, err := New(&Config{
+		Keys:     NewInMemory(),
+		Logger:   newLogger(),
+		JWKS:     []string{writeJWKS(t, rsaKey, ecKey)},
+		Issuer:   "https://issuer.example.com",
+		Audience: "edgestore",
+	})
+	require.NoError(t, err)
+
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    "https://issuer.example.com",
+			"aud":    "edgestore",
+			"sub":    "alice",
+			"tenant": "acme",
+			"roles":  []string{"reader", "billing"},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	p, err := a.Authenticate(ctx, sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims()))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{TenantID: "acme", Subject: "alice", Method: MethodJWT, Roles: []string{"reader", "billing"}}, p)
+
+	p, err = a.Authenticate(ctx, sign(t, jwt.SigningMethodES256, "ec", ecKey, claims()))
+	require.NoError(t, err)
+	assert.Equal(t, model.ID("acme"), p.TenantID)
+
+	expired := claims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	noExpiration := claims()
+	delete(noExpiration, "exp")
+
+	noTenant := claims()
+	delete(noTenant, "tenant")
+
+	wrongAudience := claims()
+	wrongAudience["aud"] = "other"
+
+	rejected := map[string]string{
+		"unknown signer": sign(t, jwt.SigningMethodRS256, "rsa", otherKey, claims()),
+		"unknown key":    sign(t, jwt.SigningMethodRS256, "missing", rsaKey, claims()),
+		"expired":        sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, expired),
+		"no expiration":  sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, noExpiration),
+		"no tenant":      sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, noTenant),
+		"wrong audience": sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, wrongAudience),
+		"hmac":           sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), claims()),
+		"malformed":      "not.a.token",
+		"empty":          "",
+	}
+
+	for name, token := range rejected {
+		_, err := a.Authenticate(ctx, token)
+		assert.True(t, errors.Is(errors.Permission, err), name)
+	}
+}
+
+func TestAuthenticator_NoJWKS(t *testing.T) {
+	a, err := New(&Config{Keys: NewInMemory(), Logger: newLogger()})
+	require.NoError(t, err)
+
+	_, err = a.Authenticate(context.Background(), "eyJhbGciOiJSUzI1NiJ9.e30.c2ln")
+	assert.True(t, errors.Is(errors.Permission, err))
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// InMemory is a KeyStore and PolicyStore for tests and single node deployments.
+type InMemory struct {
+	mux      sync.Mutex
+	keys     map[model.ID]Key
+	policies map[model.ID]map[string]Policy
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{keys: map[model.ID]Key{}, policies: map[model.ID]map[string]Policy{}}
+}
+
+// GetKey implements the KeyStore interface.
+func (m *InMemory) GetKey(ctx context.Context, id model.ID) (*Key, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	k, ok := m.keys[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	return &k, nil
+}
+
+// ListKeys implements the KeyStore interface.
+func (m *InMemory) ListKeys(ctx context.Context, tenantID model.ID) ([]*Key, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var keys []*Key
+	for _, k := range m.keys {
+		if k.TenantID == tenantID {
+			k := k
+			keys = append(keys, &k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(*keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// PutKey implements the KeyStore interface.
+func (m *InMemory) PutKey(ctx context.Context, k *Key) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	stored := *k
+	stored.Token = ""
+	m.keys[k.ID] = stored
+	return nil
+}
+
+// ListPolicies implements the PolicyStore interface.
+func (m *InMemory) ListPolicies(ctx context.Context, tenantID model.ID) ([]*Policy, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	policies := make([]*Policy, 0, len(m.policies[tenantID]))
+	for _, p := range m.policies[tenantID] {
+		p := p
+		policies = append(policies, &p)
+	}
+
+	return policies, nil
+}
+
+// PutPolicy implements the PolicyStore interface.
+func (m *InMemory) PutPolicy(ctx context.Context, p *Policy) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.policies[p.TenantID] == nil {
+		m.policies[p.TenantID] = map[string]Policy{}
+	}
+
+	m.policies[p.TenantID][p.Role] = *p
+	return nil
+}
+
+// DeletePolicy implements the PolicyStore interface.
+func (m *InMemory) DeletePolicy(ctx context.Context, tenantID model.ID, role string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.policies[tenantID], role)
+	return nil
+}
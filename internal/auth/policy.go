@@ -0,0 +1,415 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// Resources of scopes. Tenant scopes guard the administration of the tenant, their type being
+// api-keys, policies or webhooks.
+const (
+	ResourceAssociations = "associations"
+	ResourceDefinitions  = "definitions"
+	ResourceEntities     = "entities"
+	ResourceTenant       = "tenant"
+)
+
+// Verbs of scopes.
+const (
+	VerbRead  = "read"
+	VerbWrite = "write"
+)
+
+// Wildcard matches any resource, verb or type of a scope.
+const Wildcard = "*"
+
+// Built-in roles, granted by every tenant.
+const (
+	RoleAdmin  = "admin"
+	RoleReader = "reader"
+	RoleWriter = "writer"
+)
+
+// BuiltinRoles are the scopes of the built-in roles. Their policies cannot be changed.
+var BuiltinRoles = map[string][]string{
+	RoleAdmin: {"*:*:*"},
+	RoleReader: {
+		"associations:read:*",
+		"definitions:read:*",
+		"entities:read:*",
+	},
+	RoleWriter: {
+		"associations:*:*",
+		"definitions:read:*",
+		"entities:*:*",
+	},
+}
+
+// DefaultPolicyTTL bounds how long the policies of a tenant are cached before being reloaded, for
+// changes made by other nodes.
+const DefaultPolicyTTL = 30 * time.Second
+
+// Policy grants scopes, "<resource>:<verb>:<type>" such as entities:read:invoice, to the principals
+// holding its role or named among its subjects.
+type Policy struct {
+	TenantID  model.ID   `json:"tenant_id"`
+	Role      string     `json:"role"`
+	Scopes    []string   `json:"scopes"`
+	Subjects  []string   `json:"subjects,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func (p *Policy) applies(principal *Principal) bool {
+	return contains(principal.Roles, p.Role) || contains(p.Subjects, principal.Subject)
+}
+
+func (p *Policy) grants(resource string, verb string, typ string) bool {
+	for _, scope := range p.Scopes {
+		if MatchScope(scope, resource, verb, typ) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func match(pattern string, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
+
+// MatchScope reports whether scope grants the verb on the resource of the given type.
+func MatchScope(scope string, resource string, verb string, typ string) bool {
+	parts := strings.SplitN(scope, ":", 3)
+	if len(parts) != 3 {
+		return false
+	}
+
+	return match(parts[0], resource) && match(parts[1], verb) && match(parts[2], typ)
+}
+
+// coversScope reports whether held grants at least the scope requested, both being valid scopes.
+func coversScope(held string, requested string) bool {
+	h, r := strings.SplitN(held, ":", 3), strings.SplitN(requested, ":", 3)
+	if len(h) != 3 || len(r) != 3 {
+		return false
+	}
+
+	for i := range h {
+		if h[i] != Wildcard && h[i] != r[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidScope returns an error when scope is not of the form "<resource>:<verb>:<type>".
+func ValidScope(scope string) error {
+	parts := strings.Split(scope, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("scope %q is not of the form <resource>:<verb>:<type>", scope)
+	}
+
+	switch parts[0] {
+	case ResourceAssociations, ResourceDefinitions, ResourceEntities, ResourceTenant, Wildcard:
+	default:
+		return fmt.Errorf("scope %q has an unknown resource %q", scope, parts[0])
+	}
+
+	switch parts[1] {
+	case VerbRead, VerbWrite, Wildcard:
+	default:
+		return fmt.Errorf("scope %q has an unknown verb %q", scope, parts[1])
+	}
+
+	if parts[2] == "" {
+		return fmt.Errorf("scope %q has no type", scope)
+	}
+
+	return nil
+}
+
+// Authorizer authorizes the operations of the principal of a context.
+type Authorizer interface {
+	Authorize(ctx context.Context, tenantID model.ID, resource string, verb string, typ string) error
+}
+
+// PolicyStore persists the policies of tenants.
+type PolicyStore interface {
+	ListPolicies(ctx context.Context, tenantID model.ID) ([]*Policy, error)
+	PutPolicy(ctx context.Context, p *Policy) error
+	DeletePolicy(ctx context.Context, tenantID model.ID, role string) error
+}
+
+type cachedPolicies struct {
+	policies []*Policy
+	expires  time.Time
+}
+
+// Policies manages the policies of tenants and authorizes operations against them. Policies are
+// cached in memory for a TTL, and reloaded as soon as they are changed through this node.
+type Policies struct {
+	store PolicyStore
+	ttl   time.Duration
+	now   func() time.Time
+
+	mux   sync.Mutex
+	cache map[model.ID]*cachedPolicies
+}
+
+func NewPolicies(store PolicyStore, ttl time.Duration) *Policies {
+	if ttl <= 0 {
+		ttl = DefaultPolicyTTL
+	}
+
+	return &Policies{
+		store: store,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: map[model.ID]*cachedPolicies{},
+	}
+}
+
+func (e *Policies) load(ctx context.Context, tenantID model.ID) ([]*Policy, error) {
+	e.mux.Lock()
+	cached, ok := e.cache[tenantID]
+	e.mux.Unlock()
+
+	if ok && e.now().Before(cached.expires) {
+		return cached.policies, nil
+	}
+
+	policies, err := e.store.ListPolicies(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Role < policies[j].Role })
+
+	e.mux.Lock()
+	e.cache[tenantID] = &cachedPolicies{policies: policies, expires: e.now().Add(e.ttl)}
+	e.mux.Unlock()
+
+	return policies, nil
+}
+
+func (e *Policies) invalidate(tenantID model.ID) {
+	e.mux.Lock()
+	delete(e.cache, tenantID)
+	e.mux.Unlock()
+}
+
+// List returns the policies of the tenant, ordered by role.
+func (e *Policies) List(ctx context.Context, tenantID model.ID) ([]*Policy, error) {
+	const op errors.Op = "auth/Policies.List"
+
+	policies, err := e.load(ctx, tenantID)
+	if err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return policies, nil
+}
+
+// Get returns the policy of a role of the tenant.
+func (e *Policies) Get(ctx context.Context, tenantID model.ID, role string) (*Policy, error) {
+	const op errors.Op = "auth/Policies.Get"
+
+	policies, err := e.List(ctx, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	for _, p := range policies {
+		if p.Role == role {
+			return p, nil
+		}
+	}
+
+	return nil, errors.E(op, errors.NotFound, fmt.Sprintf("policy %s not found", role))
+}
+
+// Put creates or replaces the policy of a role. Built-in roles are reserved, and the principal of
+// ctx must hold every scope the policy grants, see AuthorizeGrant.
+func (e *Policies) Put(ctx context.Context, p *Policy) (*Policy, error) {
+	const op errors.Op = "auth/Policies.Put"
+
+	if p.TenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if p.Role == "" || strings.ContainsAny(p.Role, ": ") {
+		return nil, errors.E(op, errors.Invalid, fmt.Sprintf("invalid role %q", p.Role))
+	}
+
+	if _, ok := BuiltinRoles[p.Role]; ok {
+		return nil, errors.E(op, errors.Invalid, fmt.Sprintf("role %s is built-in", p.Role))
+	}
+
+	for _, scope := range p.Scopes {
+		if err := ValidScope(scope); err != nil {
+			return nil, errors.E(op, errors.Invalid, err)
+		}
+	}
+
+	if err := e.AuthorizeGrant(ctx, p.TenantID, nil, p.Scopes); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	now := e.now().UTC()
+	p.UpdatedAt = &now
+
+	if err := e.store.PutPolicy(ctx, p); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	e.invalidate(p.TenantID)
+	return p, nil
+}
+
+// Delete removes the policy of a role.
+func (e *Policies) Delete(ctx context.Context, tenantID model.ID, role string) error {
+	const op errors.Op = "auth/Policies.Delete"
+
+	if _, err := e.Get(ctx, tenantID, role); err != nil {
+		return errors.E(op, err)
+	}
+
+	if err := e.store.DeletePolicy(ctx, tenantID, role); err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	e.invalidate(tenantID)
+	return nil
+}
+
+// Authorize implements the Authorizer interface. Contexts without a principal, such as requests
+// trusted with their tenant header and calls the services make on their own behalf, are allowed.
+func (e *Policies) Authorize(ctx context.Context, tenantID model.ID, resource string, verb string, typ string) error {
+	const op errors.Op = "auth/Policies.Authorize"
+
+	principal := FromContext(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	if principal.TenantID != tenantID {
+		return errors.E(op, errors.Permission, fmt.Sprintf("no access to tenant %s", tenantID))
+	}
+
+	for _, role := range principal.Roles {
+		builtin := &Policy{Scopes: BuiltinRoles[role]}
+		if builtin.grants(resource, verb, typ) {
+			return nil
+		}
+	}
+
+	policies, err := e.load(ctx, tenantID)
+	if err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	for _, p := range policies {
+		if p.applies(principal) && p.grants(resource, verb, typ) {
+			return nil
+		}
+	}
+
+	return errors.E(op, errors.Permission, fmt.Sprintf("%s:%s:%s is not granted", resource, verb, typ))
+}
+
+// AuthorizeGrant checks that the principal of ctx holds the roles and scopes it grants to others,
+// by issuing an API key or putting a policy, so that no principal grants more than it holds. A
+// role is held when the principal has it, or holds every scope of its policy. Contexts without a
+// principal are allowed, as by Authorize.
+func (e *Policies) AuthorizeGrant(ctx context.Context, tenantID model.ID, roles []string, scopes []string) error {
+	const op errors.Op = "auth/Policies.AuthorizeGrant"
+
+	principal := FromContext(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	if principal.TenantID != tenantID {
+		return errors.E(op, errors.Permission, fmt.Sprintf("no access to tenant %s", tenantID))
+	}
+
+	policies, err := e.load(ctx, tenantID)
+	if err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	var held []string
+	for _, role := range principal.Roles {
+		held = append(held, BuiltinRoles[role]...)
+	}
+	for _, p := range policies {
+		if p.applies(principal) {
+			held = append(held, p.Scopes...)
+		}
+	}
+
+	requested := append([]string{}, scopes...)
+	for _, role := range roles {
+		if contains(principal.Roles, role) {
+			continue
+		}
+
+		if builtin, ok := BuiltinRoles[role]; ok {
+			requested = append(requested, builtin...)
+			continue
+		}
+
+		found := false
+		for _, p := range policies {
+			if p.Role == role {
+				requested = append(requested, p.Scopes...)
+				found = true
+			}
+		}
+
+		// The scopes of a role without policy are not known yet.
+		if !found {
+			return errors.E(op, errors.Permission, fmt.Sprintf("role %s is not held and has no policy", role))
+		}
+	}
+
+	for _, scope := range requested {
+		covered := false
+		for _, h := range held {
+			if coversScope(h, scope) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			return errors.E(op, errors.Permission, errors.Code("grant_exceeds_caller"), fmt.Sprintf("%s is not held by the caller", scope))
+		}
+	}
+
+	return nil
+}
+
+// Internal returns a copy of ctx without principal, for the lookups a service makes on its own
+// behalf, such as loading the ends of an association to validate it.
+func Internal(ctx context.Context) context.Context {
+	return NewContext(ctx, nil)
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchScope(t *testing.T) {
+	assert.True(t, MatchScope("entities:read:invoice", ResourceEntities, VerbRead, "invoice"))
+	assert.True(t, MatchScope("associations:write:*", ResourceAssociations, VerbWrite, "follows"))
+	assert.True(t, MatchScope("*:*:*", ResourceTenant, VerbWrite, "policies"))
+	assert.False(t, MatchScope("entities:read:invoice", ResourceEntities, VerbWrite, "invoice"))
+	assert.False(t, MatchScope("entities:read:invoice", ResourceEntities, VerbRead, "user"))
+	assert.False(t, MatchScope("entities:read", ResourceEntities, VerbRead, "read"))
+
+	assert.NoError(t, ValidScope("entities:read:invoice"))
+	assert.Error(t, ValidScope("entities:read"))
+	assert.Error(t, ValidScope("users:read:*"))
+	assert.Error(t, ValidScope("entities:delete:*"))
+	assert.Error(t, ValidScope("entities:read:"))
+}
+
+func TestPolicies_Authorize(t *testing.T) {
+	policies := NewPolicies(NewInMemory(), time.Minute)
+	authorize := func(p *Principal, resource string, verb string, typ string) error {
+		return policies.Authorize(NewContext(context.Background(), p), "acme", resource, verb, typ)
+	}
+
+	reader := &Principal{TenantID: "acme", Subject: "alice", Roles: []string{RoleReader}}
+	assert.NoError(t, authorize(reader, ResourceEntities, VerbRead, "invoice"))
+	assert.True(t, errors.Is(errors.Permission, authorize(reader, ResourceEntities, VerbWrite, "invoice")))
+
+	other := &Principal{TenantID: "other", Roles: []string{RoleAdmin}}
+	assert.True(t, errors.Is(errors.Permission, authorize(other, ResourceEntities, VerbRead, "invoice")))
+
+	// Contexts without principal are trusted.
+	assert.NoError(t, policies.Authorize(context.Background(), "acme", ResourceEntities, VerbWrite, "invoice"))
+	assert.NoError(t, policies.Authorize(Internal(NewContext(context.Background(), reader)), "acme", ResourceEntities, VerbWrite, "invoice"))
+
+	ctx := context.Background()
+	_, err := policies.Put(ctx, &Policy{TenantID: "acme", Role: "billing", Scopes: []string{"entities:*:invoice"}, Subjects: []string{"bob"}})
+	require.NoError(t, err)
+
+	billing := &Principal{TenantID: "acme", Subject: "carol", Roles: []string{"billing"}}
+	assert.NoError(t, authorize(billing, ResourceEntities, VerbWrite, "invoice"))
+	assert.True(t, errors.Is(errors.Permission, authorize(billing, ResourceEntities, VerbRead, "user")))
+
+	bob := &Principal{TenantID: "acme", Subject: "bob"}
+	assert.NoError(t, authorize(bob, ResourceEntities, VerbRead, "invoice"))
+
+	require.NoError(t, policies.Delete(ctx, "acme", "billing"))
+	assert.True(t, errors.Is(errors.Permission, authorize(billing, ResourceEntities, VerbWrite, "invoice")))
+	assert.True(t, errors.Is(errors.NotFound, policies.Delete(ctx, "acme", "billing")))
+}
+
+func TestPolicies_Put(t *testing.T) {
+	ctx := context.Background()
+	policies := NewPolicies(NewInMemory(), time.Minute)
+
+	_, err := policies.Put(ctx, &Policy{TenantID: "acme", Role: RoleAdmin, Scopes: []string{"*:*:*"}})
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = policies.Put(ctx, &Policy{TenantID: "acme", Role: "billing", Scopes: []string{"invoices:read:*"}})
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = policies.Put(ctx, &Policy{TenantID: "acme", Role: "billing", Scopes: []string{"entities:read:invoice"}})
+	require.NoError(t, err)
+
+	p, err := policies.Get(ctx, "acme", "billing")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"entities:read:invoice"}, p.Scopes)
+	assert.NotNil(t, p.UpdatedAt)
+
+	_, err = policies.Get(ctx, "other", "billing")
+	assert.True(t, errors.Is(errors.NotFound, err))
+}
+
+func TestPolicies_TTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory()
+	policies := NewPolicies(store, time.Minute)
+	now := time.Now()
+	policies.now = func() time.Time { return now }
+
+	p := &Principal{TenantID: "acme", Subject: "alice", Roles: []string{"billing"}}
+	assert.Error(t, policies.Authorize(NewContext(ctx, p), "acme", ResourceEntities, VerbRead, "invoice"))
+
+	// Changed by another node, seen once the cache expires.
+	require.NoError(t, store.PutPolicy(ctx, &Policy{TenantID: "acme", Role: "billing", Scopes: []string{"entities:read:*"}}))
+	assert.Error(t, policies.Authorize(NewContext(ctx, p), "acme", ResourceEntities, VerbRead, "invoice"))
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, policies.Authorize(NewContext(ctx, p), "acme", ResourceEntities, VerbRead, "invoice"))
+}
+
+func TestPolicies_AuthorizeGrant(t *testing.T) {
+	ctx := context.Background()
+	policies := NewPolicies(NewInMemory(), time.Minute)
+	_, err := policies.Put(ctx, &Policy{TenantID: "acme", Role: "keys", Scopes: []string{"tenant:write:api-keys", "tenant:write:policies"}})
+	require.NoError(t, err)
+	_, err = policies.Put(ctx, &Policy{TenantID: "acme", Role: "billing", Scopes: []string{"entities:read:invoice"}})
+	require.NoError(t, err)
+
+	keys := NewContext(ctx, &Principal{TenantID: "acme", Subject: "k1", Roles: []string{"keys"}})
+	writer := NewContext(ctx, &Principal{TenantID: "acme", Subject: "w1", Roles: []string{RoleWriter}})
+	admin := NewContext(ctx, &Principal{TenantID: "acme", Subject: "a1", Roles: []string{RoleAdmin}})
+
+	// A principal grants the roles it has, and the roles whose scopes it holds.
+	assert.NoError(t, policies.AuthorizeGrant(keys, "acme", []string{"keys"}, nil))
+	assert.NoError(t, policies.AuthorizeGrant(writer, "acme", []string{RoleReader, "billing"}, nil))
+	assert.NoError(t, policies.AuthorizeGrant(admin, "acme", []string{RoleAdmin, "billing"}, []string{"*:*:*"}))
+	assert.NoError(t, policies.AuthorizeGrant(ctx, "acme", []string{RoleAdmin}, nil))
+
+	// Granting the API keys scope does not grant admin keys.
+	err = policies.AuthorizeGrant(keys, "acme", []string{RoleAdmin}, nil)
+	assert.True(t, errors.Is(errors.Permission, err))
+	assert.Equal(t, errors.Code("grant_exceeds_caller"), errors.CodeOf(err))
+	assert.True(t, errors.Is(errors.Permission, policies.AuthorizeGrant(keys, "acme", []string{RoleReader}, nil)))
+	assert.True(t, errors.Is(errors.Permission, policies.AuthorizeGrant(writer, "acme", []string{"undefined"}, nil)))
+	assert.True(t, errors.Is(errors.Permission, policies.AuthorizeGrant(writer, "acme", nil, []string{"definitions:write:*"})))
+	assert.True(t, errors.Is(errors.Permission, policies.AuthorizeGrant(admin, "other", nil, nil)))
+
+	// Nor does the policies scope grant policies listing the caller with more scopes.
+	_, err = policies.Put(keys, &Policy{TenantID: "acme", Role: "escalate", Scopes: []string{"*:*:*"}, Subjects: []string{"k1"}})
+	assert.True(t, errors.Is(errors.Permission, err))
+	_, err = policies.Get(ctx, "acme", "escalate")
+	assert.True(t, errors.Is(errors.NotFound, err))
+
+	_, err = policies.Put(keys, &Policy{TenantID: "acme", Role: "rotate", Scopes: []string{"tenant:write:api-keys"}})
+	assert.NoError(t, err)
+}
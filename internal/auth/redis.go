@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a KeyStore keeping API keys as JSON strings, indexed by tenant, and a PolicyStore
+// keeping the policies of a tenant in a hash.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// storedKey is the JSON encoding of a key, including the hash of its secret.
+type storedKey struct {
+	Key
+	Hash  string `json:"hash"`
+	Token string `json:"token,omitempty"`
+}
+
+func (r *Redis) key(id model.ID) string {
+	return fmt.Sprintf("%s:apikey:%s", r.prefix, id)
+}
+
+func (r *Redis) tenantKey(tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:apikeys", r.prefix, tenantID)
+}
+
+func (r *Redis) policiesKey(tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:policies", r.prefix, tenantID)
+}
+
+// GetKey implements the KeyStore interface.
+func (r *Redis) GetKey(ctx context.Context, id model.ID) (*Key, error) {
+	b, err := r.client.Get(ctx, r.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, notFound(id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stored storedKey
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+
+	k := stored.Key
+	k.Hash = stored.Hash
+	return &k, nil
+}
+
+// ListKeys implements the KeyStore interface.
+func (r *Redis) ListKeys(ctx context.Context, tenantID model.ID) ([]*Key, error) {
+	ids, err := r.client.SMembers(ctx, r.tenantKey(tenantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		k, err := r.GetKey(ctx, model.ID(id))
+		if err != nil {
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(*keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// PutKey implements the KeyStore interface. Tokens are never stored.
+func (r *Redis) PutKey(ctx context.Context, k *Key) error {
+	b, err := json.Marshal(storedKey{Key: *k, Hash: k.Hash})
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.Set(ctx, r.key(k.ID), b, 0).Err(); err != nil {
+		return err
+	}
+
+	return r.client.SAdd(ctx, r.tenantKey(k.TenantID), string(k.ID)).Err()
+}
+
+// ListPolicies implements the PolicyStore interface.
+func (r *Redis) ListPolicies(ctx context.Context, tenantID model.ID) ([]*Policy, error) {
+	values, err := r.client.HGetAll(ctx, r.policiesKey(tenantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]*Policy, 0, len(values))
+	for _, v := range values {
+		var p Policy
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, &p)
+	}
+
+	return policies, nil
+}
+
+// PutPolicy implements the PolicyStore interface.
+func (r *Redis) PutPolicy(ctx context.Context, p *Policy) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return r.client.HSet(ctx, r.policiesKey(p.TenantID), p.Role, b).Err()
+}
+
+// DeletePolicy implements the PolicyStore interface.
+func (r *Redis) DeletePolicy(ctx context.Context, tenantID model.ID, role string) error {
+	return r.client.HDel(ctx, r.policiesKey(tenantID), role).Err()
+}
@@ -0,0 +1,81 @@
+package rediscache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// RevRangeAfter returns up to p.Limit members of the sorted set key, highest score first, starting
+// after p.After. The returned cursor is the position of the last member, nil when no member follows.
+func RevRangeAfter(ctx context.Context, client *redis.Client, key string, p *model.Pagination) ([]string, *model.Cursor, error) {
+	max := "+inf"
+	if p.After != nil {
+		max = strconv.FormatFloat(p.After.Score, 'f', -1, 64)
+	}
+
+	return revRangeAfter(p, func(offset, count int64) ([]redis.Z, error) {
+		return client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    max,
+			Offset: offset,
+			Count:  count,
+		}).Result()
+	})
+}
+
+// revRangeAfter pages through the members fetched from the score of p.After downwards. Members
+// inserted at that score since the previous page shift the offset of the following ones; those
+// already returned are recognized by sorting before p.After and skipped.
+func revRangeAfter(p *model.Pagination, fetch func(offset, count int64) ([]redis.Z, error)) ([]string, *model.Cursor, error) {
+	if p.Limit <= 0 {
+		return nil, nil, nil
+	}
+
+	after := p.After
+	offset := int64(0)
+	if after != nil {
+		offset = int64(after.Offset)
+	}
+
+	var members []string
+	var last *model.Cursor
+	pos := after
+	for {
+		// One more member than needed tells whether another page follows.
+		count := int64(p.Limit + 1 - len(members))
+		zs, err := fetch(offset, count)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, z := range zs {
+			member, _ := z.Member.(string)
+
+			next := &model.Cursor{Score: z.Score, Key: member, Offset: 1}
+			if pos != nil && pos.Score == z.Score {
+				next.Offset = pos.Offset + 1
+			}
+			pos = next
+
+			if after != nil && z.Score == after.Score && member >= after.Key {
+				continue
+			}
+
+			if len(members) == p.Limit {
+				return members, last, nil
+			}
+
+			members = append(members, member)
+			last = pos
+		}
+
+		if int64(len(zs)) < count {
+			return members, nil, nil
+		}
+
+		offset += int64(len(zs))
+	}
+}
@@ -0,0 +1,101 @@
+package rediscache
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zset mimics ZREVRANGEBYSCORE with a maximum score, ordering members of equal score in reverse
+// lexicographical order as Redis does.
+type zset []redis.Z
+
+func (s zset) fetch(max float64) func(offset, count int64) ([]redis.Z, error) {
+	return func(offset, count int64) ([]redis.Z, error) {
+		sorted := append(zset(nil), s...)
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Score != sorted[j].Score {
+				return sorted[i].Score > sorted[j].Score
+			}
+			return sorted[i].Member.(string) > sorted[j].Member.(string)
+		})
+
+		var in []redis.Z
+		for _, z := range sorted {
+			if z.Score <= max {
+				in = append(in, z)
+			}
+		}
+
+		if offset >= int64(len(in)) {
+			return nil, nil
+		}
+
+		in = in[offset:]
+		if int64(len(in)) > count {
+			in = in[:count]
+		}
+
+		return in, nil
+	}
+}
+
+func (s zset) page(t *testing.T, limit int, after *model.Cursor) ([]string, *model.Cursor) {
+	max := float64(1 << 62)
+	if after != nil {
+		max = after.Score
+	}
+
+	members, next, err := revRangeAfter(model.NewPagination(limit, after), s.fetch(max))
+	require.NoError(t, err)
+	return members, next
+}
+
+func TestRevRangeAfter(t *testing.T) {
+	s := zset{
+		{Score: 3, Member: "a"},
+		{Score: 2, Member: "b"},
+		{Score: 2, Member: "c"},
+		{Score: 2, Member: "d"},
+		{Score: 1, Member: "e"},
+	}
+
+	members, next := s.page(t, 2, nil)
+	assert.Equal(t, []string{"a", "d"}, members)
+	require.NotNil(t, next)
+
+	members, next = s.page(t, 2, next)
+	assert.Equal(t, []string{"c", "b"}, members)
+	require.NotNil(t, next)
+
+	members, next = s.page(t, 2, next)
+	assert.Equal(t, []string{"e"}, members)
+	assert.Nil(t, next)
+
+	members, next = s.page(t, 5, nil)
+	assert.Len(t, members, 5)
+	assert.Nil(t, next)
+}
+
+func TestRevRangeAfter_Insert(t *testing.T) {
+	s := zset{
+		{Score: 2, Member: "b"},
+		{Score: 2, Member: "c"},
+		{Score: 2, Member: "d"},
+		{Score: 1, Member: "e"},
+	}
+
+	members, next := s.page(t, 2, nil)
+	assert.Equal(t, []string{"d", "c"}, members)
+
+	// Inserted before the cursor at the same score, shifting the remaining members.
+	s = append(s, redis.Z{Score: 2, Member: "z"})
+
+	members, next = s.page(t, 2, next)
+	assert.Equal(t, []string{"b", "e"}, members)
+	assert.Nil(t, next)
+}
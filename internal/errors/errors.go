@@ -17,6 +17,7 @@ type Error struct {
 	ID   model.ID
 	Op   Op
 	Kind Kind
+	Code Code
 
 	// The underlying error that triggered this one, if any.
 	Err error
@@ -26,13 +27,17 @@ type Error struct {
 }
 
 func (e *Error) isZero() bool {
-	return e.ID == "" && e.Op == "" && e.Kind == 0 && e.Err == nil
+	return e.ID == "" && e.Op == "" && e.Kind == 0 && e.Code == "" && e.Err == nil
 }
 
 // Op describes an operation, usually as the package and method,
 // such as "billing/models.CreateInvoice".
 type Op string
 
+// Code identifies an error more precisely than its kind, such as "quota_exceeded" for an
+// Exhausted error. Codes are part of the API: once returned, they must not change.
+type Code string
+
 // Separator is the string used to separate nested errors. By
 // default, to make errors easier on the eye, nested errors are
 // indented on a new line. A server may instead choose to keep each
@@ -59,6 +64,8 @@ const (
 	Private                // Information withheld.
 	Internal               // Internal error or inconsistency.
 	Transient              // A transient error.
+	Conflict               // Operation conflicts with the current state of an item.
+	Exhausted              // Rate limit or quota exceeded.
 )
 
 func (k Kind) String() string {
@@ -81,6 +88,10 @@ func (k Kind) String() string {
 		return "internal error"
 	case Transient:
 		return "transient error"
+	case Conflict:
+		return "conflict"
+	case Exhausted:
+		return "resource exhausted"
 	}
 	return "unknown error kind"
 }
@@ -100,6 +111,8 @@ func E(args ...interface{}) error {
 			e.Err = Str(arg)
 		case Kind:
 			e.Kind = arg
+		case Code:
+			e.Code = arg
 		case *Error:
 			// Make a copy
 			copy := *arg
@@ -134,6 +147,11 @@ func E(args ...interface{}) error {
 		e.Kind = prev.Kind
 		prev.Kind = Other
 	}
+	// Likewise for the Code.
+	if e.Code == "" {
+		e.Code = prev.Code
+		prev.Code = ""
+	}
 	return e
 }
 
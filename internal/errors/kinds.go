@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// kindInfo describes a kind to the clients of every transport.
+type kindInfo struct {
+	name   string
+	status int
+	code   codes.Code
+}
+
+// kinds maps kinds to their stable names, HTTP statuses and gRPC codes.
+var kinds = map[Kind]kindInfo{
+	Other:      {"other", http.StatusInternalServerError, codes.Unknown},
+	Invalid:    {"invalid", http.StatusBadRequest, codes.InvalidArgument},
+	Permission: {"permission", http.StatusUnauthorized, codes.Unauthenticated},
+	IO:         {"io", http.StatusServiceUnavailable, codes.Unavailable},
+	Duplicate:  {"duplicate", http.StatusBadRequest, codes.AlreadyExists},
+	NotFound:   {"not_found", http.StatusNotFound, codes.NotFound},
+	Private:    {"private", http.StatusForbidden, codes.PermissionDenied},
+	Internal:   {"internal", http.StatusInternalServerError, codes.Internal},
+	Transient:  {"transient", http.StatusServiceUnavailable, codes.Unavailable},
+	Conflict:   {"conflict", http.StatusConflict, codes.FailedPrecondition},
+	Exhausted:  {"exhausted", http.StatusTooManyRequests, codes.ResourceExhausted},
+}
+
+func (k Kind) info() kindInfo {
+	if info, ok := kinds[k]; ok {
+		return info
+	}
+
+	return kinds[Other]
+}
+
+// Name returns the stable, machine-readable name of k.
+func (k Kind) Name() string {
+	return k.info().name
+}
+
+// HTTPStatus returns the HTTP status of errors of kind k. Permission errors are 401, which
+// servers turn into 403 for authenticated requests.
+func (k Kind) HTTPStatus() int {
+	return k.info().status
+}
+
+// GRPCCode returns the gRPC status code of errors of kind k.
+func (k Kind) GRPCCode() codes.Code {
+	return k.info().code
+}
+
+// KindOf returns the kind of err, Other when it has none or is not an *Error.
+func KindOf(err error) Kind {
+	e, ok := err.(*Error)
+	for ok {
+		if e.Kind != Other {
+			return e.Kind
+		}
+
+		e, ok = e.Err.(*Error)
+	}
+
+	return Other
+}
+
+// CodeOf returns the code of err, the name of its kind when it has none.
+func CodeOf(err error) Code {
+	e, ok := err.(*Error)
+	for ok {
+		if e.Code != "" {
+			return e.Code
+		}
+
+		e, ok = e.Err.(*Error)
+	}
+
+	return Code(KindOf(err).Name())
+}
+
+// Cause returns the innermost error of err that is not an *Error, nil when there is none.
+func Cause(err error) error {
+	e, ok := err.(*Error)
+	for ok {
+		err = e.Err
+		e, ok = err.(*Error)
+	}
+
+	return err
+}
+
+// Message returns the message of err meant for clients: the one of its cause, without the
+// operations and kinds of the errors wrapping it, or the description of its kind. Server errors
+// are described by their kind only, as their causes may disclose internals; they belong in logs.
+func Message(err error) string {
+	kind := KindOf(err)
+	if kind.HTTPStatus() >= http.StatusInternalServerError {
+		return kind.String()
+	}
+
+	if cause := Cause(err); cause != nil {
+		return cause.Error()
+	}
+
+	return kind.String()
+}
@@ -68,3 +68,42 @@ func (m *InMemory) Save(ctx context.Context, aggregateID model.ID, tenantID mode
 
 	return nil
 }
+
+// LoadAll implements the BatchLoader interface.
+func (m *InMemory) LoadAll(ctx context.Context, aggregateIDs []model.ID, tenantID model.ID) (map[model.ID]History, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	histories := make(map[model.ID]History, len(aggregateIDs))
+	for _, id := range aggregateIDs {
+		if records, ok := m.events[id+tenantID]; ok && len(records) > 0 {
+			histories[id] = records
+		}
+	}
+
+	return histories, nil
+}
+
+// Scan implements the Scanner interface.
+func (m *InMemory) Scan(ctx context.Context, tenantID model.ID, fn func(history History) error) error {
+	m.mux.Lock()
+	var histories []History
+	for _, records := range m.events {
+		if len(records) > 0 && records[0].TenantID == tenantID {
+			histories = append(histories, append(History{}, records...))
+		}
+	}
+	m.mux.Unlock()
+
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i][0].AggregateID < histories[j][0].AggregateID
+	})
+
+	for _, history := range histories {
+		if err := fn(history); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -22,8 +22,22 @@ var (
 		WHERE aggregate_id = ?aggregateID AND tenant_id = ?tenantID AND version >= ?fromVersion AND version <= ?toVersion
 		ORDER BY version ASC
 	`)
+	selectAggregateIDsSQL = strings.TrimSpace(`
+		SELECT DISTINCT aggregate_id FROM records
+		WHERE tenant_id = ?tenantID AND aggregate_id > ?after
+		ORDER BY aggregate_id ASC
+		LIMIT ?limit
+	`)
+	selectAllRecordsSQL = strings.TrimSpace(`
+		SELECT id, aggregate_id, tenant_id, version, data, created_at FROM records
+		WHERE aggregate_id IN (?aggregateIDs) AND tenant_id = ?tenantID
+		ORDER BY aggregate_id, version ASC
+	`)
 )
 
+// ScanBatchSize is the number of aggregates loaded per query by Scan.
+var ScanBatchSize = 500
+
 type PgStore struct {
 	tableName string
 	db        *pg.DB
@@ -56,6 +70,70 @@ func (p *PgStore) Load(ctx context.Context, aggregateID model.ID, tenantID model
 	return history, nil
 }
 
+// LoadAll implements the eventstore.BatchLoader interface, loading every history in one query.
+func (p *PgStore) LoadAll(ctx context.Context, aggregateIDs []model.ID, tenantID model.ID) (map[model.ID]eventstore.History, error) {
+	const op errors.Op = "pgstore/PgStore.LoadAll"
+
+	histories := make(map[model.ID]eventstore.History, len(aggregateIDs))
+	if len(aggregateIDs) == 0 {
+		return histories, nil
+	}
+
+	records := make(eventstore.History, 0)
+	_, err := p.db.
+		WithContext(ctx).
+		WithParam("tableName", p.tableName).
+		WithParam("aggregateIDs", pg.In(aggregateIDs)).
+		WithParam("tenantID", tenantID).
+		Query(&records, selectAllRecordsSQL)
+	if err != nil && err != pg.ErrNoRows {
+		return nil, errors.E(op, errors.Internal, err)
+	}
+
+	for _, record := range records {
+		histories[record.AggregateID] = append(histories[record.AggregateID], record)
+	}
+
+	return histories, nil
+}
+
+// Scan implements the eventstore.Scanner interface, loading the aggregates of the tenant by
+// batches of ScanBatchSize.
+func (p *PgStore) Scan(ctx context.Context, tenantID model.ID, fn func(history eventstore.History) error) error {
+	const op errors.Op = "pgstore/PgStore.Scan"
+
+	after := model.ID("")
+	for {
+		var ids []model.ID
+		_, err := p.db.
+			WithContext(ctx).
+			WithParam("tenantID", tenantID).
+			WithParam("after", after).
+			WithParam("limit", ScanBatchSize).
+			Query(&ids, selectAggregateIDsSQL)
+		if err != nil && err != pg.ErrNoRows {
+			return errors.E(op, errors.Internal, err)
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		histories, err := p.LoadAll(ctx, ids, tenantID)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := fn(histories[id]); err != nil {
+				return err
+			}
+		}
+
+		after = ids[len(ids)-1]
+	}
+}
+
 func (p *PgStore) checkIdempotent(ctx context.Context, aggregateID model.ID, tenantID model.ID, records []*eventstore.Record) error {
 	const op errors.Op = "pgstore/PgStore.checkIdempotent"
 
@@ -71,7 +149,7 @@ func (p *PgStore) checkIdempotent(ctx context.Context, aggregateID model.ID, ten
 	}
 
 	if !reflect.DeepEqual(segments, persisted) {
-		return errors.E(op, errors.Internal, fmt.Sprintf("conflicting records of aggregate with aggregateID %s detected", aggregateID))
+		return errors.E(op, errors.Conflict, fmt.Sprintf("conflicting records of aggregate with aggregateID %s detected", aggregateID))
 	}
 
 	return nil
@@ -111,6 +189,17 @@ func (p *PgStore) Save(ctx context.Context, aggregateID model.ID, tenantID model
 	return nil
 }
 
+// Ping checks the connection to Postgres.
+func (p *PgStore) Ping(ctx context.Context) error {
+	const op errors.Op = "store/PgStore.Ping"
+
+	if err := p.db.Ping(ctx); err != nil {
+		return errors.E(op, errors.IO, err)
+	}
+
+	return nil
+}
+
 // New returns a Postgres backed store
 func New(options *pg.Options, logger logrus.FieldLogger) eventstore.Store {
 	logger = logger.WithField("component", "PgStore")
@@ -2,6 +2,7 @@ package eventstore
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -56,6 +57,31 @@ func (r *Repository) Load(ctx context.Context, aggregateID model.ID, tenantID mo
 	return v, err
 }
 
+// History retrieves every event of the specified aggregate, in order.
+func (r *Repository) History(ctx context.Context, aggregateID model.ID, tenantID model.ID) ([]model.Event, error) {
+	const op errors.Op = "store/Repository.History"
+	history, err := r.store.Load(ctx, aggregateID, tenantID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, errors.E(op, errors.NotFound)
+	}
+
+	events := make([]model.Event, 0, len(history))
+	for _, record := range history {
+		event, err := r.serializer.UnmarshalEvent(record)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
 // LoadVersion retrieves the specified aggregate from the underlying store at a particular version.
 func (r *Repository) loadVersion(ctx context.Context, aggregateID model.ID, tenantID model.ID, version model.Version) (Aggregate, model.Version, error) {
 	const op errors.Op = "store/Repository.loadVersion"
@@ -69,11 +95,16 @@ func (r *Repository) loadVersion(ctx context.Context, aggregateID model.ID, tena
 		return nil, 0, errors.E(op, errors.NotFound)
 	}
 
-	aggregate := r.NewAggregate()
-
 	r.logger.Debugf("loaded %d event(s) for %s", count, aggregateID)
 
-	version = 0
+	return r.replay(history)
+}
+
+// replay applies the events of history to a new aggregate, and returns it along with its version.
+func (r *Repository) replay(history History) (Aggregate, model.Version, error) {
+	aggregate := r.NewAggregate()
+
+	version := model.Version(0)
 	for _, record := range history {
 		event, err := r.serializer.UnmarshalEvent(record)
 		if err != nil {
@@ -90,6 +121,48 @@ func (r *Repository) loadVersion(ctx context.Context, aggregateID model.ID, tena
 	return aggregate, version, nil
 }
 
+// LoadAll retrieves the specified aggregates, in a single query when the underlying store is a
+// BatchLoader. Aggregates that do not exist are missing from the result.
+func (r *Repository) LoadAll(ctx context.Context, aggregateIDs []model.ID, tenantID model.ID) (map[model.ID]Aggregate, error) {
+	aggregates := make(map[model.ID]Aggregate, len(aggregateIDs))
+
+	loader, ok := r.store.(BatchLoader)
+	if !ok {
+		for _, id := range aggregateIDs {
+			aggregate, err := r.Load(ctx, id, tenantID)
+			if errors.Is(errors.NotFound, err) {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			aggregates[id] = aggregate
+		}
+
+		return aggregates, nil
+	}
+
+	histories, err := loader.LoadAll(ctx, aggregateIDs, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Debugf("loaded the events of %d aggregate(s)", len(histories))
+
+	for id, history := range histories {
+		aggregate, _, err := r.replay(history)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregates[id] = aggregate
+	}
+
+	return aggregates, nil
+}
+
 // loadTime loads the specified aggregate from the store at some point in time and returns
 // both the Aggregate and the current version number of the aggregate.
 func (r *Repository) loadTime(ctx context.Context, aggregateID model.ID, tenantID model.ID, end time.Time) (Aggregate, model.Version, error) {
@@ -172,20 +245,120 @@ func (r *Repository) Apply(ctx context.Context, cmd model.Command) (model.Versio
 		version = events[v-1].EventVersion()
 	}
 
-	// publish events to observers
-	if r.observers != nil {
-		for _, event := range events {
-			for _, observer := range r.observers {
-				observer(event)
-			}
-		}
-	}
+	r.notify(events)
 
 	r.logger.Debugf("applied %d event(s)", totalEvents)
 
 	return version, nil
 }
 
+// notify publishes events to the observers.
+func (r *Repository) notify(events []model.Event) {
+	for _, event := range events {
+		for _, observer := range r.observers {
+			observer(event)
+		}
+	}
+}
+
+// Scan calls fn with every aggregate of the tenant and its history, in aggregate ID order. The
+// aggregates of other serializers sharing the store are skipped. It fails with an Internal error
+// when the store is not a Scanner.
+func (r *Repository) Scan(ctx context.Context, tenantID model.ID, fn func(aggregate Aggregate, history History) error) error {
+	const op errors.Op = "store/Repository.Scan"
+
+	scanner, ok := r.store.(Scanner)
+	if !ok {
+		return errors.E(op, errors.Internal, "the store cannot list aggregates")
+	}
+
+	return scanner.Scan(ctx, tenantID, func(history History) error {
+		if len(history) == 0 || !r.serializer.Binds(history[0]) {
+			return nil
+		}
+
+		aggregate, _, err := r.replay(history)
+		if err != nil {
+			return err
+		}
+
+		return fn(aggregate, history)
+	})
+}
+
+// Verify checks that history can be imported as the history of the aggregate, and returns the
+// aggregate it builds. The events must belong to the aggregate and the tenant, and follow each
+// other from the first version.
+func (r *Repository) Verify(aggregateID model.ID, tenantID model.ID, history History) (Aggregate, error) {
+	aggregate, _, err := r.verify(aggregateID, tenantID, history)
+	return aggregate, err
+}
+
+func (r *Repository) verify(aggregateID model.ID, tenantID model.ID, history History) (Aggregate, []model.Event, error) {
+	const op errors.Op = "store/Repository.Verify"
+
+	if len(history) == 0 {
+		return nil, nil, errors.E(op, errors.Invalid, "history cannot be empty")
+	}
+
+	aggregate := r.NewAggregate()
+	events := make([]model.Event, len(history))
+	for i, record := range history {
+		if !r.serializer.Binds(record) {
+			return nil, nil, errors.E(op, errors.Invalid, fmt.Sprintf("event %d has an unknown kind", i+1))
+		}
+
+		event, err := r.serializer.UnmarshalEvent(record)
+		if err != nil {
+			return nil, nil, errors.E(op, errors.Invalid, err)
+		}
+
+		if event.EventID() != aggregateID || event.EventTenantID() != tenantID {
+			return nil, nil, errors.E(op, errors.Invalid, fmt.Sprintf("event %d belongs to another aggregate", i+1))
+		}
+
+		if event.EventVersion() != model.Version(i+1) {
+			return nil, nil, errors.E(op, errors.Invalid, fmt.Sprintf("event %d has version %d", i+1, event.EventVersion()))
+		}
+
+		if err := aggregate.On(event); err != nil {
+			return nil, nil, errors.E(op, errors.Invalid, err)
+		}
+
+		events[i] = event
+	}
+
+	return aggregate, events, nil
+}
+
+// Import saves the history of a new aggregate, such as one exported by Scan, once verified, and
+// returns the aggregate.
+func (r *Repository) Import(ctx context.Context, aggregateID model.ID, tenantID model.ID, history History) (Aggregate, error) {
+	const op errors.Op = "store/Repository.Import"
+
+	aggregate, events, err := r.verify(aggregateID, tenantID, history)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := r.store.Load(ctx, aggregateID, tenantID, 0, 0)
+	if err != nil && !errors.Is(errors.NotFound, err) {
+		return nil, err
+	}
+
+	if len(existing) > 0 {
+		return nil, errors.E(op, errors.Duplicate, fmt.Sprintf("aggregate %s already exists", aggregateID))
+	}
+
+	if err := r.Save(ctx, tenantID, events...); err != nil {
+		return nil, err
+	}
+
+	r.notify(events)
+
+	return aggregate, nil
+}
+
 func (r *Repository) Store() Store {
 	return r.store
 }
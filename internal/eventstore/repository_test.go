@@ -292,3 +292,88 @@ func TestApplyNopCommand(t *testing.T) {
 		assert.EqualValues(t, 0, version)
 	})
 }
+
+func TestRepository_LoadAll(t *testing.T) {
+	ctx := context.Background()
+	tenantID := model.ID("anonymous")
+	logger := logrus.New()
+	serializer := NewJSONSerializer(EntityCreated{}, EntityNameSet{})
+	repository := NewRepository(&Entity{}, NewInMemory(logger), serializer, logger)
+
+	for _, id := range []model.ID{"1", "2"} {
+		err := repository.Save(ctx, tenantID,
+			&EntityCreated{EventModel: model.EventModel{ID: id, TenantID: tenantID, Version: 1}},
+			&EntityNameSet{EventModel: model.EventModel{ID: id, TenantID: tenantID, Version: 2}, Name: "name-" + string(id)},
+		)
+		assert.Nil(t, err)
+	}
+
+	aggregates, err := repository.LoadAll(ctx, []model.ID{"2", "missing", "1"}, tenantID)
+	assert.Nil(t, err)
+	assert.Len(t, aggregates, 2)
+	assert.Equal(t, "name-1", aggregates["1"].(*Entity).Name)
+	assert.Equal(t, model.Version(2), aggregates["2"].(*Entity).Version)
+
+	aggregates, err = repository.LoadAll(ctx, []model.ID{"1"}, "other")
+	assert.Nil(t, err)
+	assert.Empty(t, aggregates)
+}
+
+func TestRepository_ScanImport(t *testing.T) {
+	ctx := context.Background()
+	tenantID := model.ID("anonymous")
+	logger := logrus.New()
+	store := NewInMemory(logger)
+	repository := NewRepository(&Entity{}, store, NewJSONSerializer(EntityCreated{}, EntityNameSet{}), logger)
+	others := NewRepository(&Entity{}, store, NewJSONSerializer(EntityNameSet{}), logger)
+
+	for _, id := range []model.ID{"2", "1"} {
+		err := repository.Save(ctx, tenantID,
+			&EntityCreated{EventModel: model.EventModel{ID: id, TenantID: tenantID, Version: 1}},
+			&EntityNameSet{EventModel: model.EventModel{ID: id, TenantID: tenantID, Version: 2}, Name: "name-" + string(id)},
+		)
+		assert.Nil(t, err)
+	}
+
+	err := repository.Save(ctx, tenantID, &EntityNameSet{EventModel: model.EventModel{ID: "3", TenantID: tenantID, Version: 1}})
+	assert.Nil(t, err)
+
+	// Aggregates are told apart by the kind of their first event.
+	var names []string
+	err = others.Scan(ctx, tenantID, func(aggregate Aggregate, history History) error {
+		names = append(names, string(aggregate.(*Entity).ID))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{""}, names)
+
+	names = nil
+	var exported []History
+	err = repository.Scan(ctx, tenantID, func(aggregate Aggregate, history History) error {
+		names = append(names, aggregate.(*Entity).Name)
+		exported = append(exported, history)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name-1", "name-2", ""}, names)
+
+	// Histories import into another tenant only once rewritten for it.
+	_, err = repository.Import(ctx, "1", "other", exported[0])
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	_, err = repository.Import(ctx, "1", tenantID, exported[0])
+	assert.True(t, errors.Is(errors.Duplicate, err))
+
+	err = repository.Save(ctx, "other", &EntityCreated{EventModel: model.EventModel{ID: "1", TenantID: "other", Version: 1}})
+	assert.Nil(t, err)
+
+	history, err := store.Load(ctx, "1", "other", 0, 0)
+	assert.Nil(t, err)
+
+	aggregate, err := repository.Import(ctx, "4", "other", history)
+	assert.True(t, errors.Is(errors.Invalid, err))
+	assert.Nil(t, aggregate)
+
+	_, err = repository.Import(ctx, "5", tenantID, nil)
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
@@ -16,6 +16,10 @@ type Serializer interface {
 
 	// UnmarshalEvent converts an Event backed into a Record
 	UnmarshalEvent(record *Record) (model.Event, error)
+
+	// Binds reports whether the event of record has a registered type, telling apart the
+	// aggregates of serializers sharing a store.
+	Binds(record *Record) bool
 }
 
 type jsonEvent struct {
@@ -85,6 +89,62 @@ func (j *JSONSerializer) UnmarshalEvent(record *Record) (model.Event, error) {
 	return v.(model.Event), nil
 }
 
+// Binds implements the Serializer interface.
+func (j *JSONSerializer) Binds(record *Record) bool {
+	var wrapper jsonEvent
+	if err := json.Unmarshal(record.Data, &wrapper); err != nil {
+		return false
+	}
+
+	_, ok := j.eventTypes[wrapper.Kind]
+	return ok
+}
+
+// Retenant returns the records of JSONSerializer in history as events of the tenant, so that
+// histories exported from a tenant import into another. Events of the tenant are kept as is.
+func Retenant(history History, tenantID model.ID) (History, error) {
+	const op errors.Op = "store/Retenant"
+
+	tenant, err := json.Marshal(tenantID)
+	if err != nil {
+		return nil, errors.E(op, errors.Internal, err)
+	}
+
+	moved := make(History, len(history))
+	for i, record := range history {
+		var (
+			wrapper jsonEvent
+			payload map[string]json.RawMessage
+		)
+		if err := json.Unmarshal(record.Data, &wrapper); err != nil {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("event %d is not a JSON event", i+1))
+		}
+
+		if err := json.Unmarshal(wrapper.Payload, &payload); err != nil {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("event %d has no JSON object payload", i+1))
+		}
+
+		r := *record
+		r.TenantID = tenantID
+		moved[i] = &r
+
+		if string(payload["tenant_id"]) == string(tenant) {
+			continue
+		}
+
+		payload["tenant_id"] = tenant
+		if wrapper.Payload, err = json.Marshal(payload); err != nil {
+			return nil, errors.E(op, errors.Internal, err)
+		}
+
+		if r.Data, err = json.Marshal(wrapper); err != nil {
+			return nil, errors.E(op, errors.Internal, err)
+		}
+	}
+
+	return moved, nil
+}
+
 // MarshalAll is a utility that marshals all the events provided into a History entity
 func (j *JSONSerializer) MarshalAll(events ...model.Event) (History, error) {
 	history := make(History, 0, len(events))
@@ -55,3 +55,38 @@ func TestJSONSerializer_MarshalAll(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, &event, found)
 }
+
+func TestRetenant(t *testing.T) {
+	event := EntitySetName{
+		EventModel: model.EventModel{
+			ID:       "entity_foo",
+			TenantID: "acme",
+			Version:  1,
+		},
+		Name: "foo",
+	}
+
+	serializer := NewJSONSerializer(event)
+	history, err := serializer.MarshalAll(event)
+	assert.Nil(t, err)
+
+	moved, err := Retenant(history, "globex")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ID("globex"), moved[0].TenantID)
+
+	v, err := serializer.UnmarshalEvent(moved[0])
+	assert.Nil(t, err)
+
+	event.TenantID = "globex"
+	assert.Equal(t, &event, v)
+
+	// The exported history is left untouched.
+	assert.Equal(t, model.ID("acme"), history[0].TenantID)
+
+	same, err := Retenant(moved, "globex")
+	assert.Nil(t, err)
+	assert.Equal(t, moved[0].Data, same[0].Data)
+
+	_, err = Retenant(History{{Data: []byte(`{"kind": "EntitySetName", "payload": []}`)}}, "globex")
+	assert.NotNil(t, err)
+}
@@ -52,3 +52,23 @@ type Store interface {
 	// Save the provided serialized records to the store
 	Save(ctx context.Context, aggregateID model.ID, tenantID model.ID, records []*Record) error
 }
+
+// BatchLoader is implemented by stores loading the histories of several aggregates at once.
+type BatchLoader interface {
+	// LoadAll loads the full histories of the aggregates with the given IDs, by aggregate ID.
+	// Aggregates without events are missing from the result.
+	LoadAll(ctx context.Context, aggregateIDs []model.ID, tenantID model.ID) (map[model.ID]History, error)
+}
+
+// Scanner is implemented by stores listing the aggregates of a tenant.
+type Scanner interface {
+	// Scan calls fn with the full history of every aggregate of the tenant, in aggregate ID order,
+	// until fn fails.
+	Scan(ctx context.Context, tenantID model.ID, fn func(history History) error) error
+}
+
+// Pinger is implemented by stores checking their connection, for readiness probes.
+type Pinger interface {
+	// Ping fails when the store is unreachable.
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+const (
+	ResourceEntity      = "entity"
+	ResourceAssociation = "association"
+)
+
+// Change is an event of an entity or association, as streamed to clients.
+type Change struct {
+	// Position is set by the Hub and identifies the change when resuming a stream.
+	Position string `json:"position"`
+
+	Resource string        `json:"resource"`
+	Kind     string        `json:"kind"`
+	ID       model.ID      `json:"id"`
+	TenantID model.ID      `json:"tenant_id"`
+	Type     string        `json:"type,omitempty"`
+	Version  model.Version `json:"version"`
+	At       *time.Time    `json:"at"`
+	Event    model.Event   `json:"event"`
+}
+
+// NewChange returns the change of a resource of type typ described by event.
+func NewChange(resource string, typ string, event model.Event) Change {
+	kind, _ := model.EventType(event)
+
+	return Change{
+		Resource: resource,
+		Kind:     kind,
+		ID:       event.EventID(),
+		TenantID: event.EventTenantID(),
+		Type:     typ,
+		Version:  event.EventVersion(),
+		At:       event.EventAt(),
+		Event:    event,
+	}
+}
+
+// Filter selects the changes streamed to a client. Empty fields match every change.
+type Filter struct {
+	Resources []string
+	Types     []string
+	IDs       []model.ID
+}
+
+func contains(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match reports whether c is selected by f.
+func (f *Filter) Match(c *Change) bool {
+	if !contains(f.Resources, c.Resource) || !contains(f.Types, c.Type) {
+		return false
+	}
+
+	if len(f.IDs) == 0 {
+		return true
+	}
+
+	for _, id := range f.IDs {
+		if id == c.ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,177 @@
+package feed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// DefaultHistorySize is the number of changes kept to resume streams.
+	DefaultHistorySize = 4096
+
+	// DefaultClientBuffer is the number of changes buffered for a client before it is dropped.
+	DefaultClientBuffer = 256
+)
+
+// Hub fans changes out to subscribers. Changes are numbered in the order they are published and
+// the most recent ones are kept so that clients can resume a stream from their last position.
+//
+// Positions are only meaningful within a Hub: they are prefixed by an epoch set when the Hub is
+// created so that positions of a previous process are detected.
+type Hub struct {
+	mux          sync.Mutex
+	epoch        string
+	seq          uint64
+	history      []Change
+	historySize  int
+	clientBuffer int
+	subs         map[*Subscription]struct{}
+
+	logger logrus.FieldLogger
+}
+
+func NewHub(historySize int, clientBuffer int, logger logrus.FieldLogger) *Hub {
+	return &Hub{
+		epoch:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:      make([]Change, 0, historySize),
+		historySize:  historySize,
+		clientBuffer: clientBuffer,
+		subs:         map[*Subscription]struct{}{},
+		logger:       logger.WithField("component", "feed-hub"),
+	}
+}
+
+// Subscription receives the changes of a tenant selected by a filter.
+type Subscription struct {
+	// C is closed when the subscription is canceled or the client fell behind.
+	C <-chan Change
+
+	c        chan Change
+	tenantID model.ID
+	filter   Filter
+	closed   bool
+
+	// Overflowed is set when the subscription was dropped for falling behind.
+	Overflowed bool
+}
+
+func (h *Hub) position(seq uint64) string {
+	return fmt.Sprintf("%s-%d", h.epoch, seq)
+}
+
+func (h *Hub) parsePosition(position string) (uint64, error) {
+	i := strings.LastIndex(position, "-")
+	if i < 0 {
+		return 0, errors.E(errors.Invalid, fmt.Sprintf("invalid position %q", position))
+	}
+
+	seq, err := strconv.ParseUint(position[i+1:], 10, 64)
+	if err != nil {
+		return 0, errors.E(errors.Invalid, fmt.Sprintf("invalid position %q", position))
+	}
+
+	if position[:i] != h.epoch {
+		return 0, errors.E(errors.Conflict, fmt.Sprintf("position %q is no longer available", position))
+	}
+
+	return seq, nil
+}
+
+// Publish numbers c, sends it to the matching subscribers and returns it.
+func (h *Hub) Publish(c Change) Change {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.seq++
+	c.Position = h.position(h.seq)
+
+	if len(h.history) == h.historySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, c)
+
+	for sub := range h.subs {
+		if sub.tenantID != c.TenantID || !sub.filter.Match(&c) {
+			continue
+		}
+
+		select {
+		case sub.c <- c:
+		default:
+			h.logger.Warnf("dropping subscriber of tenant %s: buffer full", sub.tenantID)
+			sub.Overflowed = true
+			h.cancel(sub)
+		}
+	}
+
+	return c
+}
+
+// Subscribe streams the changes of a tenant selected by filter. When after is set, the changes
+// published after that position are replayed first. Positions that are no longer kept are rejected
+// with a Conflict error.
+func (h *Hub) Subscribe(tenantID model.ID, filter Filter, after string) (*Subscription, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	var backlog []Change
+	if after != "" {
+		seq, err := h.parsePosition(after)
+		if err != nil {
+			return nil, err
+		}
+
+		oldest := h.seq - uint64(len(h.history))
+		if seq < oldest {
+			return nil, errors.E(errors.Conflict, fmt.Sprintf("position %q is no longer available", after))
+		}
+
+		for i := seq - oldest; i < uint64(len(h.history)); i++ {
+			c := h.history[i]
+			if c.TenantID == tenantID && filter.Match(&c) {
+				backlog = append(backlog, c)
+			}
+		}
+	}
+
+	size := h.clientBuffer
+	if len(backlog) > size {
+		size = len(backlog)
+	}
+
+	c := make(chan Change, size)
+	for _, change := range backlog {
+		c <- change
+	}
+
+	sub := &Subscription{C: c, c: c, tenantID: tenantID, filter: filter}
+	h.subs[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Unsubscribe cancels sub.
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.cancel(sub)
+}
+
+func (h *Hub) cancel(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.c)
+}
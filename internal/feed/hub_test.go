@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+func change(tenant model.ID, resource string, typ string, id model.ID) Change {
+	return Change{Resource: resource, Type: typ, ID: id, TenantID: tenant}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub(10, 10, newLogger())
+
+	sub, err := hub.Subscribe("tenant", Filter{Types: []string{"user"}}, "")
+	require.NoError(t, err)
+
+	hub.Publish(change("tenant", ResourceEntity, "user", "1"))
+	hub.Publish(change("tenant", ResourceEntity, "post", "2"))
+	hub.Publish(change("other", ResourceEntity, "user", "3"))
+	hub.Publish(change("tenant", ResourceEntity, "user", "4"))
+
+	first := <-sub.C
+	assert.Equal(t, model.ID("1"), first.ID)
+	assert.NotEmpty(t, first.Position)
+	assert.Equal(t, model.ID("4"), (<-sub.C).ID)
+	assert.Len(t, sub.C, 0)
+
+	hub.Unsubscribe(sub)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
+
+func TestHub_Subscribe_Resume(t *testing.T) {
+	hub := NewHub(3, 10, newLogger())
+
+	var positions []string
+	for _, id := range []model.ID{"1", "2", "3", "4"} {
+		sub, err := hub.Subscribe("tenant", Filter{}, "")
+		require.NoError(t, err)
+		hub.Publish(change("tenant", ResourceEntity, "user", id))
+		positions = append(positions, (<-sub.C).Position)
+		hub.Unsubscribe(sub)
+	}
+
+	sub, err := hub.Subscribe("tenant", Filter{}, positions[1])
+	require.NoError(t, err)
+	assert.Equal(t, model.ID("3"), (<-sub.C).ID)
+	assert.Equal(t, model.ID("4"), (<-sub.C).ID)
+
+	// The first change is no longer kept.
+	_, err = hub.Subscribe("tenant", Filter{}, positions[0][:len(positions[0])-1]+"0")
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	// Positions of another hub are rejected.
+	_, err = NewHub(3, 10, newLogger()).Subscribe("tenant", Filter{}, positions[1])
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	_, err = hub.Subscribe("tenant", Filter{}, "invalid")
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
+
+func TestHub_Publish_Overflow(t *testing.T) {
+	hub := NewHub(10, 1, newLogger())
+
+	sub, err := hub.Subscribe("tenant", Filter{}, "")
+	require.NoError(t, err)
+
+	hub.Publish(change("tenant", ResourceEntity, "user", "1"))
+	hub.Publish(change("tenant", ResourceEntity, "user", "2"))
+
+	assert.Equal(t, model.ID("1"), (<-sub.C).ID)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.True(t, sub.Overflowed)
+}
+
+func TestFilter_Match(t *testing.T) {
+	c := change("tenant", ResourceAssociation, "follows", "a:follows:b")
+
+	assert.True(t, (&Filter{}).Match(&c))
+	assert.True(t, (&Filter{Resources: []string{ResourceAssociation}}).Match(&c))
+	assert.False(t, (&Filter{Resources: []string{ResourceEntity}}).Match(&c))
+	assert.False(t, (&Filter{Types: []string{"likes"}}).Match(&c))
+	assert.True(t, (&Filter{IDs: []model.ID{"a:follows:b"}}).Match(&c))
+	assert.False(t, (&Filter{IDs: []model.ID{"other"}}).Match(&c))
+}
@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// DefaultRetention is how long keys and their responses are kept.
+var DefaultRetention = 24 * time.Hour
+
+// PendingTimeout is how long a key stays taken by a request without response, such as one
+// interrupted by a crash, before it can be retried.
+var PendingTimeout = time.Minute
+
+// MaxKeyLength bounds the length of keys.
+const MaxKeyLength = 255
+
+// Response is a response stored for replay.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Record is the state of a key: the fingerprint of the request first sent with it and, once
+// completed, its response. Keys are scoped by tenant and subject, the principal sending them, so
+// that a principal cannot replay the responses of another.
+type Record struct {
+	Key         string     `json:"key"`
+	TenantID    model.ID   `json:"tenant_id"`
+	Subject     string     `json:"subject,omitempty"`
+	Fingerprint string     `json:"fingerprint"`
+	Response    *Response  `json:"response,omitempty"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+// Store keeps records for a retention period.
+type Store interface {
+	// Begin records r unless its key is taken, in which case it returns the record holding it.
+	Begin(ctx context.Context, r *Record) (*Record, error)
+
+	// Complete stores r with its response.
+	Complete(ctx context.Context, r *Record) error
+
+	// Abort releases the key of a request that failed, so that it can be retried.
+	Abort(ctx context.Context, r *Record) error
+}
+
+// Fingerprint identifies a request by its method, URI and body.
+func Fingerprint(method string, uri string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, uri)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Replay returns the response of the request holding the key of r, to answer a retry with the
+// given fingerprint. It fails with an Invalid error when the key was sent with another request,
+// and with a Conflict error while the request holding it is in progress.
+func (r *Record) Replay(fingerprint string) (*Response, error) {
+	const op errors.Op = "idempotency/Record.Replay"
+
+	if r.Fingerprint != fingerprint {
+		return nil, errors.E(op, errors.Invalid, errors.Code("idempotency_key_reused"), fmt.Sprintf("idempotency key %s was used with another request", r.Key))
+	}
+
+	if r.Response == nil {
+		return nil, errors.E(op, errors.Conflict, errors.Code("idempotency_key_in_progress"), fmt.Sprintf("the request of idempotency key %s is in progress", r.Key))
+	}
+
+	return r.Response, nil
+}
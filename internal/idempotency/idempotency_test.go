@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint(http.MethodPost, "/api/v1/entities", []byte(`{"otype":"user"}`))
+
+	assert.Equal(t, fp, Fingerprint(http.MethodPost, "/api/v1/entities", []byte(`{"otype":"user"}`)))
+	assert.NotEqual(t, fp, Fingerprint(http.MethodPost, "/api/v1/entities", []byte(`{"otype":"team"}`)))
+	assert.NotEqual(t, fp, Fingerprint(http.MethodPost, "/api/v1/entities?wait=true", []byte(`{"otype":"user"}`)))
+	assert.NotEqual(t, fp, Fingerprint(http.MethodPut, "/api/v1/entities", []byte(`{"otype":"user"}`)))
+}
+
+func TestInMemory(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory(time.Hour)
+	rec := &Record{Key: "k1", TenantID: "acme", Fingerprint: "a"}
+
+	holder, err := store.Begin(ctx, rec)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	// Retries find the pending record, then its response.
+	holder, err = store.Begin(ctx, &Record{Key: "k1", TenantID: "acme", Fingerprint: "a"})
+	require.NoError(t, err)
+	_, err = holder.Replay("a")
+	assert.True(t, errors.Is(errors.Conflict, err))
+
+	rec.Response = &Response{Status: http.StatusCreated, Body: []byte(`{}`)}
+	require.NoError(t, store.Complete(ctx, rec))
+
+	holder, err = store.Begin(ctx, &Record{Key: "k1", TenantID: "acme", Fingerprint: "a"})
+	require.NoError(t, err)
+	res, err := holder.Replay("a")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.Status)
+
+	_, err = holder.Replay("b")
+	assert.True(t, errors.Is(errors.Invalid, err))
+
+	// Keys are scoped by tenant and subject, and free again once aborted.
+	holder, err = store.Begin(ctx, &Record{Key: "k1", TenantID: "other", Fingerprint: "b"})
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	holder, err = store.Begin(ctx, &Record{Key: "k1", TenantID: "acme", Subject: "key1", Fingerprint: "a"})
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	require.NoError(t, store.Abort(ctx, &Record{Key: "k1", TenantID: "acme"}))
+	holder, err = store.Begin(ctx, &Record{Key: "k1", TenantID: "acme", Fingerprint: "b"})
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
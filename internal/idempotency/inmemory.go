@@ -0,0 +1,70 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+// InMemory is a Store keeping records in memory, for tests and single node deployments.
+type InMemory struct {
+	mux       sync.Mutex
+	records   map[string]entry
+	retention time.Duration
+}
+
+func NewInMemory(retention time.Duration) *InMemory {
+	return &InMemory{
+		records:   map[string]entry{},
+		retention: retention,
+	}
+}
+
+func key(r *Record) string {
+	return fmt.Sprintf("%s:%q:%s", r.TenantID, r.Subject, r.Key)
+}
+
+// Begin implements the Store interface.
+func (m *InMemory) Begin(ctx context.Context, r *Record) (*Record, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := time.Now()
+	for k, e := range m.records {
+		if now.After(e.expiresAt) {
+			delete(m.records, k)
+		}
+	}
+
+	if e, ok := m.records[key(r)]; ok {
+		existing := e.record
+		return &existing, nil
+	}
+
+	m.records[key(r)] = entry{record: *r, expiresAt: now.Add(PendingTimeout)}
+	return nil, nil
+}
+
+// Complete implements the Store interface.
+func (m *InMemory) Complete(ctx context.Context, r *Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.records[key(r)] = entry{record: *r, expiresAt: time.Now().Add(m.retention)}
+	return nil
+}
+
+// Abort implements the Store interface.
+func (m *InMemory) Abort(ctx context.Context, r *Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.records, key(r))
+	return nil
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store keeping records as expiring JSON strings.
+type Redis struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+}
+
+func NewRedis(client *redis.Client, prefix string, retention time.Duration) *Redis {
+	return &Redis{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+// key returns the Redis key of rec, hex encoding its subject so that it cannot run into the key.
+func (r *Redis) key(rec *Record) string {
+	return fmt.Sprintf("%s:%s:idempotency:%x:%s", r.prefix, rec.TenantID, rec.Subject, rec.Key)
+}
+
+// Begin implements the Store interface.
+func (r *Redis) Begin(ctx context.Context, rec *Record) (*Record, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.key(rec)
+
+	// The record holding the key may expire between SETNX and GET, then the key is free again.
+	for {
+		ok, err := r.client.SetNX(ctx, key, b, PendingTimeout).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return nil, nil
+		}
+
+		existing, err := r.client.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var holder Record
+		if err := json.Unmarshal(existing, &holder); err != nil {
+			return nil, err
+		}
+
+		return &holder, nil
+	}
+}
+
+// Complete implements the Store interface.
+func (r *Redis) Complete(ctx context.Context, rec *Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, r.key(rec), b, r.retention).Err()
+}
+
+// Abort implements the Store interface.
+func (r *Redis) Abort(ctx context.Context, rec *Record) error {
+	return r.client.Del(ctx, r.key(rec)).Err()
+}
@@ -0,0 +1,287 @@
+package limit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// Route groups are rate limited separately, so that a tenant flooding queries can still write.
+const (
+	GroupAdmin = "admin"
+	GroupQuery = "query"
+	GroupRead  = "read"
+	GroupWrite = "write"
+)
+
+// Rate is a token bucket holding up to Burst requests, refilled at PerSecond requests per second.
+// A zero PerSecond is unlimited.
+type Rate struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst"`
+}
+
+// burst returns the capacity of the bucket, at least one request.
+func (r Rate) burst() float64 {
+	if r.Burst < 1 {
+		return 1
+	}
+
+	return float64(r.Burst)
+}
+
+// Limits bounds the requests and the storage of a tenant. Zero values are unlimited.
+type Limits struct {
+	Rates         map[string]Rate `json:"rates,omitempty"`
+	MaxEntities   int64           `json:"max_entities,omitempty"`
+	MaxEventBytes int64           `json:"max_event_bytes,omitempty"`
+}
+
+// Usage is the storage used by a tenant: its live entities and the size of its events.
+type Usage struct {
+	Entities   int64 `json:"entities" redis:"entities"`
+	EventBytes int64 `json:"event_bytes" redis:"event_bytes"`
+}
+
+// Negate returns the delta undoing u.
+func (u Usage) Negate() Usage {
+	return Usage{Entities: -u.Entities, EventBytes: -u.EventBytes}
+}
+
+// exceeds reports whether adding delta to u goes over limits. Decreases never do.
+func (u Usage) exceeds(delta Usage, limits Limits) bool {
+	if delta.Entities > 0 && limits.MaxEntities > 0 && u.Entities+delta.Entities > limits.MaxEntities {
+		return true
+	}
+
+	return delta.EventBytes > 0 && limits.MaxEventBytes > 0 && u.EventBytes+delta.EventBytes > limits.MaxEventBytes
+}
+
+// Table holds the default limits and the tenants overriding them. It is read from a JSON file when
+// a master starts, see LoadTable: limits are changed by editing the file and restarting masters.
+type Table struct {
+	Default Limits              `json:"default"`
+	Tenants map[model.ID]Limits `json:"tenants"`
+}
+
+// LoadTable reads a table from a JSON file.
+func LoadTable(path string) (*Table, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Table
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("invalid limits %s: %w", path, err)
+	}
+
+	return &t, nil
+}
+
+// For returns the limits of a tenant, its unset fields falling back to the default ones.
+func (t *Table) For(tenantID model.ID) Limits {
+	if t == nil {
+		return Limits{}
+	}
+
+	limits, ok := t.Tenants[tenantID]
+	if !ok {
+		return t.Default
+	}
+
+	rates := make(map[string]Rate, len(t.Default.Rates)+len(limits.Rates))
+	for group, rate := range t.Default.Rates {
+		rates[group] = rate
+	}
+
+	for group, rate := range limits.Rates {
+		rates[group] = rate
+	}
+
+	limits.Rates = rates
+	if limits.MaxEntities == 0 {
+		limits.MaxEntities = t.Default.MaxEntities
+	}
+
+	if limits.MaxEventBytes == 0 {
+		limits.MaxEventBytes = t.Default.MaxEventBytes
+	}
+
+	return limits
+}
+
+// Store keeps the buckets and the usage of tenants.
+type Store interface {
+	// Take removes a request from the bucket key, returning how long to wait for one when it is
+	// empty.
+	Take(ctx context.Context, key string, rate Rate) (time.Duration, error)
+
+	// Add adds delta to the usage of a tenant unless it exceeds limits, and reports whether it did.
+	Add(ctx context.Context, tenantID model.ID, delta Usage, limits Limits) (bool, error)
+
+	// Usage returns the usage of a tenant.
+	Usage(ctx context.Context, tenantID model.ID) (*Usage, error)
+
+	// Seeded reports whether the usage of a tenant was seeded.
+	Seeded(ctx context.Context, tenantID model.ID) (bool, error)
+
+	// Seed adds u to the usage of a tenant unless it was seeded already, and reports whether it did.
+	Seed(ctx context.Context, tenantID model.ID, u Usage) (bool, error)
+}
+
+// Counter counts the storage used by a tenant in the event store.
+type Counter func(ctx context.Context, tenantID model.ID) (Usage, error)
+
+// Quotas reserves the storage of tenants at command time.
+type Quotas interface {
+	// Reserve adds delta to the usage of a tenant, failing with an Exhausted error when that goes
+	// over its limits.
+	Reserve(ctx context.Context, tenantID model.ID, delta Usage) error
+
+	// Record adds delta to the usage of a tenant unchecked, such as for deletes or to release a
+	// reservation.
+	Record(ctx context.Context, tenantID model.ID, delta Usage) error
+}
+
+// Limiter enforces the limits of a table over a store.
+type Limiter struct {
+	store Store
+	table *Table
+	count Counter
+
+	// seeding serializes the seeding of each tenant.
+	seeding sync.Map
+}
+
+func New(store Store, table *Table) *Limiter {
+	return &Limiter{
+		store: store,
+		table: table,
+	}
+}
+
+// Backfill seeds the usage of each tenant with count before it is first reserved, recorded or read,
+// so that usage does not start over at zero with a new store, such as after a restart with an in
+// memory store or a flush of Redis.
+func (l *Limiter) Backfill(count Counter) {
+	l.count = count
+}
+
+// seed seeds the usage of a tenant unless it was seeded already.
+func (l *Limiter) seed(ctx context.Context, tenantID model.ID) error {
+	if l.count == nil {
+		return nil
+	}
+
+	if ok, err := l.store.Seeded(ctx, tenantID); err != nil || ok {
+		return err
+	}
+
+	mux, _ := l.seeding.LoadOrStore(tenantID, &sync.Mutex{})
+	mux.(*sync.Mutex).Lock()
+	defer mux.(*sync.Mutex).Unlock()
+
+	// Another call seeded the tenant while this one was waiting.
+	if ok, err := l.store.Seeded(ctx, tenantID); err != nil || ok {
+		return err
+	}
+
+	u, err := l.count(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.store.Seed(ctx, tenantID, u)
+	return err
+}
+
+// Limits returns the limits of a tenant.
+func (l *Limiter) Limits(tenantID model.ID) Limits {
+	return l.table.For(tenantID)
+}
+
+// Take removes a request from the bucket of a tenant for a route group. When the bucket is empty,
+// it fails with an Exhausted error and returns how long to wait before retrying.
+func (l *Limiter) Take(ctx context.Context, tenantID model.ID, group string) (time.Duration, error) {
+	const op errors.Op = "limit/Limiter.Take"
+
+	rate, ok := l.table.For(tenantID).Rates[group]
+	if !ok || rate.PerSecond <= 0 {
+		return 0, nil
+	}
+
+	wait, err := l.store.Take(ctx, fmt.Sprintf("%s:%s", tenantID, group), rate)
+	if err != nil {
+		return 0, errors.E(op, errors.Transient, err)
+	}
+
+	if wait > 0 {
+		return wait, errors.E(op, errors.Exhausted, errors.Code("rate_limited"), fmt.Sprintf("rate limit of %s requests exceeded", group))
+	}
+
+	return 0, nil
+}
+
+// Reserve implements the Quotas interface.
+func (l *Limiter) Reserve(ctx context.Context, tenantID model.ID, delta Usage) error {
+	const op errors.Op = "limit/Limiter.Reserve"
+
+	if err := l.seed(ctx, tenantID); err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	ok, err := l.store.Add(ctx, tenantID, delta, l.table.For(tenantID))
+	if err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	if !ok {
+		return errors.E(op, errors.Exhausted, errors.Code("quota_exceeded"), fmt.Sprintf("storage quota of tenant %s exceeded", tenantID))
+	}
+
+	return nil
+}
+
+// Record implements the Quotas interface.
+func (l *Limiter) Record(ctx context.Context, tenantID model.ID, delta Usage) error {
+	const op errors.Op = "limit/Limiter.Record"
+
+	if err := l.seed(ctx, tenantID); err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	if _, err := l.store.Add(ctx, tenantID, delta, Limits{}); err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	return nil
+}
+
+// Usage returns the usage of a tenant.
+func (l *Limiter) Usage(ctx context.Context, tenantID model.ID) (*Usage, error) {
+	const op errors.Op = "limit/Limiter.Usage"
+
+	if err := l.seed(ctx, tenantID); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	u, err := l.store.Usage(ctx, tenantID)
+	if err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return u, nil
+}
+
+// RetryAfter rounds a wait up to the whole seconds of a Retry-After header.
+func RetryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
@@ -0,0 +1,173 @@
+package limit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable_For(t *testing.T) {
+	table := &Table{
+		Default: Limits{
+			Rates:       map[string]Rate{GroupRead: {PerSecond: 10, Burst: 20}, GroupWrite: {PerSecond: 5, Burst: 5}},
+			MaxEntities: 100,
+		},
+		Tenants: map[model.ID]Limits{
+			"acme": {Rates: map[string]Rate{GroupWrite: {PerSecond: 50, Burst: 50}}, MaxEventBytes: 1 << 20},
+		},
+	}
+
+	assert.Equal(t, table.Default, table.For("other"))
+
+	acme := table.For("acme")
+	assert.Equal(t, Rate{PerSecond: 10, Burst: 20}, acme.Rates[GroupRead])
+	assert.Equal(t, Rate{PerSecond: 50, Burst: 50}, acme.Rates[GroupWrite])
+	assert.Equal(t, int64(100), acme.MaxEntities)
+	assert.Equal(t, int64(1<<20), acme.MaxEventBytes)
+
+	var unset *Table
+	assert.Equal(t, Limits{}, unset.For("acme"))
+}
+
+func TestLoadTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": {"rates": {"read": {"per_second": 1, "burst": 2}}}, "tenants": {"acme": {"max_entities": 3}}}`), 0600))
+
+	table, err := LoadTable(path)
+	require.NoError(t, err)
+	assert.Equal(t, Rate{PerSecond: 1, Burst: 2}, table.For("acme").Rates[GroupRead])
+	assert.Equal(t, int64(3), table.For("acme").MaxEntities)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0600))
+	_, err = LoadTable(path)
+	assert.Error(t, err)
+}
+
+func TestLimiter_Take(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	store := NewInMemory()
+	store.now = func() time.Time { return now }
+
+	limiter := New(store, &Table{Default: Limits{Rates: map[string]Rate{GroupWrite: {PerSecond: 2, Burst: 2}}}})
+
+	for i := 0; i < 2; i++ {
+		wait, err := limiter.Take(ctx, "acme", GroupWrite)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	wait, err := limiter.Take(ctx, "acme", GroupWrite)
+	assert.True(t, errors.Is(errors.Exhausted, err))
+	assert.Equal(t, 500*time.Millisecond, wait)
+	assert.Equal(t, 1, RetryAfter(wait))
+
+	// Buckets are per tenant and per group, and groups without rate are unlimited.
+	_, err = limiter.Take(ctx, "other", GroupWrite)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = limiter.Take(ctx, "acme", GroupRead)
+		require.NoError(t, err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	_, err = limiter.Take(ctx, "acme", GroupWrite)
+	assert.NoError(t, err)
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	ctx := context.Background()
+	limiter := New(NewInMemory(), &Table{Default: Limits{MaxEntities: 2, MaxEventBytes: 100}})
+
+	require.NoError(t, limiter.Reserve(ctx, "acme", Usage{Entities: 1, EventBytes: 40}))
+	require.NoError(t, limiter.Reserve(ctx, "acme", Usage{Entities: 1, EventBytes: 40}))
+
+	err := limiter.Reserve(ctx, "acme", Usage{Entities: 1, EventBytes: 10})
+	assert.True(t, errors.Is(errors.Exhausted, err))
+
+	err = limiter.Reserve(ctx, "acme", Usage{EventBytes: 30})
+	assert.True(t, errors.Is(errors.Exhausted, err))
+
+	// Deletes are recorded even over the quota, and free entities.
+	require.NoError(t, limiter.Record(ctx, "acme", Usage{Entities: -1, EventBytes: 30}))
+	require.NoError(t, limiter.Reserve(ctx, "acme", Usage{Entities: 1}))
+
+	usage, err := limiter.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{Entities: 2, EventBytes: 110}, usage)
+}
+
+func TestLimiter_Backfill(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory()
+	limiter := New(store, &Table{Default: Limits{MaxEntities: 3}})
+
+	counted := 0
+	limiter.Backfill(func(ctx context.Context, tenantID model.ID) (Usage, error) {
+		counted++
+		return Usage{Entities: 2, EventBytes: 200}, nil
+	})
+
+	// The usage of a tenant starts from its storage.
+	usage, err := limiter.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{Entities: 2, EventBytes: 200}, usage)
+
+	require.NoError(t, limiter.Reserve(ctx, "acme", Usage{Entities: 1, EventBytes: 10}))
+	err = limiter.Reserve(ctx, "acme", Usage{Entities: 1})
+	assert.True(t, errors.Is(errors.Exhausted, err))
+
+	require.NoError(t, limiter.Record(ctx, "acme", Usage{Entities: -1}))
+	assert.Equal(t, 1, counted)
+
+	usage, err = limiter.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{Entities: 2, EventBytes: 210}, usage)
+
+	// A store seeded by another master is not seeded again.
+	ok, err := store.Seed(ctx, "acme", Usage{Entities: 5})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedis_Seed(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedis(client, "test")
+
+	seeded, err := store.Seeded(ctx, "acme")
+	require.NoError(t, err)
+	assert.False(t, seeded)
+
+	ok, err := store.Seed(ctx, "acme", Usage{Entities: 2, EventBytes: 200})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.Seed(ctx, "acme", Usage{Entities: 2, EventBytes: 200})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = store.Add(ctx, "acme", Usage{Entities: 1, EventBytes: 10}, Limits{})
+	require.NoError(t, err)
+
+	usage, err := store.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{Entities: 3, EventBytes: 210}, usage)
+
+	// Usage lost with Redis is seeded again.
+	mr.FlushAll()
+	seeded, err = store.Seeded(ctx, "acme")
+	require.NoError(t, err)
+	assert.False(t, seeded)
+}
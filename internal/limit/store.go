@@ -0,0 +1,230 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// take refills b at rate until now and removes a request, returning how long to wait for one
+// when b is empty.
+func (b *bucket) take(rate Rate, now time.Time) time.Duration {
+	if elapsed := now.Sub(b.at).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(rate.burst(), b.tokens+elapsed*rate.PerSecond)
+	}
+
+	b.at = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / rate.PerSecond * float64(time.Second))
+}
+
+// InMemory is a Store for tests and single node deployments.
+type InMemory struct {
+	mux     sync.Mutex
+	buckets map[string]*bucket
+	usage   map[model.ID]Usage
+	seeded  map[model.ID]bool
+	now     func() time.Time
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{
+		buckets: map[string]*bucket{},
+		usage:   map[model.ID]Usage{},
+		seeded:  map[model.ID]bool{},
+		now:     time.Now,
+	}
+}
+
+// Take implements the Store interface.
+func (m *InMemory) Take(ctx context.Context, key string, rate Rate) (time.Duration, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rate.burst(), at: now}
+		m.buckets[key] = b
+	}
+
+	return b.take(rate, now), nil
+}
+
+// Add implements the Store interface.
+func (m *InMemory) Add(ctx context.Context, tenantID model.ID, delta Usage, limits Limits) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	u := m.usage[tenantID]
+	if u.exceeds(delta, limits) {
+		return false, nil
+	}
+
+	m.usage[tenantID] = Usage{Entities: u.Entities + delta.Entities, EventBytes: u.EventBytes + delta.EventBytes}
+	return true, nil
+}
+
+// Usage implements the Store interface.
+func (m *InMemory) Usage(ctx context.Context, tenantID model.ID) (*Usage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	u := m.usage[tenantID]
+	return &u, nil
+}
+
+// Seeded implements the Store interface.
+func (m *InMemory) Seeded(ctx context.Context, tenantID model.ID) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.seeded[tenantID], nil
+}
+
+// Seed implements the Store interface.
+func (m *InMemory) Seed(ctx context.Context, tenantID model.ID, u Usage) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.seeded[tenantID] {
+		return false, nil
+	}
+
+	v := m.usage[tenantID]
+	m.usage[tenantID] = Usage{Entities: v.Entities + u.Entities, EventBytes: v.EventBytes + u.EventBytes}
+	m.seeded[tenantID] = true
+	return true, nil
+}
+
+// takeScript refills the bucket KEYS[1] at ARGV[1] requests per second up to ARGV[2] by the clock
+// of Redis, shared by all masters, and returns the milliseconds to wait for a request.
+var takeScript = redis.NewScript(`
+local rate, burst = tonumber(ARGV[1]), tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens, at = tonumber(state[1]), tonumber(state[2])
+if tokens == nil then
+	tokens, at = burst, now
+end
+if now > at then
+	tokens = math.min(burst, tokens + (now - at) / 1000 * rate)
+end
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return wait
+`)
+
+// addScript adds ARGV[1] entities and ARGV[2] event bytes to the usage KEYS[1], unless increases
+// go over the maximums ARGV[3] and ARGV[4] when they are positive.
+var addScript = redis.NewScript(`
+local entities = tonumber(redis.call('HGET', KEYS[1], 'entities') or '0')
+local bytes = tonumber(redis.call('HGET', KEYS[1], 'event_bytes') or '0')
+local de, db = tonumber(ARGV[1]), tonumber(ARGV[2])
+local me, mb = tonumber(ARGV[3]), tonumber(ARGV[4])
+if de > 0 and me > 0 and entities + de > me then
+	return 0
+end
+if db > 0 and mb > 0 and bytes + db > mb then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'entities', de)
+redis.call('HINCRBY', KEYS[1], 'event_bytes', db)
+return 1
+`)
+
+// seedScript adds ARGV[1] entities and ARGV[2] event bytes to the usage KEYS[1] unless it was
+// seeded already.
+var seedScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], 'seeded') == 1 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'entities', ARGV[1])
+redis.call('HINCRBY', KEYS[1], 'event_bytes', ARGV[2])
+redis.call('HSET', KEYS[1], 'seeded', 1)
+return 1
+`)
+
+// Redis is a Store shared by all masters, keeping buckets and usage in hashes.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take implements the Store interface.
+func (r *Redis) Take(ctx context.Context, key string, rate Rate) (time.Duration, error) {
+	wait, err := takeScript.Run(ctx, r.client, []string{fmt.Sprintf("%s:ratelimit:%s", r.prefix, key)}, rate.PerSecond, rate.burst()).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func (r *Redis) usageKey(tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:usage", r.prefix, tenantID)
+}
+
+// Add implements the Store interface.
+func (r *Redis) Add(ctx context.Context, tenantID model.ID, delta Usage, limits Limits) (bool, error) {
+	args := []interface{}{delta.Entities, delta.EventBytes, limits.MaxEntities, limits.MaxEventBytes}
+	ok, err := addScript.Run(ctx, r.client, []string{r.usageKey(tenantID)}, args...).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return ok == 1, nil
+}
+
+// Usage implements the Store interface.
+func (r *Redis) Usage(ctx context.Context, tenantID model.ID) (*Usage, error) {
+	var u Usage
+	if err := r.client.HMGet(ctx, r.usageKey(tenantID), "entities", "event_bytes").Scan(&u); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// Seeded implements the Store interface.
+func (r *Redis) Seeded(ctx context.Context, tenantID model.ID) (bool, error) {
+	return r.client.HExists(ctx, r.usageKey(tenantID), "seeded").Result()
+}
+
+// Seed implements the Store interface.
+func (r *Redis) Seed(ctx context.Context, tenantID model.ID, u Usage) (bool, error) {
+	ok, err := seedScript.Run(ctx, r.client, []string{r.usageKey(tenantID)}, u.Entities, u.EventBytes).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return ok == 1, nil
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields is a sparse fieldset, the paths of the attributes of a resource to return, such as id or
+// data.address.city. A nil Fields selects every attribute.
+type Fields [][]string
+
+// ParseFields parses a comma-separated list of dotted paths. Paths under another path of the list
+// are dropped, as it selects them already.
+func ParseFields(s string) (Fields, error) {
+	var paths []string
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(paths)
+
+	var f Fields
+	for _, path := range paths {
+		keys := strings.Split(path, ".")
+		for _, key := range keys {
+			if key == "" {
+				return nil, fmt.Errorf("invalid field %q", path)
+			}
+		}
+
+		if len(f) > 0 && f.selects(keys) {
+			continue
+		}
+
+		f = append(f, keys)
+	}
+
+	return f, nil
+}
+
+// selects reports whether keys is a path of f or under one.
+func (f Fields) selects(keys []string) bool {
+	for _, path := range f {
+		if len(path) <= len(keys) && strings.Join(path, ".") == strings.Join(keys[:len(path)], ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the paths of f separated by commas, in a canonical order.
+func (f Fields) String() string {
+	paths := make([]string, len(f))
+	for i, path := range f {
+		paths[i] = strings.Join(path, ".")
+	}
+
+	return strings.Join(paths, ",")
+}
+
+// Project returns the attributes of v selected by f, v being encoded as a JSON object. Paths
+// missing from v are left out, and a nil v projects to nil.
+func (f Fields) Project(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var src map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&src); err != nil {
+		return nil, err
+	}
+
+	if src == nil || f == nil {
+		return src, nil
+	}
+
+	dst := map[string]interface{}{}
+	for _, path := range f {
+		project(dst, src, path)
+	}
+
+	return dst, nil
+}
+
+func project(dst map[string]interface{}, src map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	next, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+	}
+
+	project(next, child, path[1:])
+	if len(next) > 0 {
+		dst[path[0]] = next
+	}
+}
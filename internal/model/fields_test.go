@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	f, err := ParseFields("data.address.city, id,data.address,version")
+	require.NoError(t, err)
+	assert.Equal(t, "data.address,id,version", f.String())
+
+	f, err = ParseFields("")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	_, err = ParseFields("data..city")
+	assert.Error(t, err)
+}
+
+func TestFields_Project(t *testing.T) {
+	v := struct {
+		ID   ID   `json:"id"`
+		Data Data `json:"data"`
+	}{
+		ID:   "1",
+		Data: Data{"name": "Ada", "address": map[string]interface{}{"city": "London", "zip": "N1"}, "age": 36},
+	}
+
+	f, err := ParseFields("id,data.address.city,data.age,data.missing.key")
+	require.NoError(t, err)
+
+	res, err := f.Project(v)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "1", "data": {"address": {"city": "London"}, "age": 36}}`, string(b))
+
+	res, err = f.Project(nil)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+}
@@ -1,14 +1,43 @@
 package model
 
-// Pagination is passed as a parameter to limit the total of rows.
+// Pagination is passed as a parameter to limit the total of rows. Rows start after the After
+// cursor, or at the beginning of the collection when it is nil.
 type Pagination struct {
-	Limit  int
-	Offset int
+	Limit int
+	After *Cursor
 }
 
-func NewPagination(perPage, page int) *Pagination {
+func NewPagination(limit int, after *Cursor) *Pagination {
 	return &Pagination{
-		Limit:  perPage,
-		Offset: page * perPage,
+		Limit: limit,
+		After: after,
 	}
 }
+
+// Page is a page of a collection. Next is the cursor of the following page, set when HasMore is.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+
+	Next *Cursor `json:"-"`
+}
+
+// NewPage returns a page of items followed by the page at next, if any.
+func NewPage[T any](items []T, next *Cursor) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return &Page[T]{
+		Items:   items,
+		HasMore: next != nil,
+		Next:    next,
+	}
+}
+
+// Sign sets NextCursor to the token of Next.
+func (p *Page[T]) Sign(signer *CursorSigner) *Page[T] {
+	p.NextCursor = signer.Encode(p.Next)
+	return p
+}
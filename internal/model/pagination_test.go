@@ -4,10 +4,43 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewPagination(t *testing.T) {
-	p := NewPagination(20, 5)
+	after := &Cursor{Score: 10, Key: "key", Offset: 1}
+	p := NewPagination(20, after)
 	assert.Equal(t, 20, p.Limit)
-	assert.Equal(t, 100, p.Offset)
+	assert.Equal(t, after, p.After)
+}
+
+func TestNewPage(t *testing.T) {
+	page := NewPage[string](nil, nil)
+	assert.Equal(t, []string{}, page.Items)
+	assert.False(t, page.HasMore)
+
+	page = NewPage([]string{"a"}, &Cursor{Offset: 1})
+	assert.True(t, page.HasMore)
+}
+
+func TestCursorSigner(t *testing.T) {
+	signer := NewCursorSigner([]byte("secret"))
+
+	c := &Cursor{Score: 1700000000, Key: "edgestore:1:tenant", Offset: 2}
+	token := signer.Encode(c)
+
+	decoded, err := signer.Decode(token)
+	require.NoError(t, err)
+	assert.Equal(t, c, decoded)
+
+	decoded, err = signer.Decode("")
+	require.NoError(t, err)
+	assert.Nil(t, decoded)
+	assert.Equal(t, "", signer.Encode(nil))
+
+	_, err = NewCursorSigner([]byte("other")).Decode(token)
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	_, err = signer.Decode("not a cursor")
+	assert.Equal(t, ErrInvalidCursor, err)
 }
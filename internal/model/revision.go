@@ -0,0 +1,33 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Revision identifies a state of an aggregate without its data.
+type Revision struct {
+	Version   Version
+	UpdatedAt *time.Time
+}
+
+// ParseRevision parses a revision from its cached representation, a version number and an optional
+// RFC 3339 update time.
+func ParseRevision(version string, updatedAt string) (*Revision, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %v", version, err)
+	}
+
+	r := &Revision{Version: Version(v)}
+	if updatedAt != "" {
+		t, err := time.Parse(time.RFC3339, updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid update time %q: %v", updatedAt, err)
+		}
+		r.UpdatedAt = &t
+	}
+
+	return r, nil
+}
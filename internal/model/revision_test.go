@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRevision(t *testing.T) {
+	r, err := ParseRevision("3", "2023-08-01T10:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, Version(3), r.Version)
+	assert.Equal(t, time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC), *r.UpdatedAt)
+
+	r, err = ParseRevision("1", "")
+	assert.NoError(t, err)
+	assert.Nil(t, r.UpdatedAt)
+
+	_, err = ParseRevision("", "")
+	assert.Error(t, err)
+
+	_, err = ParseRevision("1", "yesterday")
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package operation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// SweepInterval is how often InMemory drops expired operations. Get ignores them meanwhile.
+var SweepInterval = time.Minute
+
+type entry struct {
+	op        Operation
+	expiresAt time.Time
+}
+
+// InMemory is a Store keeping operations in memory, for tests and single node deployments.
+type InMemory struct {
+	mux        sync.Mutex
+	operations map[model.ID]entry
+	retention  time.Duration
+	swept      time.Time
+}
+
+func NewInMemory(retention time.Duration) *InMemory {
+	return &InMemory{
+		operations: map[model.ID]entry{},
+		retention:  retention,
+	}
+}
+
+// Get implements the Store interface.
+func (m *InMemory) Get(ctx context.Context, id model.ID, tenantID model.ID) (*Operation, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	e, ok := m.operations[tenantID+":"+id]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, notFound(id)
+	}
+
+	op := e.op
+	return &op, nil
+}
+
+// Put implements the Store interface.
+func (m *InMemory) Put(ctx context.Context, op *Operation) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.swept) >= SweepInterval {
+		for key, e := range m.operations {
+			if now.After(e.expiresAt) {
+				delete(m.operations, key)
+			}
+		}
+		m.swept = now
+	}
+
+	m.operations[op.TenantID+":"+op.ID] = entry{op: *op, expiresAt: now.Add(m.retention)}
+	return nil
+}
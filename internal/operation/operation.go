@@ -0,0 +1,162 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/worker"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRetention is how long finished operations are kept.
+var DefaultRetention = 24 * time.Hour
+
+type State string
+
+const (
+	Pending   State = "pending"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+)
+
+// ErrPending is the cause of the errors of synchronous writes whose wait ends before their command
+// is applied. Unlike with any other error, the command is queued and still applies.
+var ErrPending = errors.Str("the command is still pending")
+
+// PendingError is ErrPending along with the operation tracking the pending command, when there is
+// one.
+type PendingError struct {
+	Operation *Operation
+}
+
+func (e *PendingError) Error() string {
+	return ErrPending.Error()
+}
+
+// IsPending reports whether err is caused by ErrPending or a PendingError.
+func IsPending(err error) bool {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*PendingError); ok {
+		return true
+	}
+
+	return cause == ErrPending
+}
+
+// PendingOperation returns the operation tracking the pending command of err, nil when there is
+// none.
+func PendingOperation(err error) *Operation {
+	if e, ok := errors.Cause(err).(*PendingError); ok {
+		return e.Operation
+	}
+
+	return nil
+}
+
+// Operation records the state of an enqueued job.
+type Operation struct {
+	ID        model.ID   `json:"id"`
+	Name      string     `json:"name"`
+	TenantID  model.ID   `json:"tenant_id"`
+	State     State      `json:"state"`
+	Error     string     `json:"error,omitempty"`
+	ErrorKind string     `json:"error_kind,omitempty"`
+	ErrorCode string     `json:"error_code,omitempty"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// Store persists operations for a retention period.
+type Store interface {
+	Get(ctx context.Context, id model.ID, tenantID model.ID) (*Operation, error)
+	Put(ctx context.Context, op *Operation) error
+}
+
+// Tracker records the state of the jobs it tracks as operations.
+type Tracker struct {
+	store  Store
+	logger logrus.FieldLogger
+}
+
+func NewTracker(store Store, logger logrus.FieldLogger) *Tracker {
+	return &Tracker{
+		store:  store,
+		logger: logger.WithField("component", "operation-tracker"),
+	}
+}
+
+// Track records a pending operation for job and returns it along with a job updating its state
+// when processed. A nil Tracker tracks nothing and returns job as is.
+func (t *Tracker) Track(ctx context.Context, tenantID model.ID, job worker.Job) (*Operation, worker.Job, error) {
+	const op errors.Op = "operation/Tracker.Track"
+
+	if t == nil {
+		return nil, job, nil
+	}
+
+	now := time.Now().UTC()
+	o := &Operation{
+		ID:        model.ID(uuid.Must(uuid.NewV4()).String()),
+		Name:      job.Name(),
+		TenantID:  tenantID,
+		State:     Pending,
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+
+	if err := t.store.Put(ctx, o); err != nil {
+		return nil, nil, errors.E(op, errors.IO, err)
+	}
+
+	tracked := worker.NewJob(job.Name(), func() error {
+		t.update(o, Running, nil)
+		err := job.Do()
+		if err != nil {
+			t.update(o, Failed, err)
+		} else {
+			t.update(o, Succeeded, nil)
+		}
+		return err
+	})
+
+	// Return a copy as the job updates o concurrently.
+	res := *o
+	return &res, tracked, nil
+}
+
+func (t *Tracker) update(o *Operation, state State, err error) {
+	now := time.Now().UTC()
+	o.State = state
+	o.UpdatedAt = &now
+
+	if err != nil {
+		o.Error = errors.Message(err)
+		o.ErrorKind = errors.KindOf(err).Name()
+		o.ErrorCode = string(errors.CodeOf(err))
+	}
+
+	if err := t.store.Put(context.Background(), o); err != nil {
+		t.logger.Errorf("unable to record operation %s as %s: %v", o.ID, state, err)
+	}
+}
+
+// Get returns the operation with the given ID.
+func (t *Tracker) Get(ctx context.Context, id model.ID, tenantID model.ID) (*Operation, error) {
+	const op errors.Op = "operation/Tracker.Get"
+
+	o, err := t.store.Get(ctx, id, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return o, nil
+}
+
+func notFound(id model.ID) error {
+	return errors.E(errors.NotFound, fmt.Sprintf("operation %s not found", id))
+}
@@ -0,0 +1,118 @@
+package operation
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/worker"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+func TestTracker_Track(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTracker(NewInMemory(time.Hour), newLogger())
+
+	op, job, err := tracker.Track(ctx, "tenant", worker.NewJob("create-1", func() error { return nil }))
+	require.NoError(t, err)
+	assert.Equal(t, Pending, op.State)
+	assert.Equal(t, "create-1", op.Name)
+
+	got, err := tracker.Get(ctx, op.ID, "tenant")
+	require.NoError(t, err)
+	assert.Equal(t, Pending, got.State)
+
+	require.NoError(t, job.Do())
+
+	got, err = tracker.Get(ctx, op.ID, "tenant")
+	require.NoError(t, err)
+	assert.Equal(t, Succeeded, got.State)
+	assert.Empty(t, got.Error)
+
+	_, err = tracker.Get(ctx, op.ID, "other")
+	assert.True(t, errors.Is(errors.NotFound, err))
+}
+
+func TestTracker_Track_Failed(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTracker(NewInMemory(time.Hour), newLogger())
+
+	op, job, err := tracker.Track(ctx, "tenant", worker.NewJob("update-1", func() error {
+		return errors.E(errors.Invalid, "invalid data")
+	}))
+	require.NoError(t, err)
+	assert.Error(t, job.Do())
+
+	got, err := tracker.Get(ctx, op.ID, "tenant")
+	require.NoError(t, err)
+	assert.Equal(t, Failed, got.State)
+	assert.Contains(t, got.Error, "invalid data")
+	assert.Equal(t, errors.Invalid.Name(), got.ErrorKind)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	op, job, err := tracker.Track(context.Background(), "tenant", worker.NewJob("noop", func() error { return nil }))
+	require.NoError(t, err)
+	assert.Nil(t, op)
+	assert.NotNil(t, job)
+}
+
+func TestPendingOperation(t *testing.T) {
+	o := &Operation{ID: "1"}
+
+	err := errors.E(errors.Op("test"), errors.Transient, &PendingError{Operation: o})
+	assert.True(t, IsPending(err))
+	assert.Equal(t, o, PendingOperation(err))
+
+	err = errors.E(errors.Transient, ErrPending)
+	assert.True(t, IsPending(err))
+	assert.Nil(t, PendingOperation(err))
+
+	assert.False(t, IsPending(errors.E(errors.Transient, "unavailable")))
+}
+
+func TestInMemory_Retention(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory(time.Millisecond)
+
+	require.NoError(t, store.Put(ctx, &Operation{ID: "1", TenantID: "tenant"}))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := store.Get(ctx, "1", "tenant")
+	assert.True(t, errors.Is(errors.NotFound, err))
+}
+
+func TestInMemory_Sweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory(time.Hour)
+
+	require.NoError(t, store.Put(ctx, &Operation{ID: "1", TenantID: "tenant"}))
+	e := store.operations["tenant:1"]
+	e.expiresAt = time.Now().Add(-time.Second)
+	store.operations["tenant:1"] = e
+
+	// Expired operations are kept until the next sweep, but not found.
+	require.NoError(t, store.Put(ctx, &Operation{ID: "2", TenantID: "tenant"}))
+	assert.Len(t, store.operations, 2)
+
+	_, err := store.Get(ctx, "1", "tenant")
+	assert.True(t, errors.Is(errors.NotFound, err))
+
+	store.swept = time.Now().Add(-SweepInterval)
+	require.NoError(t, store.Put(ctx, &Operation{ID: "3", TenantID: "tenant"}))
+	assert.Len(t, store.operations, 2)
+	assert.NotContains(t, store.operations, model.ID("tenant:1"))
+}
@@ -0,0 +1,59 @@
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store keeping operations as expiring JSON strings.
+type Redis struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+}
+
+func NewRedis(client *redis.Client, prefix string, retention time.Duration) *Redis {
+	return &Redis{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+func (r *Redis) key(id model.ID, tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:operation:%s", r.prefix, tenantID, id)
+}
+
+// Get implements the Store interface.
+func (r *Redis) Get(ctx context.Context, id model.ID, tenantID model.ID) (*Operation, error) {
+	b, err := r.client.Get(ctx, r.key(id, tenantID)).Bytes()
+	if err == redis.Nil {
+		return nil, notFound(id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var op Operation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return nil, err
+	}
+
+	return &op, nil
+}
+
+// Put implements the Store interface.
+func (r *Redis) Put(ctx context.Context, op *Operation) error {
+	b, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, r.key(op.ID, op.TenantID), b, r.retention).Err()
+}
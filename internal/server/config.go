@@ -33,7 +33,11 @@ type Config struct {
 	// The default is 8081.
 	RPCPort int `json:"rpc_port"`
 
-	// Enable pprof Profiling. Off by default.
+	// AdminPort, when set, serves the debug endpoints, runtime stats and pprof, on their own
+	// port. They are not served otherwise. Off by default.
+	AdminPort int `json:"admin_port"`
+
+	// Enable pprof Profiling on AdminPort. Off by default.
 	EnablePProf bool `json:"enable_pprof"`
 
 	// LoggerHandler level (eg.: panic, fatal, error, warn, info, debug)
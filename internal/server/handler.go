@@ -1,18 +1,18 @@
 package server
 
 import (
-	"net/http"
 	"time"
 
+	"github.com/edgestore/edgestore/internal/errors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 )
 
-// NotFoundHandler is a helper function that calls Server.Abort.
+// NotFoundHandler answers the requests matching no route.
 func NotFoundHandler(c *gin.Context) {
-	Abort(c, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	AbortWithError(c, errors.E(errors.NotFound, errors.Code("route_not_found"), "no route matches the request"))
 }
 
 // LoggerHandler returns a gin.HandlerFunc (middleware) that logs requests using logrus.
@@ -65,12 +65,16 @@ func CORSHandler() gin.HandlerFunc {
 		"Accept",
 		"Authorization",
 		"Content-Type",
+		"Idempotency-Key",
+		"If-Modified-Since",
+		"If-None-Match",
 		"Keep-Alive",
 		"Origin",
 		"User-Agent",
 		"X-Requested-With",
 	}
 	config.AllowHeaders = append(config.AllowHeaders, allowHeaders...)
+	config.ExposeHeaders = []string{"ETag", "Idempotent-Replayed", "Last-Modified"}
 	config.AllowAllOrigins = true
 	config.AllowCredentials = true
 	return cors.New(config)
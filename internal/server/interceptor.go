@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is the gRPC counterpart of the X-Request-Id header.
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// RequestID returns the request ID set by the request ID interceptors.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var reqID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+			reqID = values[0]
+		}
+	}
+
+	if reqID == "" {
+		reqID = uuid.Must(uuid.NewV4()).String()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, reqID))
+	return context.WithValue(ctx, requestIDKey{}, reqID)
+}
+
+// RequestIDUnaryInterceptor is the gRPC counterpart of RequestIDHandler. It reads the x-request-id
+// metadata, generating one when missing, and sends it back as a response header.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// RequestIDStreamInterceptor is the streaming counterpart of RequestIDUnaryInterceptor.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = withRequestID(stream.Context())
+		return handler(srv, wrapped)
+	}
+}
+
+func logCall(logger logrus.FieldLogger, ctx context.Context, method string, start time.Time, err error) {
+	entry := logger.WithFields(logrus.Fields{
+		"method":       method,
+		"code":         status.Code(err).String(),
+		"x-request-id": RequestID(ctx),
+		"latency":      time.Since(start),
+	})
+
+	if err != nil {
+		entry.Error(err)
+	} else {
+		entry.Info()
+	}
+}
+
+// LoggerUnaryInterceptor is the gRPC counterpart of LoggerHandler.
+func LoggerUnaryInterceptor(logger logrus.FieldLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		logCall(logger, ctx, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// LoggerStreamInterceptor is the streaming counterpart of LoggerUnaryInterceptor.
+func LoggerStreamInterceptor(logger logrus.FieldLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(logger, stream.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
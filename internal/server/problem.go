@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an error response, following RFC 7807 with the kind and the code of the error as
+// extension members.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+
+	// Kind is the stable name of the kind of the error, such as "not_found".
+	Kind string `json:"kind"`
+
+	// Code identifies the error more precisely than its kind, such as "quota_exceeded". It is
+	// the name of the kind when there is no more precise code.
+	Code string `json:"code"`
+
+	// RequestID is the X-Request-Id of the request, to correlate the error with server logs.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Errors are the fields of the request failing validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a field of a request failing validation.
+type FieldError struct {
+	// Field is the JSON path of the field, such as "tuple_to_userset.tupleset".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%d - %s", p.Status, p.Detail)
+}
+
+func init() {
+	// Name fields in validation errors as clients send them.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+
+			if name == "" {
+				return f.Name
+			}
+
+			return name
+		})
+	}
+}
+
+// NewProblem describes err as a response to the request of c, mapping its kind to the status.
+func NewProblem(c *gin.Context, err error) *Problem {
+	kind := errors.KindOf(err)
+	p := &Problem{
+		Type:      "about:blank",
+		Status:    kind.HTTPStatus(),
+		Detail:    errors.Message(err),
+		Kind:      kind.Name(),
+		Code:      string(errors.CodeOf(err)),
+		RequestID: c.Writer.Header().Get("X-Request-Id"),
+	}
+	p.Title = http.StatusText(p.Status)
+
+	if c.Request != nil && c.Request.URL != nil {
+		p.Instance = c.Request.URL.Path
+	}
+
+	switch cause := errors.Cause(err).(type) {
+	case validator.ValidationErrors:
+		p.Detail = "The request has invalid fields."
+		for _, fe := range cause {
+			p.Errors = append(p.Errors, FieldError{Field: fieldPath(fe.Namespace()), Message: validationMessage(fe)})
+		}
+	case *json.UnmarshalTypeError:
+		p.Detail = "The request has invalid fields."
+		p.Errors = append(p.Errors, FieldError{Field: cause.Field, Message: fmt.Sprintf("must be %s", cause.Type)})
+	}
+
+	if len(p.Errors) > 0 && p.Code == errors.Invalid.Name() {
+		p.Code = "validation_failed"
+	}
+
+	return p
+}
+
+// fieldPath drops the name of the request struct from the namespace of a validation error.
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return namespace
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	}
+
+	if fe.Param() != "" {
+		return fmt.Sprintf("fails the %s=%s constraint", fe.Tag(), fe.Param())
+	}
+
+	return fmt.Sprintf("fails the %s constraint", fe.Tag())
+}
+
+// AbortWithProblem stops the chain of c and responds with p.
+func AbortWithProblem(c *gin.Context, p *Problem) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+
+	c.Abort()
+	c.Data(p.Status, ProblemContentType, b)
+}
+
+// AbortWithError stops the chain of c and responds with the problem describing err.
+func AbortWithError(c *gin.Context, err error) {
+	AbortWithProblem(c, NewProblem(c, err))
+}
@@ -28,6 +28,9 @@ type server struct {
 	HTTPServer *http.Server
 	GRPCServer *grpc.Server
 
+	// AdminServer, when set, serves the debug endpoints on Config.AdminPort.
+	AdminServer *http.Server
+
 	Shutdown func()
 
 	// Exit chan for graceful Shutdown
@@ -54,6 +57,17 @@ func (s *server) start() error {
 	}()
 	s.Logger.Infof("Listening and serving HTTP on %s", s.HTTPServer.Addr)
 
+	if s.AdminServer != nil {
+		go func() {
+			err := s.AdminServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				s.Logger.Errorf("Admin Server error - initiating shutting down: %v", err)
+				s.stop()
+			}
+		}()
+		s.Logger.Infof("Listening and serving admin HTTP on %s", s.AdminServer.Addr)
+	}
+
 	if s.GRPCServer != nil {
 		addr := fmt.Sprintf(":%d", s.Config.RPCPort)
 		lis, err := net.Listen("tcp", addr)
@@ -92,6 +106,13 @@ func (s *server) start() error {
 			s.GRPCServer.GracefulStop()
 		}
 
+		// stop admin Server
+		if s.AdminServer != nil {
+			if err := s.AdminServer.Shutdown(ctx); err != nil {
+				s.Logger.Errorf("Admin Server shutdown error: %v", err)
+			}
+		}
+
 		// stop HTTP Server
 		exit <- s.HTTPServer.Shutdown(ctx)
 	}()
@@ -144,3 +165,15 @@ func NewHTTPServer(cfg Config, handler http.Handler) *http.Server {
 		IdleTimeout:    cfg.IdleTimeout,
 	}
 }
+
+// NewAdminServer returns the HTTP server of the debug endpoints, on the admin port. Profiles
+// outlast the write timeout of the main server, so it has none.
+func NewAdminServer(cfg Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		Addr:           fmt.Sprintf(":%d", cfg.AdminPort),
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		ReadTimeout:    cfg.ReadTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
+	}
+}
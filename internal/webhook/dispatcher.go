@@ -0,0 +1,338 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBackoff      = 5 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultPollInterval = time.Second
+	DefaultTimeout      = 10 * time.Second
+
+	// DefaultConcurrency is the number of deliveries sent at once to each webhook.
+	DefaultConcurrency = 4
+
+	// DefaultVisibility covers the attempts of a claim sent DefaultConcurrency at a time to a single
+	// webhook, each timing out.
+	DefaultVisibility = (claimLimit/DefaultConcurrency + 1) * DefaultTimeout
+
+	// claimLimit is the number of deliveries claimed by each poll.
+	claimLimit = 64
+)
+
+type Config struct {
+	Store Store
+
+	// Client sends the deliveries. The default client refuses to connect to the addresses of
+	// Blocked.
+	Client *http.Client
+	Logger logrus.FieldLogger
+
+	// MaxAttempts is the number of failed attempts after which a delivery is moved to the dead
+	// letters of its webhook.
+	MaxAttempts int
+
+	// Backoff is the delay after the first failed attempt, doubled after each further attempt up
+	// to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	PollInterval time.Duration
+
+	// Concurrency is the number of deliveries sent at once to each webhook.
+	Concurrency int
+
+	// Visibility is the time claimed deliveries are given to be attempted before they are
+	// queued again, should their node stop.
+	Visibility time.Duration
+}
+
+// Dispatcher schedules and sends the deliveries of webhooks.
+type Dispatcher struct {
+	store        Store
+	client       *http.Client
+	logger       logrus.FieldLogger
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	concurrency  int
+	visibility   time.Duration
+	now          func() time.Time
+}
+
+func NewDispatcher(cfg *Config) *Dispatcher {
+	d := &Dispatcher{
+		store:        cfg.Store,
+		client:       cfg.Client,
+		logger:       cfg.Logger.WithField("component", "webhook-dispatcher"),
+		maxAttempts:  cfg.MaxAttempts,
+		backoff:      cfg.Backoff,
+		maxBackoff:   cfg.MaxBackoff,
+		pollInterval: cfg.PollInterval,
+		concurrency:  cfg.Concurrency,
+		visibility:   cfg.Visibility,
+		now:          time.Now,
+	}
+
+	if d.client == nil {
+		d.client = NewClient(DefaultTimeout)
+	}
+
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = DefaultMaxAttempts
+	}
+
+	if d.backoff <= 0 {
+		d.backoff = DefaultBackoff
+	}
+
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = DefaultMaxBackoff
+	}
+
+	if d.pollInterval <= 0 {
+		d.pollInterval = DefaultPollInterval
+	}
+
+	if d.concurrency <= 0 {
+		d.concurrency = DefaultConcurrency
+	}
+
+	if d.visibility <= 0 {
+		d.visibility = DefaultVisibility
+	}
+
+	return d
+}
+
+// NewClient returns a client refusing to connect to the addresses of Blocked, whatever the host
+// of the webhook resolves to when it is called and wherever it redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || Blocked(ip) {
+				return fmt.Errorf("connections to %s are not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	// Deliveries are not sent through proxies, which would be the address dialed.
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConnsPerHost: DefaultConcurrency,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: timeout,
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Store returns the store of webhooks and deliveries.
+func (d *Dispatcher) Store() Store {
+	return d.store
+}
+
+// Notify schedules a delivery of payload to every webhook of the tenant subscribed to kind.
+func (d *Dispatcher) Notify(ctx context.Context, tenantID model.ID, kind string, payload []byte) error {
+	const op errors.Op = "webhook/Dispatcher.Notify"
+
+	webhooks, err := d.store.ListWebhooks(ctx, tenantID)
+	if err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	now := d.now().UTC()
+	for _, w := range webhooks {
+		if !w.Subscribed(kind) {
+			continue
+		}
+
+		delivery := &Delivery{
+			ID:            model.ID(uuid.Must(uuid.NewV4()).String()),
+			WebhookID:     w.ID,
+			TenantID:      tenantID,
+			Kind:          kind,
+			Payload:       payload,
+			State:         Pending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+
+		if err := d.store.Schedule(ctx, delivery); err != nil {
+			return errors.E(op, errors.Transient, err)
+		}
+	}
+
+	return nil
+}
+
+// Replay moves a dead delivery back to the queue for an immediate attempt.
+func (d *Dispatcher) Replay(ctx context.Context, id model.ID, webhookID model.ID, tenantID model.ID) (*Delivery, error) {
+	const op errors.Op = "webhook/Dispatcher.Replay"
+
+	delivery, err := d.store.Unbury(ctx, id, webhookID, tenantID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	delivery.State = Pending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = d.now().UTC()
+
+	if err := d.store.Schedule(ctx, delivery); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return delivery, nil
+}
+
+// Run processes due deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.ProcessDue(ctx); err != nil {
+			d.logger.Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue attempts the deliveries due now and returns the number of attempts. Deliveries are
+// sent concurrently, up to the concurrency of the dispatcher for each webhook.
+func (d *Dispatcher) ProcessDue(ctx context.Context) (int, error) {
+	const op errors.Op = "webhook/Dispatcher.ProcessDue"
+
+	deliveries, err := d.store.Claim(ctx, d.now(), claimLimit, d.visibility)
+	if err != nil {
+		return 0, errors.E(op, errors.Transient, err)
+	}
+
+	var wg sync.WaitGroup
+	slots := map[string]chan struct{}{}
+	for _, delivery := range deliveries {
+		key := string(delivery.TenantID) + "/" + string(delivery.WebhookID)
+		slot, ok := slots[key]
+		if !ok {
+			slot = make(chan struct{}, d.concurrency)
+			slots[key] = slot
+		}
+
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer wg.Done()
+
+			slot <- struct{}{}
+			defer func() { <-slot }()
+
+			if err := d.attempt(ctx, delivery); err != nil {
+				d.logger.Error(errors.E(op, err))
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) error {
+	w, err := d.store.GetWebhook(ctx, delivery.WebhookID, delivery.TenantID)
+	if errors.Is(errors.NotFound, err) {
+		// The webhook was deleted since the delivery was scheduled.
+		return d.store.Complete(ctx, delivery)
+	}
+
+	if err != nil {
+		// Retried without counting an attempt against the endpoint.
+		delivery.NextAttemptAt = d.now().Add(d.backoff)
+		return d.store.Schedule(ctx, delivery)
+	}
+
+	delivery.Attempts++
+	status, err := d.send(ctx, w, delivery)
+	delivery.LastStatus = status
+	if err == nil {
+		delivery.LastError = ""
+		return d.store.Complete(ctx, delivery)
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.State = Dead
+		return d.store.Bury(ctx, delivery)
+	}
+
+	delivery.NextAttemptAt = d.now().Add(d.delay(delivery.Attempts))
+	return d.store.Schedule(ctx, delivery)
+}
+
+// delay returns the backoff after the given number of failed attempts.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) send(ctx context.Context, w *Webhook, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "edgestore-webhook")
+	req.Header.Set(SignatureHeader, Sign(w.Secret, d.now(), delivery.Payload))
+	req.Header.Set(DeliveryHeader, string(delivery.ID))
+	req.Header.Set(KindHeader, delivery.Kind)
+	req.Header.Set(WebhookHeader, string(w.ID))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// InMemory is a Store for tests and single node deployments. Its queue does not survive restarts.
+type InMemory struct {
+	mux      sync.Mutex
+	webhooks map[model.ID]Webhook
+	queue    map[model.ID]Delivery
+	inFlight map[model.ID]claim
+	dead     map[model.ID]Delivery
+}
+
+// claim is a delivery in flight, visible until a time.
+type claim struct {
+	delivery Delivery
+	until    time.Time
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{
+		webhooks: map[model.ID]Webhook{},
+		queue:    map[model.ID]Delivery{},
+		inFlight: map[model.ID]claim{},
+		dead:     map[model.ID]Delivery{},
+	}
+}
+
+// GetWebhook implements the Store interface.
+func (m *InMemory) GetWebhook(ctx context.Context, id model.ID, tenantID model.ID) (*Webhook, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	w, ok := m.webhooks[id]
+	if !ok || w.TenantID != tenantID {
+		return nil, notFound("webhook", id)
+	}
+
+	return &w, nil
+}
+
+// ListWebhooks implements the Store interface.
+func (m *InMemory) ListWebhooks(ctx context.Context, tenantID model.ID) ([]*Webhook, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var webhooks []*Webhook
+	for _, w := range m.webhooks {
+		if w.TenantID == tenantID {
+			w := w
+			webhooks = append(webhooks, &w)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+// PutWebhook implements the Store interface.
+func (m *InMemory) PutWebhook(ctx context.Context, w *Webhook) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.webhooks[w.ID] = *w
+	return nil
+}
+
+// DeleteWebhook implements the Store interface.
+func (m *InMemory) DeleteWebhook(ctx context.Context, id model.ID, tenantID model.ID) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if w, ok := m.webhooks[id]; !ok || w.TenantID != tenantID {
+		return notFound("webhook", id)
+	}
+
+	delete(m.webhooks, id)
+	return nil
+}
+
+// Schedule implements the Store interface.
+func (m *InMemory) Schedule(ctx context.Context, d *Delivery) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.inFlight, d.ID)
+	m.queue[d.ID] = *d
+	return nil
+}
+
+// Claim implements the Store interface.
+func (m *InMemory) Claim(ctx context.Context, now time.Time, limit int, visibility time.Duration) ([]*Delivery, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for id, c := range m.inFlight {
+		if !c.until.After(now) {
+			delete(m.inFlight, id)
+			m.queue[id] = c.delivery
+		}
+	}
+
+	var due []*Delivery
+	for _, d := range m.queue {
+		if !d.NextAttemptAt.After(now) {
+			d := d
+			due = append(due, &d)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, d := range due {
+		delete(m.queue, d.ID)
+		m.inFlight[d.ID] = claim{delivery: *d, until: now.Add(visibility)}
+	}
+
+	return due, nil
+}
+
+// Complete implements the Store interface.
+func (m *InMemory) Complete(ctx context.Context, d *Delivery) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.inFlight, d.ID)
+	return nil
+}
+
+// Bury implements the Store interface.
+func (m *InMemory) Bury(ctx context.Context, d *Delivery) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.inFlight, d.ID)
+	m.dead[d.ID] = *d
+	return nil
+}
+
+// DeadLetters implements the Store interface.
+func (m *InMemory) DeadLetters(ctx context.Context, webhookID model.ID, tenantID model.ID, p *model.Pagination) (*model.Page[*Delivery], error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var deliveries []*Delivery
+	for _, d := range m.dead {
+		if d.WebhookID == webhookID && d.TenantID == tenantID && before(&d, p.After) {
+			d := d
+			deliveries = append(deliveries, &d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return before(deliveries[j], cursor(deliveries[i])) })
+	if p.Limit <= 0 {
+		return model.NewPage[*Delivery](nil, nil), nil
+	}
+
+	if len(deliveries) > p.Limit {
+		return model.NewPage(deliveries[:p.Limit], cursor(deliveries[p.Limit-1])), nil
+	}
+
+	return model.NewPage(deliveries, nil), nil
+}
+
+// cursor returns the position of a dead letter, sorted by creation time then ID.
+func cursor(d *Delivery) *model.Cursor {
+	return &model.Cursor{Score: float64(d.CreatedAt.UnixMilli()), Key: string(d.ID)}
+}
+
+// before reports whether d follows the position c in the dead letters, most recent first.
+func before(d *Delivery, c *model.Cursor) bool {
+	if c == nil {
+		return true
+	}
+
+	pos := cursor(d)
+	return pos.Score < c.Score || (pos.Score == c.Score && pos.Key < c.Key)
+}
+
+// Unbury implements the Store interface.
+func (m *InMemory) Unbury(ctx context.Context, id model.ID, webhookID model.ID, tenantID model.ID) (*Delivery, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	d, ok := m.dead[id]
+	if !ok || d.WebhookID != webhookID || d.TenantID != tenantID {
+		return nil, notFound("delivery", id)
+	}
+
+	delete(m.dead, id)
+	return &d, nil
+}
@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/cache/rediscache"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store keeping webhooks and deliveries as JSON strings. Queued deliveries are scored by
+// their next attempt time in a sorted set shared by every node, and claimed deliveries by the end
+// of their visibility in another.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *Redis) webhookKey(id model.ID, tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:webhook:%s", r.prefix, tenantID, id)
+}
+
+func (r *Redis) webhooksKey(tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:webhooks", r.prefix, tenantID)
+}
+
+func (r *Redis) deliveryKey(id model.ID) string {
+	return fmt.Sprintf("%s:webhook-delivery:%s", r.prefix, id)
+}
+
+func (r *Redis) queueKey() string {
+	return fmt.Sprintf("%s:webhook-queue", r.prefix)
+}
+
+func (r *Redis) inFlightKey() string {
+	return fmt.Sprintf("%s:webhook-in-flight", r.prefix)
+}
+
+func (r *Redis) deadKey(webhookID model.ID, tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:webhook:%s:dead", r.prefix, tenantID, webhookID)
+}
+
+func (r *Redis) get(ctx context.Context, key string, v interface{}) (bool, error) {
+	b, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(b, v)
+}
+
+func (r *Redis) set(ctx context.Context, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, key, b, 0).Err()
+}
+
+// GetWebhook implements the Store interface.
+func (r *Redis) GetWebhook(ctx context.Context, id model.ID, tenantID model.ID) (*Webhook, error) {
+	var w Webhook
+	found, err := r.get(ctx, r.webhookKey(id, tenantID), &w)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, notFound("webhook", id)
+	}
+
+	return &w, nil
+}
+
+// ListWebhooks implements the Store interface.
+func (r *Redis) ListWebhooks(ctx context.Context, tenantID model.ID) ([]*Webhook, error) {
+	ids, err := r.client.SMembers(ctx, r.webhooksKey(tenantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]*Webhook, 0, len(ids))
+	for _, id := range ids {
+		w, err := r.GetWebhook(ctx, model.ID(id), tenantID)
+		if err != nil {
+			continue
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, nil
+}
+
+// PutWebhook implements the Store interface.
+func (r *Redis) PutWebhook(ctx context.Context, w *Webhook) error {
+	if err := r.set(ctx, r.webhookKey(w.ID, w.TenantID), w); err != nil {
+		return err
+	}
+
+	return r.client.SAdd(ctx, r.webhooksKey(w.TenantID), string(w.ID)).Err()
+}
+
+// DeleteWebhook implements the Store interface.
+func (r *Redis) DeleteWebhook(ctx context.Context, id model.ID, tenantID model.ID) error {
+	n, err := r.client.Del(ctx, r.webhookKey(id, tenantID), r.deadKey(id, tenantID)).Result()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return notFound("webhook", id)
+	}
+
+	return r.client.SRem(ctx, r.webhooksKey(tenantID), string(id)).Err()
+}
+
+// Schedule implements the Store interface.
+func (r *Redis) Schedule(ctx context.Context, d *Delivery) error {
+	if err := r.set(ctx, r.deliveryKey(d.ID), d); err != nil {
+		return err
+	}
+
+	z := redis.Z{
+		Member: string(d.ID),
+		Score:  float64(d.NextAttemptAt.UnixMilli()),
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, r.queueKey(), z)
+	pipe.ZRem(ctx, r.inFlightKey(), string(d.ID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// claimScript queues again the deliveries of the in-flight set KEYS[2] whose visibility ended by
+// ARGV[1], then moves up to ARGV[2] deliveries due by ARGV[1] from the queue KEYS[1] to KEYS[2],
+// visible until ARGV[3], and returns their IDs.
+var claimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('ZADD', KEYS[1], ARGV[1], id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('ZADD', KEYS[2], ARGV[3], id)
+end
+return ids
+`)
+
+// Claim implements the Store interface.
+func (r *Redis) Claim(ctx context.Context, now time.Time, limit int, visibility time.Duration) ([]*Delivery, error) {
+	keys := []string{r.queueKey(), r.inFlightKey()}
+	ids, err := claimScript.Run(ctx, r.client, keys, now.UnixMilli(), limit, now.Add(visibility).UnixMilli()).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*Delivery
+	for _, id := range ids {
+		var d Delivery
+		found, err := r.get(ctx, r.deliveryKey(model.ID(id)), &d)
+		if err != nil {
+			// Left in flight, to be queued again.
+			continue
+		}
+
+		if !found {
+			r.client.ZRem(ctx, r.inFlightKey(), id)
+			continue
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, nil
+}
+
+// Complete implements the Store interface.
+func (r *Redis) Complete(ctx context.Context, d *Delivery) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.deliveryKey(d.ID))
+	pipe.ZRem(ctx, r.inFlightKey(), string(d.ID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Bury implements the Store interface.
+func (r *Redis) Bury(ctx context.Context, d *Delivery) error {
+	if err := r.set(ctx, r.deliveryKey(d.ID), d); err != nil {
+		return err
+	}
+
+	z := redis.Z{
+		Member: string(d.ID),
+		Score:  float64(d.CreatedAt.UnixMilli()),
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, r.deadKey(d.WebhookID, d.TenantID), z)
+	pipe.ZRem(ctx, r.inFlightKey(), string(d.ID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeadLetters implements the Store interface.
+func (r *Redis) DeadLetters(ctx context.Context, webhookID model.ID, tenantID model.ID, p *model.Pagination) (*model.Page[*Delivery], error) {
+	ids, next, err := rediscache.RevRangeAfter(ctx, r.client, r.deadKey(webhookID, tenantID), p)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(ids))
+	for _, id := range ids {
+		var d Delivery
+		if found, err := r.get(ctx, r.deliveryKey(model.ID(id)), &d); err != nil || !found {
+			continue
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	return model.NewPage(deliveries, next), nil
+}
+
+// Unbury implements the Store interface.
+func (r *Redis) Unbury(ctx context.Context, id model.ID, webhookID model.ID, tenantID model.ID) (*Delivery, error) {
+	n, err := r.client.ZRem(ctx, r.deadKey(webhookID, tenantID), string(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, notFound("delivery", id)
+	}
+
+	var d Delivery
+	found, err := r.get(ctx, r.deliveryKey(id), &d)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, notFound("delivery", id)
+	}
+
+	return &d, nil
+}
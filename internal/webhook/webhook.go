@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+const (
+	// SignatureHeader holds the signature of a delivery, see Sign.
+	SignatureHeader = "Edgestore-Signature"
+
+	DeliveryHeader = "Edgestore-Delivery"
+	KindHeader     = "Edgestore-Event-Kind"
+	WebhookHeader  = "Edgestore-Webhook"
+)
+
+// Webhook is an endpoint of a tenant notified of the events of the given kinds.
+type Webhook struct {
+	ID       model.ID `json:"id"`
+	TenantID model.ID `json:"tenant_id"`
+	URL      string   `json:"url" binding:"required"`
+	Kinds    []string `json:"kinds" binding:"required"`
+
+	// Secret signs the deliveries. It is generated when omitted.
+	Secret string `json:"secret,omitempty"`
+
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// Resolver looks up the addresses of webhook hosts when they are registered.
+var Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+} = net.DefaultResolver
+
+// Validate checks the URL and kinds of w against the known event kinds. The host of the URL must
+// only resolve to public addresses, see Blocked.
+func (w *Webhook) Validate(ctx context.Context, kinds []string) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.E(errors.Invalid, fmt.Sprintf("invalid url %q", w.URL))
+	}
+
+	addrs, err := Resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return errors.E(errors.Invalid, fmt.Sprintf("unable to resolve the host of url %q", w.URL))
+	}
+
+	for _, addr := range addrs {
+		if Blocked(addr.IP) {
+			return errors.E(errors.Invalid, errors.Code("private_address"), fmt.Sprintf("url %q resolves to the non-public address %s", w.URL, addr.IP))
+		}
+	}
+
+	if len(w.Kinds) == 0 {
+		return errors.E(errors.Invalid, "at least one event kind is required")
+	}
+
+	for _, kind := range w.Kinds {
+		known := false
+		for _, k := range kinds {
+			known = known || k == kind
+		}
+
+		if !known {
+			return errors.E(errors.Invalid, fmt.Sprintf("unknown event kind %q", kind))
+		}
+	}
+
+	return nil
+}
+
+// Blocked reports whether webhooks may not reach ip: loopback, private, link-local, such as the
+// metadata endpoint 169.254.169.254 of cloud providers, shared, unspecified and multicast addresses.
+func Blocked(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the range of carrier-grade NAT, RFC 6598.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Subscribed reports whether w is notified of events of the given kind.
+func (w *Webhook) Subscribed(kind string) bool {
+	for _, k := range w.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewSecret returns a random secret to sign deliveries.
+func NewSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+type State string
+
+const (
+	Pending State = "pending"
+	Dead    State = "dead"
+)
+
+// Delivery is the notification of an event to a webhook.
+type Delivery struct {
+	ID        model.ID        `json:"id"`
+	WebhookID model.ID        `json:"webhook_id"`
+	TenantID  model.ID        `json:"tenant_id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	State     State           `json:"state"`
+
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastStatus    int       `json:"last_status,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists webhooks and the queue of their deliveries.
+type Store interface {
+	GetWebhook(ctx context.Context, id model.ID, tenantID model.ID) (*Webhook, error)
+	ListWebhooks(ctx context.Context, tenantID model.ID) ([]*Webhook, error)
+	PutWebhook(ctx context.Context, w *Webhook) error
+	DeleteWebhook(ctx context.Context, id model.ID, tenantID model.ID) error
+
+	// Schedule queues d for an attempt at d.NextAttemptAt, out of the deliveries in flight.
+	Schedule(ctx context.Context, d *Delivery) error
+
+	// Claim moves up to limit deliveries due at now from the queue to the deliveries in flight, and
+	// returns them. A delivery is claimed by a single caller, which must complete, schedule or
+	// bury it within visibility: deliveries in flight for longer, such as those of a crashed node,
+	// are queued again.
+	Claim(ctx context.Context, now time.Time, limit int, visibility time.Duration) ([]*Delivery, error)
+
+	// Complete forgets a delivered d.
+	Complete(ctx context.Context, d *Delivery) error
+
+	// Bury moves d to the dead letters of its webhook.
+	Bury(ctx context.Context, d *Delivery) error
+
+	// DeadLetters returns the dead letters of a webhook, most recent first.
+	DeadLetters(ctx context.Context, webhookID model.ID, tenantID model.ID, p *model.Pagination) (*model.Page[*Delivery], error)
+
+	// Unbury removes a delivery from the dead letters of its webhook and returns it.
+	Unbury(ctx context.Context, id model.ID, webhookID model.ID, tenantID model.ID) (*Delivery, error)
+}
+
+// Sign returns the signature header of a delivery body sent at t: "t=<unix time>,v1=<hex HMAC>",
+// where the HMAC-SHA256 covers "<unix time>.<body>".
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac(secret, ts, body)))
+}
+
+func mac(secret string, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify checks a signature header produced by Sign, rejecting signatures older than tolerance.
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var sig []byte
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig, _ = hex.DecodeString(kv[1])
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == nil {
+		return errors.E(errors.Invalid, "malformed signature")
+	}
+
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return errors.E(errors.Invalid, "signature expired")
+	}
+
+	if !hmac.Equal(sig, mac(secret, ts, body)) {
+		return errors.E(errors.Permission, "signature mismatch")
+	}
+
+	return nil
+}
+
+func notFound(what string, id model.ID) error {
+	return errors.E(errors.NotFound, fmt.Sprintf("%s %s not found", what, id))
+}
@@ -0,0 +1,433 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+// receiver is an endpoint recording the deliveries it accepts and failing while failing is set.
+type receiver struct {
+	mux      sync.Mutex
+	secret   string
+	failing  bool
+	received []string
+	errs     []error
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if err := Verify(r.secret, req.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+		r.errs = append(r.errs, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	r.received = append(r.received, req.Header.Get(KindHeader)+" "+string(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newDispatcher(t *testing.T, r http.Handler) (*Dispatcher, *clock, *Webhook) {
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	store := NewInMemory()
+	d := NewDispatcher(&Config{
+		Store:       store,
+		Client:      srv.Client(),
+		Logger:      newLogger(),
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+	})
+
+	c := &clock{t: time.Now()}
+	d.now = c.now
+
+	w := &Webhook{
+		ID:       "webhook",
+		TenantID: "tenant",
+		URL:      srv.URL,
+		Kinds:    []string{"EntityInserted"},
+		Secret:   secretOf(r),
+	}
+	require.NoError(t, store.PutWebhook(context.Background(), w))
+
+	return d, c, w
+}
+
+// secretOf returns the secret a handler verifies deliveries with, if any.
+func secretOf(h http.Handler) string {
+	if r, ok := h.(*receiver); ok {
+		return r.secret
+	}
+
+	return NewSecret()
+}
+
+// resolver resolves the hosts it maps to their address.
+type resolver map[string]string
+
+func (r resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addr, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return []net.IPAddr{{IP: net.ParseIP(addr)}}, nil
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	header := Sign("secret", time.Now(), body)
+
+	assert.NoError(t, Verify("secret", header, body, time.Minute))
+	assert.True(t, errors.Is(errors.Permission, Verify("other", header, body, time.Minute)))
+	assert.True(t, errors.Is(errors.Permission, Verify("secret", header, []byte(`{}`), time.Minute)))
+	assert.True(t, errors.Is(errors.Invalid, Verify("secret", "v1=00", body, time.Minute)))
+
+	old := Sign("secret", time.Now().Add(-time.Hour), body)
+	assert.True(t, errors.Is(errors.Invalid, Verify("secret", old, body, time.Minute)))
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	ctx := context.Background()
+	kinds := []string{"EntityInserted", "AssociationDeleted"}
+
+	defer func(r interface {
+		LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	}) {
+		Resolver = r
+	}(Resolver)
+	Resolver = resolver{
+		"example.com":     "93.184.216.34",
+		"internal.local":  "10.0.0.7",
+		"127.0.0.1":       "127.0.0.1",
+		"169.254.169.254": "169.254.169.254",
+	}
+
+	w := &Webhook{URL: "https://example.com/hook", Kinds: []string{"AssociationDeleted"}}
+	assert.NoError(t, w.Validate(ctx, kinds))
+
+	w = &Webhook{URL: "ftp://example.com", Kinds: []string{"AssociationDeleted"}}
+	assert.True(t, errors.Is(errors.Invalid, w.Validate(ctx, kinds)))
+
+	w = &Webhook{URL: "https://example.com/hook", Kinds: []string{"Unknown"}}
+	assert.True(t, errors.Is(errors.Invalid, w.Validate(ctx, kinds)))
+
+	w = &Webhook{URL: "https://unknown.example/hook", Kinds: []string{"AssociationDeleted"}}
+	assert.True(t, errors.Is(errors.Invalid, w.Validate(ctx, kinds)))
+
+	for _, url := range []string{"http://internal.local/hook", "http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data"} {
+		w = &Webhook{URL: url, Kinds: []string{"AssociationDeleted"}}
+		err := w.Validate(ctx, kinds)
+		assert.True(t, errors.Is(errors.Invalid, err), url)
+		assert.Equal(t, errors.Code("private_address"), errors.CodeOf(err), url)
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.blocked, Blocked(net.ParseIP(test.ip)), test.ip)
+	}
+}
+
+func TestNewClient_Blocked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	ctx := context.Background()
+	r := &receiver{secret: NewSecret()}
+	d, _, _ := newDispatcher(t, r)
+
+	require.NoError(t, d.Notify(ctx, "tenant", "EntityInserted", []byte(`{"id":"1"}`)))
+	require.NoError(t, d.Notify(ctx, "tenant", "EntityDeleted", []byte(`{"id":"2"}`)))
+	require.NoError(t, d.Notify(ctx, "other", "EntityInserted", []byte(`{"id":"3"}`)))
+
+	n, err := d.ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, r.errs)
+	assert.Equal(t, []string{`EntityInserted {"id":"1"}`}, r.received)
+
+	n, err = d.ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestDispatcher_RetryAndDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	r := &receiver{secret: NewSecret(), failing: true}
+	d, c, w := newDispatcher(t, r)
+
+	require.NoError(t, d.Notify(ctx, "tenant", "EntityInserted", []byte(`{"id":"1"}`)))
+
+	n, err := d.ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// The second attempt is due a second after the first, the third two seconds later.
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 0, n)
+
+	c.t = c.t.Add(time.Second)
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 1, n)
+
+	c.t = c.t.Add(time.Second)
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 0, n)
+
+	c.t = c.t.Add(time.Second)
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 1, n)
+
+	page, err := d.Store().DeadLetters(ctx, w.ID, w.TenantID, model.NewPagination(10, nil))
+	require.NoError(t, err)
+	dead := page.Items
+	require.Len(t, dead, 1)
+	assert.Equal(t, Dead, dead[0].State)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead[0].LastStatus)
+	assert.Empty(t, r.received)
+
+	c.t = c.t.Add(time.Hour)
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 0, n)
+
+	r.mux.Lock()
+	r.failing = false
+	r.mux.Unlock()
+
+	replayed, err := d.Replay(ctx, dead[0].ID, w.ID, w.TenantID)
+	require.NoError(t, err)
+	assert.Equal(t, Pending, replayed.State)
+
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{`EntityInserted {"id":"1"}`}, r.received)
+
+	page, err = d.Store().DeadLetters(ctx, w.ID, w.TenantID, model.NewPagination(10, nil))
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	_, err = d.Replay(ctx, replayed.ID, w.ID, w.TenantID)
+	assert.True(t, errors.Is(errors.NotFound, err))
+}
+
+func TestDispatcher_DeletedWebhook(t *testing.T) {
+	ctx := context.Background()
+	r := &receiver{secret: NewSecret()}
+	d, _, w := newDispatcher(t, r)
+
+	require.NoError(t, d.Notify(ctx, "tenant", "EntityInserted", []byte(`{"id":"1"}`)))
+	require.NoError(t, d.Store().DeleteWebhook(ctx, w.ID, w.TenantID))
+
+	n, err := d.ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, r.received)
+
+	n, _ = d.ProcessDue(ctx)
+	assert.Equal(t, 0, n)
+}
+
+func TestDispatcher_Concurrency(t *testing.T) {
+	ctx := context.Background()
+
+	var mux sync.Mutex
+	var running, peak, received int
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mux.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mux.Unlock()
+
+		<-release
+
+		mux.Lock()
+		running--
+		received++
+		mux.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	d, _, _ := newDispatcher(t, handler)
+	d.concurrency = 2
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, d.Notify(ctx, "tenant", "EntityInserted", []byte(`{}`)))
+	}
+
+	done := make(chan int)
+	go func() {
+		n, _ := d.ProcessDue(ctx)
+		done <- n
+	}()
+
+	// Deliveries are released one at a time once the endpoint is saturated.
+	for i := 0; i < 6; i++ {
+		want := 2
+		if 6-i < want {
+			want = 6 - i
+		}
+
+		assert.Eventually(t, func() bool {
+			mux.Lock()
+			defer mux.Unlock()
+			return running == want
+		}, time.Second, time.Millisecond)
+		release <- struct{}{}
+	}
+
+	assert.Equal(t, 6, <-done)
+	assert.Equal(t, 2, peak)
+	assert.Equal(t, 6, received)
+}
+
+func TestInMemory_Visibility(t *testing.T) {
+	testStoreVisibility(t, NewInMemory())
+}
+
+func TestRedis_Visibility(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testStoreVisibility(t, NewRedis(client, "test"))
+}
+
+// testStoreVisibility checks that claimed deliveries are queued again when they are neither
+// completed, scheduled nor buried in time.
+func testStoreVisibility(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, id := range []model.ID{"a", "b"} {
+		require.NoError(t, store.Schedule(ctx, &Delivery{ID: id, WebhookID: "webhook", TenantID: "tenant", NextAttemptAt: now}))
+	}
+
+	claimed, err := store.Claim(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	// Claimed deliveries are invisible to other claims until their visibility ends.
+	claimed, err = store.Claim(ctx, now.Add(30*time.Second), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	require.NoError(t, store.Complete(ctx, &Delivery{ID: "a", WebhookID: "webhook", TenantID: "tenant"}))
+
+	// The delivery left in flight, as by a crashed node, is claimed again.
+	claimed, err = store.Claim(ctx, now.Add(time.Minute), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, model.ID("b"), claimed[0].ID)
+
+	retried := claimed[0]
+	retried.NextAttemptAt = now.Add(time.Hour)
+	require.NoError(t, store.Schedule(ctx, retried))
+
+	// Scheduled deliveries wait for their next attempt.
+	claimed, err = store.Claim(ctx, now.Add(2*time.Minute), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = store.Claim(ctx, now.Add(time.Hour), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	require.NoError(t, store.Bury(ctx, claimed[0]))
+	claimed, err = store.Claim(ctx, now.Add(3*time.Hour), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+}
+
+func TestInMemory_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemory()
+
+	now := time.Now()
+	for i, id := range []model.ID{"a", "b", "c"} {
+		d := &Delivery{ID: id, WebhookID: "webhook", TenantID: "tenant", CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, store.Bury(ctx, d))
+	}
+
+	page, err := store.DeadLetters(ctx, "webhook", "tenant", model.NewPagination(2, nil))
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, model.ID("c"), page.Items[0].ID)
+	assert.Equal(t, model.ID("b"), page.Items[1].ID)
+	assert.True(t, page.HasMore)
+
+	page, err = store.DeadLetters(ctx, "webhook", "tenant", model.NewPagination(2, page.Next))
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, model.ID("a"), page.Items[0].ID)
+	assert.False(t, page.HasMore)
+}
@@ -1,5 +1,13 @@
 package worker
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrPending is returned by Await when ctx is done after the job is queued: the job still runs.
+var ErrPending = errors.New("job is still pending")
+
 type Job interface {
 	Name() string
 	Do() error
@@ -24,3 +32,32 @@ func (j job) Name() string {
 func (j job) Do() error {
 	return j.do()
 }
+
+// Await enqueues a job running do and blocks until the job is processed, returning its error, or until
+// ctx is done. When ctx is done before the job is queued, such as while the queue is full, the job
+// never runs and ctx.Err() is returned; once it is queued, ErrPending is.
+func Await(ctx context.Context, queue chan<- Job, name string, do func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	job := NewJob(name, func() error {
+		err := do()
+		done <- err
+		return err
+	})
+
+	select {
+	case queue <- job:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ErrPending
+	}
+}
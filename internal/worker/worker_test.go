@@ -1,8 +1,11 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -39,3 +42,42 @@ func TestNewDispatcher(t *testing.T) {
 		assert.NotNil(t, ds.jobQueue)
 	}
 }
+
+func TestAwait(t *testing.T) {
+	queue := make(chan Job)
+	d := NewDispatcher(queue, 1, newLogger())
+	d.Run()
+
+	err := Await(context.Background(), queue, "ok", func() error { return nil })
+	assert.NoError(t, err)
+
+	failure := errors.New("failure")
+	err = Await(context.Background(), queue, "failure", func() error { return failure })
+	assert.Equal(t, failure, err)
+}
+
+func TestAwait_Canceled(t *testing.T) {
+	queue := make(chan Job, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Await(ctx, queue, "never", func() error { return nil })
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestAwait_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Nothing reads the queue: the job is never queued.
+	err := Await(ctx, make(chan Job), "full", func() error { return nil })
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// The job is queued but no worker runs it before the timeout.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = Await(ctx, make(chan Job, 1), "queued", func() error { return nil })
+	assert.Equal(t, ErrPending, err)
+}
@@ -0,0 +1,127 @@
+package master
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey is the form of CreateAPIKeyHandler.
+type CreateAPIKey struct {
+	// Name describes the holder of the key.
+	Name string `json:"name" binding:"required"`
+
+	// Roles granted to the holder of the key, the roles of the caller when empty. The caller must
+	// hold them.
+	Roles []string `json:"roles"`
+}
+
+func (s *service) ListAPIKeysHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ListAPIKeysHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if keys, err := s.auth.Keys().List(ctx, model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		// Tenants have few keys, listed on a single page.
+		ctx.JSON(http.StatusOK, model.NewPage(keys, nil))
+	}
+}
+
+func (s *service) GetAPIKeyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetAPIKeyHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if k, err := s.auth.Keys().Get(ctx, model.ID(ctx.Param("id")), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, k)
+	}
+}
+
+// CreateAPIKeyHandler issues an API key. The response is the only one including its token.
+// Requests trusted with their tenant header, without principal, issue admin keys by default.
+func (s *service) CreateAPIKeyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.CreateAPIKeyHandler"
+
+	var form CreateAPIKey
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+
+	roles := form.Roles
+	if len(roles) == 0 {
+		roles = []string{auth.RoleAdmin}
+		if p := auth.FromContext(ctx.Request.Context()); p != nil {
+			roles = p.Roles
+		}
+	}
+
+	if err := s.policies.AuthorizeGrant(ctx.Request.Context(), model.ID(tenant), roles, nil); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	if k, err := s.auth.Keys().Create(ctx, model.ID(tenant), form.Name, roles); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		location := path.Join(Prefix, "api-keys", string(k.ID))
+		ctx.Header("Location", location)
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusCreated, k)
+	}
+}
+
+// RotateAPIKeyHandler replaces the secret of an API key and returns its new token. The caller
+// must hold the roles of the key, whose token it obtains.
+func (s *service) RotateAPIKeyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.RotateAPIKeyHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	k, err := s.auth.Keys().Get(ctx, model.ID(ctx.Param("id")), model.ID(tenant))
+	if err == nil {
+		err = s.policies.AuthorizeGrant(ctx.Request.Context(), model.ID(tenant), k.GrantedRoles(), nil)
+	}
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	if k, err := s.auth.Keys().Rotate(ctx, model.ID(ctx.Param("id")), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, k)
+	}
+}
+
+// RevokeAPIKeyHandler disables an API key for good.
+func (s *service) RevokeAPIKeyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.RevokeAPIKeyHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if _, err := s.auth.Keys().Revoke(ctx, model.ID(ctx.Param("id")), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
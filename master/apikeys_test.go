@@ -0,0 +1,69 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysAndPolicies_Escalation(t *testing.T) {
+	ctx := context.Background()
+	authn := newTestAuthenticator(t)
+
+	s := newTestService()
+	s.auth = authn
+	s.policies = auth.NewPolicies(auth.NewInMemory(), time.Minute)
+	_, err := s.policies.Put(ctx, &auth.Policy{TenantID: "acme", Role: "keys", Scopes: []string{"tenant:write:api-keys", "tenant:write:policies"}})
+	require.NoError(t, err)
+
+	admin, err := authn.Keys().Create(ctx, "acme", "admin", []string{auth.RoleAdmin})
+	require.NoError(t, err)
+	manager, err := authn.Keys().Create(ctx, "acme", "manager", []string{"keys"})
+	require.NoError(t, err)
+
+	engine := newTenantEngine(authn, false)
+	engine.POST("/api-keys", s.Authorize(auth.ResourceTenant, auth.VerbWrite, "api-keys"), s.CreateAPIKeyHandler)
+	engine.POST("/api-keys/:id/rotate", s.Authorize(auth.ResourceTenant, auth.VerbWrite, "api-keys"), s.RotateAPIKeyHandler)
+	engine.PUT("/policies/:role", s.Authorize(auth.ResourceTenant, auth.VerbWrite, "policies"), s.PutPolicyHandler)
+
+	bearer := func(k *auth.Key) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + k.Token, "Content-Type": "application/json"}
+	}
+
+	// Keys with roles the caller does not hold, admin included, are rejected.
+	w := post(engine, "/api-keys", `{"name": "ci", "roles": ["admin"]}`, bearer(manager))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = post(engine, "/api-keys", `{"name": "ci", "roles": ["reader"]}`, bearer(manager))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Keys default to the roles of the caller.
+	w = post(engine, "/api-keys", `{"name": "ci"}`, bearer(manager))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var k auth.Key
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &k))
+	assert.Equal(t, []string{"keys"}, k.Roles)
+
+	w = post(engine, "/api-keys", `{"name": "ci", "roles": ["reader"]}`, bearer(admin))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Rotating a key returns its token, so its roles must be held too.
+	w = post(engine, "/api-keys/"+string(admin.ID)+"/rotate", "", bearer(manager))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = post(engine, "/api-keys/"+string(k.ID)+"/rotate", "", bearer(manager))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Policies granting the caller scopes it does not hold are rejected.
+	w = put(engine, "/policies/escalate", `{"scopes": ["*:*:*"], "subjects": ["`+string(manager.ID)+`"]}`, bearer(manager))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = put(engine, "/policies/escalate", `{"scopes": ["*:*:*"], "subjects": ["`+string(manager.ID)+`"]}`, bearer(admin))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,315 @@
+package master
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/server"
+	"github.com/gin-gonic/gin"
+)
+
+// NDJSONContentType is the media type of imports and exports, a JSON Row per line.
+const NDJSONContentType = "application/x-ndjson"
+
+var (
+	// ImportBatchSize is the number of rows imported concurrently. Within a batch, entities are
+	// imported before associations, which may depend on them.
+	ImportBatchSize = 256
+
+	// ImportConcurrency is the number of rows of a batch imported at once.
+	ImportConcurrency = 16
+
+	// MaxImportRowSize bounds the length of a line of an import.
+	MaxImportRowSize = 4 << 20
+
+	// MaxImportErrors bounds the errors returned by an import. Failures past it are counted only.
+	MaxImportErrors = 1000
+
+	// ExportFlushInterval is how often exports are flushed to the client.
+	ExportFlushInterval = time.Second
+)
+
+// Row is a line of imports and exports, either an entity or an association. Rows of history
+// exports have the events of the resource, oldest first, and are imported from them.
+type Row struct {
+	Entity      *entity.Entity           `json:"entity,omitempty"`
+	Association *association.Association `json:"association,omitempty"`
+	Events      []json.RawMessage        `json:"events,omitempty"`
+
+	// Error ends exports failing midway, whose status is sent already.
+	Error *server.Problem `json:"error,omitempty"`
+}
+
+// ImportError is a row that failed to import.
+type ImportError struct {
+	// Line is the line of the row in the import, from 1.
+	Line int `json:"line"`
+
+	// Row is the row, or a string of the line when it is not valid JSON.
+	Row   json.RawMessage `json:"row"`
+	Error *server.Problem `json:"error"`
+}
+
+// ImportResult is the outcome of an import.
+type ImportResult struct {
+	Processed int `json:"processed"`
+	Imported  int `json:"imported"`
+	Failed    int `json:"failed"`
+
+	// Errors are the first MaxImportErrors failed rows, with Truncated set when there are more.
+	Errors    []*ImportError `json:"errors"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// importRow is a row of an import with its line.
+type importRow struct {
+	line int
+	raw  json.RawMessage
+	row  *Row
+	err  error
+}
+
+// ImportHandler imports the NDJSON rows of the request body, by batches imported concurrently.
+// Rows failing to import do not stop the import, they are returned with the reason they failed.
+func (s *service) ImportHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ImportHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+
+	// Imports outlive the read and write timeouts of the server.
+	rc := http.NewResponseController(ctx.Writer)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		s.logger.Warn(errors.E(op, err))
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warn(errors.E(op, err))
+	}
+
+	res := &ImportResult{Errors: []*ImportError{}}
+	fail := func(r *importRow, err error) {
+		res.Failed++
+		if len(res.Errors) == MaxImportErrors {
+			res.Truncated = true
+			return
+		}
+
+		res.Errors = append(res.Errors, &ImportError{Line: r.line, Row: r.raw, Error: NewProblem(ctx, err)})
+	}
+
+	scanner := bufio.NewScanner(ctx.Request.Body)
+	scanner.Buffer(make([]byte, 64<<10), MaxImportRowSize)
+
+	start := time.Now()
+	batch := make([]*importRow, 0, ImportBatchSize)
+	flush := func() {
+		s.importBatch(ctx, tenant, batch)
+		for _, r := range batch {
+			res.Processed++
+			if r.err != nil {
+				fail(r, r.err)
+			} else {
+				res.Imported++
+			}
+		}
+
+		batch = batch[:0]
+		s.logger.Infof("%s: tenant=%s, processed=%d, failed=%d, rows/s=%.0f", op, tenant, res.Processed, res.Failed, float64(res.Processed)/time.Since(start).Seconds())
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+
+		r := &importRow{line: line, raw: append(json.RawMessage{}, raw...), row: &Row{}}
+		if err := json.Unmarshal(raw, r.row); err != nil {
+			r.raw, _ = json.Marshal(string(raw))
+			r.err = errors.E(op, errors.Invalid, err)
+		}
+
+		if batch = append(batch, r); len(batch) == ImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, fmt.Sprintf("import stopped after %d rows: %v", res.Processed, err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// importBatch imports the rows of batch, setting the error of the rows that failed.
+func (s *service) importBatch(ctx context.Context, tenantID model.ID, batch []*importRow) {
+	var entities, assocs []*importRow
+	for _, r := range batch {
+		switch {
+		case r.err != nil:
+			continue
+		case r.row.Entity != nil && r.row.Association == nil:
+			entities = append(entities, r)
+		case r.row.Association != nil && r.row.Entity == nil:
+			assocs = append(assocs, r)
+		default:
+			r.err = errors.E(errors.Invalid, "a row must have either an entity or an association")
+		}
+	}
+
+	for _, rows := range [][]*importRow{entities, assocs} {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, ImportConcurrency)
+		for _, r := range rows {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(r *importRow) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				r.err = s.importRow(ctx, tenantID, r.row)
+			}(r)
+		}
+		wg.Wait()
+	}
+}
+
+// importRow imports r in the tenant, whatever the tenant it was exported from: the events of
+// histories are rewritten as events of the tenant.
+func (s *service) importRow(ctx context.Context, tenantID model.ID, r *Row) error {
+	const op errors.Op = "api/service.importRow"
+
+	var history eventstore.History
+	for _, event := range r.Events {
+		history = append(history, &eventstore.Record{Data: event})
+	}
+
+	var err error
+	if history != nil {
+		if history, err = eventstore.Retenant(history, tenantID); err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	switch {
+	case r.Entity != nil && history != nil:
+		_, err = s.entity.ImportEntityHistory(ctx, r.Entity.ID, tenantID, history)
+	case r.Association != nil && history != nil:
+		_, err = s.association.ImportAssociationHistory(ctx, r.Association.ID, tenantID, history)
+	case r.Entity != nil && r.Entity.DeletedAt != nil, r.Association != nil && r.Association.DeletedAt != nil:
+		err = errors.E(errors.Invalid, "deleted resources are only imported with their events")
+	case r.Entity != nil:
+		_, err = s.entity.ImportEntity(ctx, &entity.InsertEntity{
+			CommandModel: model.CommandModel{ID: r.Entity.ID, TenantID: tenantID},
+			Data:         r.Entity.Data,
+			Type:         r.Entity.Type,
+		})
+	case r.Association != nil:
+		_, err = s.association.ImportAssociation(ctx, &association.InsertAssociation{
+			CommandModel: model.CommandModel{ID: r.Association.ID, TenantID: tenantID},
+			Data:         r.Association.Data,
+			In:           r.Association.In,
+			Out:          r.Association.Out,
+			Type:         r.Association.Type,
+		})
+	}
+
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// ExportHandler streams the entities and then the associations of the tenant as NDJSON rows.
+// Deleted resources are skipped unless ?history is set, which adds the events of every resource.
+// ?resources restricts the export to entities or associations.
+func (s *service) ExportHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ExportHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+	history, _ := strconv.ParseBool(ctx.DefaultQuery("history", "false"))
+
+	resources := map[string]bool{"entities": true, "associations": true}
+	if r := ctx.Query("resources"); r != "" {
+		resources = map[string]bool{}
+		for _, resource := range strings.Split(r, ",") {
+			if resource != "entities" && resource != "associations" {
+				s.AbortWithError(ctx, errors.E(op, errors.Invalid, fmt.Sprintf("invalid resource %q, expected entities or associations", resource)))
+				return
+			}
+
+			resources[resource] = true
+		}
+	}
+
+	// Exports outlive the write timeout of the server.
+	if err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warn(errors.E(op, err))
+	}
+
+	ctx.Header("Content-Type", NDJSONContentType)
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	enc := json.NewEncoder(ctx.Writer)
+	flushed := time.Now()
+	write := func(row *Row, h eventstore.History, deleted bool) error {
+		if deleted && !history {
+			return nil
+		}
+
+		if history {
+			for _, record := range h {
+				row.Events = append(row.Events, record.Data)
+			}
+		}
+
+		if err := enc.Encode(row); err != nil {
+			return errors.E(errors.IO, err)
+		}
+
+		if time.Since(flushed) >= ExportFlushInterval {
+			ctx.Writer.Flush()
+			flushed = time.Now()
+		}
+
+		return nil
+	}
+
+	var err error
+	if resources["entities"] {
+		err = s.entity.ExportEntities(ctx, tenant, func(e *entity.Entity, h eventstore.History) error {
+			return write(&Row{Entity: e}, h, e.DeletedAt != nil)
+		})
+	}
+
+	if err == nil && resources["associations"] {
+		err = s.association.ExportAssociations(ctx, tenant, func(a *association.Association, h eventstore.History) error {
+			return write(&Row{Association: a}, h, a.DeletedAt != nil)
+		})
+	}
+
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		enc.Encode(&Row{Error: NewProblem(ctx, err)})
+	}
+
+	ctx.Writer.Flush()
+}
@@ -0,0 +1,84 @@
+package master
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHandler_InvalidRows(t *testing.T) {
+	s := newTestService()
+
+	engine := gin.New()
+	engine.POST("/import", s.ImportHandler)
+	engine.GET("/export", s.ExportHandler)
+
+	w := post(engine, "/import", "{\"entity\": \n\n{}\n{\"entity\": {\"id\": \"1\"}, \"association\": {\"id\": \"2\"}}\n", map[string]string{"Content-Type": NDJSONContentType})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res ImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 3, res.Processed)
+	assert.Equal(t, 0, res.Imported)
+	assert.Equal(t, 3, res.Failed)
+	require.Len(t, res.Errors, 3)
+	assert.Equal(t, []int{1, 3, 4}, []int{res.Errors[0].Line, res.Errors[1].Line, res.Errors[2].Line})
+	assert.Equal(t, "invalid", res.Errors[1].Error.Kind)
+	assert.JSONEq(t, `"{\"entity\": "`, string(res.Errors[0].Row))
+	assert.JSONEq(t, `{}`, string(res.Errors[1].Row))
+
+	w = get(engine, "/export?resources=entities,webhooks", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportHandler_OtherTenant(t *testing.T) {
+	ctx := context.Background()
+	s := newTestGraphService(t)
+
+	_, err := s.entity.ImportEntity(ctx, &entity.InsertEntity{
+		CommandModel: model.CommandModel{ID: "alice", TenantID: "acme"},
+		Type:         "user",
+	})
+	require.NoError(t, err)
+
+	var export bytes.Buffer
+	err = s.entity.ExportEntities(ctx, "acme", func(e *entity.Entity, h eventstore.History) error {
+		row := &Row{Entity: e}
+		for _, record := range h {
+			row.Events = append(row.Events, record.Data)
+		}
+
+		return json.NewEncoder(&export).Encode(row)
+	})
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(func(ctx *gin.Context) { ctx.Set(TenantKey, "globex") })
+	engine.POST("/import", s.ImportHandler)
+
+	w := post(engine, "/import", export.String(), map[string]string{"Content-Type": NDJSONContentType})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res ImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 1, res.Imported, w.Body.String())
+
+	imported, err := s.entity.GetEntity(ctx, "alice", "globex")
+	require.NoError(t, err)
+	assert.Equal(t, model.ID("globex"), imported.TenantID)
+	assert.Equal(t, "user", imported.Type)
+
+	history, err := s.entity.GetEntityHistory(ctx, "alice", "globex")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, model.ID("globex"), history[0].EventTenantID())
+}
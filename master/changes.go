@@ -0,0 +1,240 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/feed"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/webhook"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// KeepAliveInterval is the interval between keep-alive messages of idle change streams.
+var KeepAliveInterval = 15 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// Origins are not restricted, as with CORSHandler.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// publisher sends a change to the change feed and the webhooks of its tenant.
+type publisher func(c feed.Change)
+
+func newPublisher(hub *feed.Hub, webhooks *webhook.Dispatcher, logger logrus.FieldLogger) publisher {
+	return func(c feed.Change) {
+		const op errors.Op = "api/publisher"
+
+		c = hub.Publish(c)
+
+		payload, err := json.Marshal(c)
+		if err != nil {
+			logger.Error(errors.E(op, err))
+			return
+		}
+
+		if err := webhooks.Notify(context.Background(), c.TenantID, c.Kind, payload); err != nil {
+			logger.Error(errors.E(op, err))
+		}
+	}
+}
+
+// newEntityObserver publishes entity events.
+func newEntityObserver(publish publisher) eventstore.Observer {
+	return func(event model.Event) {
+		var otype string
+		switch e := event.(type) {
+		case *entity.EntityInserted:
+			otype = e.Type
+		case *entity.EntityUpdated:
+			otype = e.Type
+		case *entity.EntityDeleted:
+			otype = e.Type
+		}
+
+		publish(feed.NewChange(feed.ResourceEntity, otype, event))
+	}
+}
+
+// newAssociationObserver publishes association events.
+func newAssociationObserver(publish publisher) eventstore.Observer {
+	return func(event model.Event) {
+		var atype string
+		switch e := event.(type) {
+		case *association.AssociationInserted:
+			atype = e.Type
+		case *association.AssociationUpdated:
+			atype = e.Type
+		case *association.AssociationDeleted:
+			atype = e.Type
+		}
+
+		publish(feed.NewChange(feed.ResourceAssociation, atype, event))
+	}
+}
+
+// NewFeedFilter reads the resource, otype, atype and id query parameters of a change stream.
+func NewFeedFilter(ctx *gin.Context) feed.Filter {
+	f := feed.Filter{
+		Resources: ctx.QueryArray("resource"),
+		Types:     append(ctx.QueryArray("otype"), ctx.QueryArray("atype")...),
+	}
+
+	for _, id := range ctx.QueryArray("id") {
+		f.IDs = append(f.IDs, model.ID(id))
+	}
+
+	return f
+}
+
+// LastEventID returns the position a change stream resumes from, set by the Last-Event-ID header
+// of reconnecting EventSource clients or the last_event_id query parameter.
+func LastEventID(ctx *gin.Context) string {
+	if id := ctx.GetHeader("Last-Event-ID"); id != "" {
+		return id
+	}
+
+	return ctx.Query("last_event_id")
+}
+
+// readable reports whether the caller of ctx may read the changed resource. Streams check it as
+// they send changes, as the hub matches them under its lock and policies may be read from Redis.
+func (s *service) readable(ctx context.Context, c *feed.Change) bool {
+	resource := auth.ResourceEntities
+	if c.Resource == feed.ResourceAssociation {
+		resource = auth.ResourceAssociations
+	}
+
+	return s.policies.Authorize(ctx, c.TenantID, resource, auth.VerbRead, c.Type) == nil
+}
+
+func (s *service) subscribe(ctx *gin.Context, op errors.Op) (*feed.Subscription, bool) {
+	tenant := ctx.GetString(TenantKey)
+
+	sub, err := s.changes.Subscribe(model.ID(tenant), NewFeedFilter(ctx), LastEventID(ctx))
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return nil, false
+	}
+
+	return sub, true
+}
+
+// ChangesHandler streams the changes of the tenant as Server-Sent Events.
+func (s *service) ChangesHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ChangesHandler"
+
+	sub, ok := s.subscribe(ctx, op)
+	if !ok {
+		return
+	}
+	defer s.changes.Unsubscribe(sub)
+
+	// Streams outlive the write timeout of the server.
+	if err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warn(errors.E(op, err))
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ticker := time.NewTicker(KeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case c, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind, the client resumes from its last event.
+				return
+			}
+
+			if !s.readable(ctx.Request.Context(), &c) {
+				continue
+			}
+
+			ctx.Render(-1, sse.Event{Id: c.Position, Data: c})
+			ctx.Writer.Flush()
+		case <-ticker.C:
+			if _, err := ctx.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-ctx.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// ChangesWebSocketHandler streams the changes of the tenant as JSON WebSocket messages.
+func (s *service) ChangesWebSocketHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ChangesWebSocketHandler"
+
+	sub, ok := s.subscribe(ctx, op)
+	if !ok {
+		return
+	}
+	defer s.changes.Unsubscribe(sub)
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		return
+	}
+	defer conn.Close()
+
+	// Messages from the client are discarded, reading detects closed connections.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(KeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case c, ok := <-sub.C:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow, resume from the last position")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+				return
+			}
+
+			if !s.readable(ctx.Request.Context(), &c) {
+				continue
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(KeepAliveInterval))
+			if err := conn.WriteJSON(c); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
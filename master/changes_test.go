@@ -0,0 +1,61 @@
+package master
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangesHandler_Readable(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+	s.changes = feed.NewHub(16, 16, s.logger)
+	s.policies = auth.NewPolicies(auth.NewInMemory(), time.Minute)
+
+	_, err := s.policies.Put(ctx, &auth.Policy{TenantID: "acme", Role: "billing", Scopes: []string{"entities:read:invoice"}})
+	require.NoError(t, err)
+
+	authn := newTestAuthenticator(t)
+	key, err := authn.Keys().Create(ctx, "acme", "billing", []string{"billing"})
+	require.NoError(t, err)
+
+	engine := newTenantEngine(authn, false)
+	engine.GET("/changes", s.ChangesHandler)
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/changes", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+key.Token)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Changes the principal may not read are left out of its stream.
+	s.changes.Publish(feed.Change{Resource: feed.ResourceEntity, Kind: "EntityInserted", ID: "alice", TenantID: "acme", Type: "user"})
+	s.changes.Publish(feed.Change{Resource: feed.ResourceEntity, Kind: "EntityInserted", ID: "inv1", TenantID: "acme", Type: "invoice"})
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if data := strings.TrimPrefix(scanner.Text(), "data:"); data != scanner.Text() {
+			assert.Contains(t, data, `"id":"inv1"`)
+			return
+		}
+	}
+
+	t.Fatal("no change streamed")
+}
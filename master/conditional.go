@@ -0,0 +1,63 @@
+package master
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns the weak entity tag of a revision. Variants of a representation, such as the data
+// only view of an entity, are told apart by a suffix.
+func ETag(rev *model.Revision, variant string) string {
+	if variant != "" {
+		return fmt.Sprintf(`W/"%d-%s"`, rev.Version, variant)
+	}
+
+	return fmt.Sprintf(`W/"%d"`, rev.Version)
+}
+
+// Conditional reports whether the request has a precondition to evaluate.
+func Conditional(ctx *gin.Context) bool {
+	return ctx.GetHeader("If-None-Match") != "" || ctx.GetHeader("If-Modified-Since") != ""
+}
+
+// SetRevisionHeaders sets the ETag and Last-Modified headers of a revision.
+func SetRevisionHeaders(ctx *gin.Context, rev *model.Revision, variant string) {
+	ctx.Header("ETag", ETag(rev, variant))
+	if rev.UpdatedAt != nil {
+		ctx.Header("Last-Modified", rev.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified sets the revision headers and reports whether the client copy is still fresh,
+// following the If-None-Match and If-Modified-Since precedence of RFC 7232.
+func NotModified(ctx *gin.Context, rev *model.Revision, variant string) bool {
+	SetRevisionHeaders(ctx, rev, variant)
+
+	if match := ctx.GetHeader("If-None-Match"); match != "" {
+		etag := strings.TrimPrefix(ETag(rev, variant), "W/")
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if since := ctx.GetHeader("If-Modified-Since"); since != "" && rev.UpdatedAt != nil {
+		t, err := http.ParseTime(since)
+		if err != nil {
+			return false
+		}
+
+		return !rev.UpdatedAt.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
@@ -1,6 +1,9 @@
 package master
 
 import (
+	"time"
+
+	"github.com/edgestore/edgestore/internal/limit"
 	"github.com/edgestore/edgestore/internal/server"
 	"github.com/go-pg/pg/v10"
 	"github.com/redis/go-redis/v9"
@@ -11,4 +14,47 @@ type Config struct {
 	Database  *pg.Options
 	Cache     *redis.Options
 	MachineID uint16
+
+	// OperationRetention is how long asynchronous operations can be looked up.
+	// Defaults to operation.DefaultRetention.
+	OperationRetention time.Duration
+
+	// IdempotencyRetention is how long idempotency keys and their responses are kept.
+	// Defaults to idempotency.DefaultRetention.
+	IdempotencyRetention time.Duration
+
+	// CursorSecret signs pagination cursors and permission consistency tokens. Nodes behind the
+	// same load balancer need the same secret; a random one is generated when empty, with a warning,
+	// invalidating cursors and tokens on restart.
+	CursorSecret string
+
+	Auth   AuthConfig
+	Limits LimitsConfig
+}
+
+// AuthConfig configures the authentication and authorization of requests, see auth.Config.
+type AuthConfig struct {
+	// JWKS are paths of JSON Web Key Set files. JWTs are rejected when empty.
+	JWKS        []string
+	Issuer      string
+	Audience    string
+	TenantClaim string
+	RolesClaim  string
+
+	// PolicyTTL bounds how long the policies of a tenant are cached. Defaults to
+	// auth.DefaultPolicyTTL.
+	PolicyTTL time.Duration
+
+	// InsecureTenantHeader trusts the Edgestore-Tenant header of requests without credentials.
+	// It is meant for local development only.
+	InsecureTenantHeader bool
+}
+
+// LimitsConfig configures the rate limits and storage quotas of tenants.
+type LimitsConfig struct {
+	// Table holds the limits of tenants, unlimited when nil. It is loaded once, at startup.
+	Table *limit.Table
+
+	// Shared keeps buckets and usage in Redis, shared by every master, instead of in memory.
+	Shared bool
 }
@@ -2,34 +2,44 @@ package master
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/edgestore/edgestore/internal/errors"
 	"github.com/edgestore/edgestore/internal/server"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
 )
 
-func ER(err error) *server.ErrorResponse {
-	code := http.StatusInternalServerError
-	msg := err.Error()
-
-	e, ok := err.(*errors.Error)
-	if ok {
-		slices := strings.Split(msg, ": ")
-		msg = slices[len(slices)-1]
-		switch e.Kind {
-		case errors.Duplicate:
-			code = http.StatusBadRequest
-		case errors.Invalid:
-			code = http.StatusBadRequest
-		case errors.NotFound:
-			code = http.StatusNotFound
-		case errors.Permission:
-			code = http.StatusUnauthorized
+// NewProblem describes err as a response to the request of ctx. Permission errors are
+// "unauthenticated" 401 for anonymous requests and "forbidden" 403 for authenticated ones.
+func NewProblem(ctx *gin.Context, err error) *server.Problem {
+	p := server.NewProblem(ctx, err)
+	if errors.KindOf(err) != errors.Permission {
+		return p
+	}
+
+	p.Status = PermissionStatus(ctx, err)
+	p.Title = http.StatusText(p.Status)
+	if p.Code == errors.Permission.Name() {
+		p.Code = "unauthenticated"
+		if p.Status == http.StatusForbidden {
+			p.Code = "forbidden"
 		}
 	}
 
-	return &server.ErrorResponse{
-		Code:    code,
-		Message: msg,
+	return p
+}
+
+// AbortWithError stops the chain of ctx and responds with the problem describing err.
+func AbortWithError(ctx *gin.Context, err error) {
+	p := NewProblem(ctx, err)
+	if p.Status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", "Bearer")
 	}
+
+	server.AbortWithProblem(ctx, p)
+}
+
+// GRPCError converts err to a gRPC status error, mapping its kind as HTTP responses do.
+func GRPCError(err error) error {
+	return status.Error(errors.KindOf(err).GRPCCode(), errors.Message(err))
 }
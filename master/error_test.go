@@ -0,0 +1,79 @@
+package master
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/server"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func problem(t *testing.T, engine http.Handler, path string, body string) (int, *server.Problem) {
+	w := post(engine, path, body, map[string]string{TenantHeader: "acme", "X-Request-Id": "req-1"})
+	assert.Equal(t, server.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var p server.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return w.Code, &p
+}
+
+func TestAbortWithError(t *testing.T) {
+	s := newTestService()
+
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.NoRoute(server.NotFoundHandler)
+	engine.POST("/entities", server.RequestIDHandler(), func(ctx *gin.Context) {
+		var form PutPolicy
+		if err := ctx.ShouldBind(&form); err != nil {
+			s.AbortWithError(ctx, errors.E(errors.Op("api/test"), errors.Invalid, err))
+		}
+	})
+	engine.POST("/transient", server.RequestIDHandler(), func(ctx *gin.Context) {
+		s.AbortWithError(ctx, errors.E(errors.Op("api/test"), errors.E(errors.Transient, "the store is unavailable")))
+	})
+
+	code, p := problem(t, engine, "/entities", `{}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "invalid", p.Kind)
+	assert.Equal(t, "validation_failed", p.Code)
+	assert.Equal(t, "req-1", p.RequestID)
+	assert.Equal(t, "/entities", p.Instance)
+	assert.Equal(t, []server.FieldError{{Field: "scopes", Message: "is required"}}, p.Errors)
+
+	code, p = problem(t, engine, "/entities", `{"scopes": "entities:*:read"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "scopes", p.Errors[0].Field)
+
+	code, p = problem(t, engine, "/transient", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "transient", p.Code)
+	// The causes of server errors are logged, not disclosed.
+	assert.Equal(t, "transient error", p.Detail)
+
+	// Routes without handler are problems too.
+	code, p = problem(t, engine, "/missing", `{}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "route_not_found", p.Code)
+}
+
+func TestGRPCError(t *testing.T) {
+	err := GRPCError(errors.E(errors.Op("api/test"), errors.Exhausted, errors.Code("rate_limited"), "slow down"))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "slow down", st.Message())
+
+	assert.Equal(t, codes.Unavailable, status.Code(GRPCError(errors.E(errors.Transient))))
+
+	st, _ = status.FromError(GRPCError(errors.E(errors.Op("api/test"), errors.Internal, "pq: relation \"records\" does not exist")))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+}
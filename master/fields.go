@@ -0,0 +1,91 @@
+package master
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Fields returns the sparse fieldset of the fields query parameter, nil when there is none. Its
+// paths must start with an attribute of resources like v, and only the data attribute has
+// nested paths.
+func Fields(ctx *gin.Context, v interface{}) (model.Fields, error) {
+	const op errors.Op = "api/Fields"
+
+	f, err := model.ParseFields(ctx.Query("fields"))
+	if err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+
+	attributes := jsonAttributes(reflect.TypeOf(v))
+	for _, path := range f {
+		if !attributes[path[0]] {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("unknown field %q", path[0]))
+		}
+
+		if len(path) > 1 && path[0] != "data" {
+			return nil, errors.E(op, errors.Invalid, fmt.Sprintf("field %q has no nested fields", path[0]))
+		}
+	}
+
+	return f, nil
+}
+
+// jsonAttributes returns the names of the attributes of the JSON encoding of the struct t.
+func jsonAttributes(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	attributes := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.SplitN(t.Field(i).Tag.Get("json"), ",", 2)[0]
+		if name != "" && name != "-" {
+			attributes[name] = true
+		}
+	}
+
+	return attributes
+}
+
+// fieldsVariant names the representation of a sparse fieldset in entity tags. Paths are hashed
+// as their commas would split If-None-Match lists.
+func fieldsVariant(f model.Fields) string {
+	h := fnv.New32a()
+	h.Write([]byte(f.String()))
+	return fmt.Sprintf("fields-%08x", h.Sum32())
+}
+
+// projectItems projects items with f, null items staying null.
+func projectItems[T any](f model.Fields, items []T) ([]map[string]interface{}, error) {
+	projected := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		p, err := f.Project(item)
+		if err != nil {
+			return nil, err
+		}
+
+		projected[i] = p
+	}
+
+	return projected, nil
+}
+
+// projectJSON responds with the attributes of v selected by f.
+func (s *service) projectJSON(ctx *gin.Context, f model.Fields, v interface{}) {
+	const op errors.Op = "api/service.projectJSON"
+
+	p, err := f.Project(v)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Internal, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, p)
+}
@@ -0,0 +1,43 @@
+package master
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/entities", func(ctx *gin.Context) {
+		fields, err := Fields(ctx, entity.Entity{})
+		if err != nil {
+			AbortWithError(ctx, err)
+			return
+		}
+
+		items := []*entity.Entity{{ID: "1", Type: "user", Data: model.Data{"name": "Ada", "bio": "..."}}, nil}
+		res, err := projectMultiGet(fields, newMultiGetResult([]model.ID{"1", "2"}, items, nil))
+		if err != nil {
+			AbortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, res)
+	})
+
+	w := get(engine, "/entities?fields=id,data.name", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items": [{"id": "1", "data": {"name": "Ada"}}, null], "not_found": ["2"], "forbidden": []}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get(engine, "/entities?fields=password", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, get(engine, "/entities?fields=otype.name", nil).Code)
+
+	f, err := model.ParseFields("id,data.name")
+	require.NoError(t, err)
+	assert.NotEqual(t, fieldsVariant(f), fieldsVariant(model.Fields{{"id"}}))
+}
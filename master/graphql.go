@@ -0,0 +1,807 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// MaxGraphQLDepth bounds the nesting of the fields of a GraphQL request.
+const MaxGraphQLDepth = 12
+
+// MaxGraphQLFields bounds the fields selected by a GraphQL request, fragments expanded.
+const MaxGraphQLFields = 500
+
+// GraphQLTimeout bounds the execution of a GraphQL request.
+var GraphQLTimeout = 10 * time.Second
+
+// GraphQLRequest is the body of a GraphQL request.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLError exposes the status, kind and code of an error as extensions of a GraphQL error,
+// as they are in problem responses.
+type graphQLError struct {
+	status int
+	kind   string
+	code   string
+	msg    string
+
+	// err is logged when it is a server error, as msg only describes its kind.
+	err error
+}
+
+func (e *graphQLError) Error() string {
+	return e.msg
+}
+
+func (e *graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"status": e.status, "kind": e.kind, "code": e.code}
+}
+
+func newGraphQLError(err error) error {
+	kind := errors.KindOf(err)
+	return &graphQLError{status: kind.HTTPStatus(), kind: kind.Name(), code: string(errors.CodeOf(err)), msg: errors.Message(err), err: err}
+}
+
+// unwrapGraphQLError returns the graphQLError err was built from, nil when there is none.
+func unwrapGraphQLError(err error) *graphQLError {
+	for err != nil {
+		switch e := err.(type) {
+		case *graphQLError:
+			return e
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// completeGraphQLErrors sets the extensions of the errors of res the executor dropped, as it does
+// for the errors of thunks, and logs the causes of server errors.
+func (s *service) completeGraphQLErrors(op errors.Op, res *graphql.Result) {
+	for i, e := range res.Errors {
+		gerr := unwrapGraphQLError(e.OriginalError())
+		if gerr == nil {
+			continue
+		}
+
+		if e.Extensions == nil {
+			res.Errors[i].Extensions = gerr.Extensions()
+		}
+
+		if gerr.status >= http.StatusInternalServerError {
+			s.logger.Error(errors.E(op, gerr.err))
+		}
+	}
+}
+
+type graphQLContextKey struct{}
+
+// graphQLContext holds the state of a single GraphQL request.
+type graphQLContext struct {
+	tenant   model.ID
+	entities *entityLoader
+}
+
+func fromGraphQLContext(ctx context.Context) *graphQLContext {
+	return ctx.Value(graphQLContextKey{}).(*graphQLContext)
+}
+
+// entitiesGetter is implemented by entity.Service.
+type entitiesGetter interface {
+	GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*entity.Entity, error)
+}
+
+// entityResult is the outcome of the lookup of an entity, shared by the thunks loading it.
+type entityResult struct {
+	entity *entity.Entity
+	err    error
+}
+
+// entityLoader batches the entity lookups of a GraphQL request. Resolvers queue IDs with Load and
+// the first returned thunk evaluated by the executor fetches every queued entity at once. Entities
+// the caller may not read resolve to null.
+type entityLoader struct {
+	ctx    context.Context
+	svc    entitiesGetter
+	tenant model.ID
+
+	mu      sync.Mutex
+	pending []model.ID
+	loaded  map[model.ID]*entityResult
+}
+
+func newEntityLoader(ctx context.Context, svc entitiesGetter, tenant model.ID) *entityLoader {
+	return &entityLoader{
+		ctx:    ctx,
+		svc:    svc,
+		tenant: tenant,
+		loaded: make(map[model.ID]*entityResult),
+	}
+}
+
+// Load returns a thunk resolving to the entity with the given ID, or nil when it does not exist.
+// When the lookup of its batch fails, every thunk of the batch returns the error.
+func (l *entityLoader) Load(id model.ID) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[id]; !ok {
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		r := l.loaded[id]
+		switch {
+		case r.err != nil:
+			return nil, newGraphQLError(r.err)
+		case r.entity != nil:
+			return r.entity, nil
+		}
+
+		return nil, nil
+	}
+}
+
+// dispatch looks up the pending IDs and records the result of each.
+func (l *entityLoader) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var ids []model.ID
+	seen := make(map[model.ID]bool)
+	for _, id := range l.pending {
+		if _, ok := l.loaded[id]; !ok && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	l.pending = nil
+
+	if len(ids) == 0 {
+		return
+	}
+
+	entities, err := l.svc.GetReadableEntities(l.ctx, ids, l.tenant)
+	for i, id := range ids {
+		if err != nil {
+			l.loaded[id] = &entityResult{err: err}
+			continue
+		}
+
+		l.loaded[id] = &entityResult{entity: entities[i]}
+	}
+}
+
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value, used for entity and association data.",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: parseJSONLiteral,
+})
+
+func parseJSONLiteral(value ast.Value) interface{} {
+	switch v := value.(type) {
+	case *ast.ObjectValue:
+		m := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			m[f.Name.Value] = parseJSONLiteral(f.Value)
+		}
+		return m
+	case *ast.ListValue:
+		l := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			l = append(l, parseJSONLiteral(item))
+		}
+		return l
+	case *ast.IntValue:
+		return graphql.Int.ParseLiteral(v)
+	case *ast.FloatValue:
+		return graphql.Float.ParseLiteral(v)
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.StringValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	}
+
+	return nil
+}
+
+var directionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Direction",
+	Values: graphql.EnumValueConfigMap{
+		"IN":  &graphql.EnumValueConfig{Value: graph.DirectionIn},
+		"OUT": &graphql.EnumValueConfig{Value: graph.DirectionOut},
+	},
+})
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	v, _ := p.Args[name].(string)
+	return v
+}
+
+func intArg(p graphql.ResolveParams, name string) int {
+	v, _ := p.Args[name].(int)
+	return v
+}
+
+func dataArg(p graphql.ResolveParams) model.Data {
+	v, _ := p.Args["data"].(map[string]interface{})
+	return v
+}
+
+func stringsArg(p graphql.ResolveParams, name string) []string {
+	return toStrings(p.Args[name])
+}
+
+func toStrings(v interface{}) []string {
+	l, _ := v.([]interface{})
+	values := make([]string, 0, len(l))
+	for _, v := range l {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func (s *service) paginationArgs(p graphql.ResolveParams) (*model.Pagination, error) {
+	after, err := s.cursors.Decode(stringArg(p, "cursor"))
+	if err != nil {
+		return nil, newGraphQLError(errors.E(errors.Invalid, err))
+	}
+
+	return model.NewPagination(pageSize(intArg(p, "per_page")), after), nil
+}
+
+var paginationArgsConfig = graphql.FieldConfigArgument{
+	"per_page": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultPaginationLimit},
+	"cursor":   &graphql.ArgumentConfig{Type: graphql.String, Description: "next_cursor of the previous page."},
+}
+
+// newPageType returns the type of a page of items, see model.Page.
+func newPageType(name string, itemType graphql.Output) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"items":       &graphql.Field{Type: graphql.NewList(itemType)},
+			"next_cursor": &graphql.Field{Type: graphql.String},
+			"has_more":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+}
+
+func versionField(p graphql.ResolveParams) (interface{}, error) {
+	switch v := p.Source.(type) {
+	case *entity.Entity:
+		return int(v.Version), nil
+	case *association.Association:
+		return int(v.Version), nil
+	}
+
+	return nil, nil
+}
+
+// newGraphQLSchema builds the GraphQL schema exposing entities, associations and traversals.
+func (s *service) newGraphQLSchema() (graphql.Schema, error) {
+	entityType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Entity",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"tenant_id":  &graphql.Field{Type: graphql.ID},
+			"otype":      &graphql.Field{Type: graphql.String},
+			"data":       &graphql.Field{Type: jsonScalar},
+			"version":    &graphql.Field{Type: graphql.Int, Resolve: versionField},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+			"updated_at": &graphql.Field{Type: graphql.DateTime},
+			"deleted_at": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	associationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Association",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"tenant_id":  &graphql.Field{Type: graphql.ID},
+			"atype":      &graphql.Field{Type: graphql.String},
+			"in":         &graphql.Field{Type: graphql.ID},
+			"out":        &graphql.Field{Type: graphql.ID},
+			"data":       &graphql.Field{Type: jsonScalar},
+			"version":    &graphql.Field{Type: graphql.Int, Resolve: versionField},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+			"updated_at": &graphql.Field{Type: graphql.DateTime},
+			"deleted_at": &graphql.Field{Type: graphql.DateTime},
+			"source": &graphql.Field{
+				Type:        entityType,
+				Description: "Entity the association starts from.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromGraphQLContext(p.Context).entities.Load(p.Source.(*association.Association).In), nil
+				},
+			},
+			"target": &graphql.Field{
+				Type:        entityType,
+				Description: "Entity the association points to.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromGraphQLContext(p.Context).entities.Load(p.Source.(*association.Association).Out), nil
+				},
+			},
+		},
+	})
+
+	entityType.AddFieldConfig("associations", &graphql.Field{
+		Type:        newPageType("AssociationPage", associationType),
+		Description: "Associations of the entity, most recently updated first.",
+		Args: graphql.FieldConfigArgument{
+			"direction": &graphql.ArgumentConfig{Type: directionEnum, DefaultValue: graph.DirectionOut},
+			"atype":     &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: association.AnyType},
+			"per_page":  paginationArgsConfig["per_page"],
+			"cursor":    paginationArgsConfig["cursor"],
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			gctx := fromGraphQLContext(p.Context)
+			id := p.Source.(*entity.Entity).ID
+
+			pagination, err := s.paginationArgs(p)
+			if err != nil {
+				return nil, err
+			}
+
+			var page *model.Page[*association.Association]
+			if stringArg(p, "direction") == graph.DirectionIn {
+				page, err = s.association.GetIncomingAssociations(p.Context, id, stringArg(p, "atype"), gctx.tenant, pagination)
+			} else {
+				page, err = s.association.GetOutgoingAssociations(p.Context, id, stringArg(p, "atype"), gctx.tenant, pagination)
+			}
+
+			if err != nil {
+				return nil, newGraphQLError(err)
+			}
+
+			return page.Sign(s.cursors), nil
+		},
+	})
+
+	nodeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"entity": &graphql.Field{
+				Type: entityType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*graph.Node).Entity, nil
+				},
+			},
+			"depth": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	traversalType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Traversal",
+		Fields: graphql.Fields{
+			"entities":     &graphql.Field{Type: graphql.NewList(nodeType)},
+			"associations": &graphql.Field{Type: graphql.NewList(associationType)},
+			"truncated":    &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+
+	pathType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Path",
+		Fields: graphql.Fields{
+			"entities":     &graphql.Field{Type: graphql.NewList(entityType)},
+			"associations": &graphql.Field{Type: graphql.NewList(associationType)},
+		},
+	})
+
+	hopInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "HopInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"atypes":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"otypes":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"direction": &graphql.InputObjectFieldConfig{Type: directionEnum, DefaultValue: graph.DirectionOut},
+			"limit":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	writeResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "WriteResult",
+		Description: "Acknowledges a command enqueued for the entity or association id.",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.ID},
+			"operation_id": &graphql.Field{Type: graphql.ID, Description: "Operation tracking the command, see /operations/:id."},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"entity": &graphql.Field{
+				Type: entityType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromGraphQLContext(p.Context).entities.Load(model.ID(stringArg(p, "id"))), nil
+				},
+			},
+			"entities": &graphql.Field{
+				Type:        newPageType("EntityPage", entityType),
+				Description: "Entities of a given type, most recently updated first.",
+				Args: graphql.FieldConfigArgument{
+					"otype":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"per_page": paginationArgsConfig["per_page"],
+					"cursor":   paginationArgsConfig["cursor"],
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					gctx := fromGraphQLContext(p.Context)
+
+					pagination, err := s.paginationArgs(p)
+					if err != nil {
+						return nil, err
+					}
+
+					page, err := s.entity.GetEntitiesByType(p.Context, stringArg(p, "otype"), gctx.tenant, pagination)
+					if err != nil {
+						return nil, newGraphQLError(err)
+					}
+
+					return page.Sign(s.cursors), nil
+				},
+			},
+			"association": &graphql.Field{
+				Type: associationType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					gctx := fromGraphQLContext(p.Context)
+					assoc, err := s.association.GetAssociation(p.Context, model.ID(stringArg(p, "id")), gctx.tenant)
+					if errors.Is(errors.NotFound, err) {
+						return nil, nil
+					}
+
+					if err != nil {
+						return nil, newGraphQLError(err)
+					}
+
+					return assoc, nil
+				},
+			},
+			"traverse": &graphql.Field{
+				Type: traversalType,
+				Args: graphql.FieldConfigArgument{
+					"start":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"hops":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(hopInput)))},
+					"max_nodes": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					q := &graph.Traverse{
+						TenantID: fromGraphQLContext(p.Context).tenant,
+						Start:    model.ID(stringArg(p, "start")),
+						MaxNodes: intArg(p, "max_nodes"),
+					}
+
+					hops, _ := p.Args["hops"].([]interface{})
+					for _, h := range hops {
+						m, _ := h.(map[string]interface{})
+						hop := graph.Hop{}
+						hop.Direction, _ = m["direction"].(string)
+						hop.Limit, _ = m["limit"].(int)
+						hop.Types = toStrings(m["atypes"])
+						hop.EntityTypes = toStrings(m["otypes"])
+						q.Hops = append(q.Hops, hop)
+					}
+
+					res, err := s.graph.Traverse(p.Context, q)
+					if err != nil {
+						return nil, newGraphQLError(err)
+					}
+
+					return res, nil
+				},
+			},
+			"path": &graphql.Field{
+				Type:        pathType,
+				Description: "Shortest path of outgoing associations between two entities.",
+				Args: graphql.FieldConfigArgument{
+					"from":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"to":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"atypes":    &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+					"max_depth": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					res, err := s.graph.ShortestPath(p.Context, &graph.ShortestPath{
+						TenantID: fromGraphQLContext(p.Context).tenant,
+						From:     model.ID(stringArg(p, "from")),
+						To:       model.ID(stringArg(p, "to")),
+						Types:    stringsArg(p, "atypes"),
+						MaxDepth: intArg(p, "max_depth"),
+					})
+					if err != nil {
+						return nil, newGraphQLError(err)
+					}
+
+					return res, nil
+				},
+			},
+		},
+	})
+
+	write := func(id model.ID, o *operation.Operation, err error) (interface{}, error) {
+		if err != nil {
+			return nil, newGraphQLError(err)
+		}
+
+		res := map[string]interface{}{"id": string(id)}
+		if o != nil {
+			res["operation_id"] = string(o.ID)
+		}
+
+		return res, nil
+	}
+
+	idArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	updateArgs := graphql.FieldConfigArgument{
+		"id":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+		"data": &graphql.ArgumentConfig{Type: jsonScalar},
+	}
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createEntity": &graphql.Field{
+				Type: writeResultType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.ID},
+					"otype": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"data":  &graphql.ArgumentConfig{Type: jsonScalar},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &entity.InsertEntity{Data: dataArg(p), Type: stringArg(p, "otype")}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.entity.CreateEntity(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+			"updateEntity": &graphql.Field{
+				Type: writeResultType,
+				Args: updateArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &entity.UpdateEntity{Data: dataArg(p)}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.entity.UpdateEntity(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+			"deleteEntity": &graphql.Field{
+				Type: writeResultType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &entity.DeleteEntity{}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.entity.DeleteEntity(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+			"createAssociation": &graphql.Field{
+				Type: writeResultType,
+				Args: graphql.FieldConfigArgument{
+					"id":           &graphql.ArgumentConfig{Type: graphql.ID},
+					"atype":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"in":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"out":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"data":         &graphql.ArgumentConfig{Type: jsonScalar},
+					"on_duplicate": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &association.InsertAssociation{
+						Data:        dataArg(p),
+						In:          model.ID(stringArg(p, "in")),
+						Out:         model.ID(stringArg(p, "out")),
+						Type:        stringArg(p, "atype"),
+						OnDuplicate: stringArg(p, "on_duplicate"),
+					}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.association.CreateAssociation(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+			"updateAssociation": &graphql.Field{
+				Type: writeResultType,
+				Args: updateArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &association.UpdateAssociation{Data: dataArg(p)}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.association.UpdateAssociation(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+			"deleteAssociation": &graphql.Field{
+				Type: writeResultType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cmd := &association.DeleteAssociation{}
+					cmd.ID = model.ID(stringArg(p, "id"))
+					cmd.TenantID = fromGraphQLContext(p.Context).tenant
+					o, err := s.association.DeleteAssociation(p.Context, cmd)
+					return write(cmd.ID, o, err)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// GraphQLHandler serves GraphQL requests scoped to the tenant set by NewTenantMiddleware.
+func (s *service) GraphQLHandler() gin.HandlerFunc {
+	schema, err := s.newGraphQLSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	return func(ctx *gin.Context) {
+		const op errors.Op = "api/service.GraphQLHandler"
+
+		var form GraphQLRequest
+		if err := ctx.ShouldBind(&form); err != nil {
+			s.logger.Error(errors.E(op, err))
+			s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+			return
+		}
+
+		res := s.executeGraphQL(ctx.Request.Context(), op, &schema, &form, model.ID(ctx.GetString(TenantKey)))
+		s.completeGraphQLErrors(op, res)
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+// executeGraphQL executes a request as graphql.Do does, rejecting the documents over the GraphQL
+// limits before executing them within GraphQLTimeout.
+func (s *service) executeGraphQL(ctx context.Context, op errors.Op, schema *graphql.Schema, form *GraphQLRequest, tenant model.ID) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(form.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	if v := graphql.ValidateDocument(schema, doc, nil); !v.IsValid {
+		return &graphql.Result{Errors: v.Errors}
+	}
+
+	if err := checkGraphQLLimits(doc); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(newGraphQLError(errors.E(op, err)))}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, GraphQLTimeout)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, graphQLContextKey{}, &graphQLContext{
+		tenant:   tenant,
+		entities: newEntityLoader(ctx, s.entity, tenant),
+	})
+
+	res := graphql.Execute(graphql.ExecuteParams{
+		Schema:        *schema,
+		AST:           doc,
+		OperationName: form.OperationName,
+		Args:          form.Variables,
+		Context:       ctx,
+	})
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(newGraphQLError(errors.E(op, errors.Transient, errors.Code("timeout"), "the request timed out")))}
+	}
+
+	return res
+}
+
+// graphQLLimits counts the fields of a document, expanding its fragments, to check it against
+// MaxGraphQLDepth and MaxGraphQLFields.
+type graphQLLimits struct {
+	fragments map[string]*ast.FragmentDefinition
+	expanding map[string]bool
+	fields    int
+}
+
+// checkGraphQLLimits fails with an Invalid error when the operations of doc nest fields deeper
+// than MaxGraphQLDepth or select more than MaxGraphQLFields fields.
+func checkGraphQLLimits(doc *ast.Document) error {
+	l := &graphQLLimits{fragments: map[string]*ast.FragmentDefinition{}, expanding: map[string]bool{}}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			l.fragments[frag.Name.Value] = frag
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if err := l.selectionSet(op.SelectionSet, 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *graphQLLimits) selectionSet(set *ast.SelectionSet, depth int) error {
+	if set == nil {
+		return nil
+	}
+
+	for _, selection := range set.Selections {
+		var err error
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if l.fields++; l.fields > MaxGraphQLFields {
+				return errors.E(errors.Invalid, errors.Code("query_too_large"), fmt.Sprintf("requests are limited to %d fields", MaxGraphQLFields))
+			}
+
+			if sel.SelectionSet != nil && depth == MaxGraphQLDepth {
+				return errors.E(errors.Invalid, errors.Code("query_too_deep"), fmt.Sprintf("requests are limited to %d levels of fields", MaxGraphQLDepth))
+			}
+
+			err = l.selectionSet(sel.SelectionSet, depth+1)
+		case *ast.InlineFragment:
+			err = l.selectionSet(sel.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			// Fragment cycles are rejected by the validation of the document, and not followed.
+			name := sel.Name.Value
+			if frag := l.fragments[name]; frag != nil && !l.expanding[name] {
+				l.expanding[name] = true
+				err = l.selectionSet(frag.SelectionSet, depth)
+				delete(l.expanding, name)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
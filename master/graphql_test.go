@@ -0,0 +1,209 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEntities records the batches of IDs looked up, failing them with err when set.
+type countingEntities struct {
+	entitiesGetter
+	batches [][]model.ID
+	err     error
+}
+
+func (c *countingEntities) GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*entity.Entity, error) {
+	c.batches = append(c.batches, ids)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.entitiesGetter.GetReadableEntities(ctx, ids, tenantID)
+}
+
+// newTestGraph returns a test service with the entities alice, bob and carol of tenant acme,
+// alice following bob and carol.
+func newTestGraph(t *testing.T) *service {
+	ctx := context.Background()
+	s := newTestGraphService(t)
+
+	for _, id := range []model.ID{"alice", "bob", "carol"} {
+		_, err := s.entity.CreateEntityAndWait(ctx, &entity.InsertEntity{
+			CommandModel: model.CommandModel{ID: id, TenantID: "acme"},
+			Data:         model.Data{"name": string(id)},
+			Type:         "user",
+		})
+		require.NoError(t, err)
+	}
+
+	for _, out := range []model.ID{"bob", "carol"} {
+		_, err := s.association.CreateAssociationAndWait(ctx, &association.InsertAssociation{
+			CommandModel: model.CommandModel{TenantID: "acme"},
+			In:           "alice",
+			Out:          out,
+			Type:         "follows",
+		})
+		require.NoError(t, err)
+	}
+
+	return s
+}
+
+func doGraphQL(t *testing.T, s *service, entities entitiesGetter, query string) *graphql.Result {
+	schema, err := s.newGraphQLSchema()
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), graphQLContextKey{}, &graphQLContext{
+		tenant:   "acme",
+		entities: newEntityLoader(context.Background(), entities, "acme"),
+	})
+
+	res := graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: ctx})
+	s.completeGraphQLErrors("api/test", res)
+	return res
+}
+
+func jsonOf(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestEntityLoader_Batching(t *testing.T) {
+	s := newTestGraph(t)
+	entities := &countingEntities{entitiesGetter: s.entity}
+
+	res := doGraphQL(t, s, entities, `{
+		a: entity(id: "alice") { id otype }
+		b: entity(id: "bob") { id }
+		again: entity(id: "alice") { id }
+		ghost: entity(id: "ghost") { id }
+	}`)
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"a": {"id": "alice", "otype": "user"}, "b": {"id": "bob"}, "again": {"id": "alice"}, "ghost": null}`, jsonOf(t, res.Data))
+
+	// Entities are looked up at once, each once.
+	require.Len(t, entities.batches, 1)
+	assert.ElementsMatch(t, []model.ID{"alice", "bob", "ghost"}, entities.batches[0])
+
+	entities.batches = nil
+	res = doGraphQL(t, s, entities, `{
+		entity(id: "alice") {
+			associations(atype: "follows") { items { source { id } target { id data } } has_more }
+		}
+	}`)
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"entity": {"associations": {"has_more": false, "items": [
+		{"source": {"id": "alice"}, "target": {"id": "carol", "data": {"name": "carol"}}},
+		{"source": {"id": "alice"}, "target": {"id": "bob", "data": {"name": "bob"}}}
+	]}}}`, jsonOf(t, res.Data))
+
+	// The targets of a page are looked up together, the source being loaded already.
+	require.Len(t, entities.batches, 2)
+	assert.ElementsMatch(t, []model.ID{"bob", "carol"}, entities.batches[1])
+}
+
+func TestEntityLoader_Errors(t *testing.T) {
+	s := newTestGraph(t)
+
+	for _, tt := range []struct {
+		err     error
+		message string
+		status  int
+		kind    string
+	}{
+		{errors.E(errors.Permission, "no access to entities of type user"), "no access to entities of type user", http.StatusUnauthorized, "permission"},
+		{errors.E(errors.IO, "dial tcp 10.0.0.1:6379: connection refused"), "I/O error", http.StatusServiceUnavailable, "io"},
+	} {
+		// Every entity of the failed batch reports the error.
+		res := doGraphQL(t, s, &countingEntities{err: tt.err}, `{ a: entity(id: "alice") { id } b: entity(id: "bob") { id } }`)
+		require.Len(t, res.Errors, 2)
+		for _, e := range res.Errors {
+			assert.Equal(t, tt.message, e.Message)
+			assert.Equal(t, map[string]interface{}{"status": tt.status, "kind": tt.kind, "code": tt.kind}, e.Extensions)
+		}
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	s := newTestGraph(t)
+
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.POST("/graphql", s.GraphQLHandler())
+
+	query := func(q string) (map[string]interface{}, []map[string]interface{}) {
+		w := post(engine, "/graphql", jsonOf(t, GraphQLRequest{Query: q}), map[string]string{TenantHeader: "acme", "Content-Type": "application/json"})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Data   map[string]interface{}   `json:"data"`
+			Errors []map[string]interface{} `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Data, res.Errors
+	}
+
+	data, errs := query(`{ entities(otype: "user", per_page: 2) { items { id } has_more next_cursor } }`)
+	require.Empty(t, errs)
+	page := data["entities"].(map[string]interface{})
+	assert.Len(t, page["items"], 2)
+	assert.Equal(t, true, page["has_more"])
+	assert.NotEmpty(t, page["next_cursor"])
+
+	data, errs = query(`{ association(id: "missing") { id } }`)
+	require.Empty(t, errs)
+	assert.Nil(t, data["association"])
+
+	for _, q := range []string{
+		`{ entities(otype: "") { items { id } } }`,
+		`{ entities(otype: "user", cursor: "forged") { items { id } } }`,
+		`{ entity(id: "alice") { associations(cursor: "forged") { items { id } } } }`,
+	} {
+		_, errs = query(q)
+		require.Len(t, errs, 1, q)
+		assert.Equal(t, map[string]interface{}{"status": float64(http.StatusBadRequest), "kind": "invalid", "code": "invalid"}, errs[0]["extensions"], q)
+	}
+
+	data, errs = query(`mutation { createEntity(id: "dave", otype: "user", data: {name: "dave"}) { id } }`)
+	require.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"id": "dave"}, data["createEntity"])
+
+	_, errs = query(`{ unknown }`)
+	assert.NotEmpty(t, errs)
+
+	w := post(engine, "/graphql", `{}`, map[string]string{TenantHeader: "acme", "Content-Type": "application/json"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Documents are bounded in depth and fields, and executed within GraphQLTimeout.
+	deep := `{ entity(id: "alice") { associations { items { target { associations { items { target { associations { items { target { associations { items { id } } } } } } } } } } } } }`
+	large := `{ entity(id: "alice") { ...f } } fragment f on Entity { ` + strings.Repeat("id ", MaxGraphQLFields) + `}`
+	for q, code := range map[string]string{deep: "query_too_deep", large: "query_too_large"} {
+		_, errs = query(q)
+		require.Len(t, errs, 1)
+		assert.Equal(t, map[string]interface{}{"status": float64(http.StatusBadRequest), "kind": "invalid", "code": code}, errs[0]["extensions"])
+	}
+
+	timeout := GraphQLTimeout
+	GraphQLTimeout = time.Nanosecond
+	_, errs = query(`{ entity(id: "alice") { id } }`)
+	GraphQLTimeout = timeout
+	require.Len(t, errs, 1)
+	assert.Equal(t, map[string]interface{}{"status": float64(http.StatusServiceUnavailable), "kind": "transient", "code": "timeout"}, errs[0]["extensions"])
+
+	// Requests are scoped to their tenant.
+	w = post(engine, "/graphql", jsonOf(t, GraphQLRequest{Query: `{ entity(id: "alice") { id } }`}), map[string]string{TenantHeader: "other", "Content-Type": "application/json"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"entity": null}}`, w.Body.String())
+}
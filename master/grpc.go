@@ -0,0 +1,391 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/edgestorepb"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TenantMetadataKey is the gRPC counterpart of the Edgestore-Tenant header.
+const TenantMetadataKey = "edgestore-tenant"
+
+type tenantKey struct{}
+
+func tenantFromContext(ctx context.Context) model.ID {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return model.ID(tenant)
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// withTenant is the gRPC counterpart of NewTenantMiddleware, reading the authorization and
+// edgestore-tenant metadata.
+func withTenant(ctx context.Context, authn *auth.Authenticator, allowHeader bool) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tenant := firstMetadata(md, TenantMetadataKey)
+	credential := Credential(firstMetadata(md, "authorization"))
+
+	if credential == "" && allowHeader && tenant != "" {
+		return context.WithValue(ctx, tenantKey{}, tenant), nil
+	}
+
+	p, err := authn.Authenticate(ctx, credential)
+	if err != nil {
+		return nil, GRPCError(err)
+	}
+
+	if tenant != "" && model.ID(tenant) != p.TenantID {
+		return nil, status.Error(codes.PermissionDenied, "The credential does not grant access to the requested tenant.")
+	}
+
+	ctx = auth.NewContext(ctx, p)
+	return context.WithValue(ctx, tenantKey{}, string(p.TenantID)), nil
+}
+
+// NewTenantUnaryInterceptor is the gRPC counterpart of NewTenantMiddleware.
+func NewTenantUnaryInterceptor(authn *auth.Authenticator, allowHeader bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := withTenant(ctx, authn, allowHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewTenantStreamInterceptor is the streaming counterpart of NewTenantUnaryInterceptor.
+func NewTenantStreamInterceptor(authn *auth.Authenticator, allowHeader bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withTenant(stream.Context(), authn, allowHeader)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &tenantStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcService implements edgestorepb.EdgestoreServer on top of the master services.
+type grpcService struct {
+	edgestorepb.UnimplementedEdgestoreServer
+	*service
+}
+
+func (s *grpcService) abort(op errors.Op, err error) error {
+	s.logger.Error(errors.E(op, err))
+	return GRPCError(err)
+}
+
+func (s *grpcService) GetEntity(ctx context.Context, req *edgestorepb.GetEntityRequest) (*edgestorepb.Entity, error) {
+	const op errors.Op = "api/grpcService.GetEntity"
+
+	ent, err := s.entity.GetEntity(ctx, model.ID(req.GetId()), tenantFromContext(ctx))
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	res, err := newEntityMessage(ent)
+	if err != nil {
+		return nil, s.abort(op, errors.E(op, errors.Internal, err))
+	}
+
+	return res, nil
+}
+
+func (s *grpcService) CreateEntity(ctx context.Context, req *edgestorepb.CreateEntityRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.CreateEntity"
+
+	if req.GetOtype() == "" {
+		return nil, s.abort(op, errors.E(op, errors.Invalid, "otype is required"))
+	}
+
+	cmd := &entity.InsertEntity{
+		Data: req.GetData().AsMap(),
+		Type: req.GetOtype(),
+	}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.entity.CreateEntity(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) UpdateEntity(ctx context.Context, req *edgestorepb.UpdateEntityRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.UpdateEntity"
+
+	cmd := &entity.UpdateEntity{Data: req.GetData().AsMap()}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.entity.UpdateEntity(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) DeleteEntity(ctx context.Context, req *edgestorepb.DeleteEntityRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.DeleteEntity"
+
+	cmd := &entity.DeleteEntity{}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.entity.DeleteEntity(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) StreamEntityHistory(req *edgestorepb.HistoryRequest, stream edgestorepb.Edgestore_StreamEntityHistoryServer) error {
+	const op errors.Op = "api/grpcService.StreamEntityHistory"
+
+	ctx := stream.Context()
+	events, err := s.entity.GetEntityHistory(ctx, model.ID(req.GetId()), tenantFromContext(ctx))
+	if err != nil {
+		return s.abort(op, err)
+	}
+
+	return s.sendEvents(op, events, stream.Send)
+}
+
+func (s *grpcService) GetAssociation(ctx context.Context, req *edgestorepb.GetAssociationRequest) (*edgestorepb.Association, error) {
+	const op errors.Op = "api/grpcService.GetAssociation"
+
+	assoc, err := s.association.GetAssociation(ctx, model.ID(req.GetId()), tenantFromContext(ctx))
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	res, err := newAssociationMessage(assoc)
+	if err != nil {
+		return nil, s.abort(op, errors.E(op, errors.Internal, err))
+	}
+
+	return res, nil
+}
+
+func (s *grpcService) CreateAssociation(ctx context.Context, req *edgestorepb.CreateAssociationRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.CreateAssociation"
+
+	if req.GetAtype() == "" || req.GetIn() == "" || req.GetOut() == "" {
+		return nil, s.abort(op, errors.E(op, errors.Invalid, "atype, in and out are required"))
+	}
+
+	cmd := &association.InsertAssociation{
+		Data:        req.GetData().AsMap(),
+		In:          model.ID(req.GetIn()),
+		Out:         model.ID(req.GetOut()),
+		Type:        req.GetAtype(),
+		OnDuplicate: req.GetOnDuplicate(),
+	}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.association.CreateAssociation(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) UpdateAssociation(ctx context.Context, req *edgestorepb.UpdateAssociationRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.UpdateAssociation"
+
+	cmd := &association.UpdateAssociation{Data: req.GetData().AsMap()}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.association.UpdateAssociation(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) DeleteAssociation(ctx context.Context, req *edgestorepb.DeleteAssociationRequest) (*edgestorepb.WriteResponse, error) {
+	const op errors.Op = "api/grpcService.DeleteAssociation"
+
+	cmd := &association.DeleteAssociation{}
+	cmd.ID = model.ID(req.GetId())
+	cmd.TenantID = tenantFromContext(ctx)
+
+	o, err := s.association.DeleteAssociation(ctx, cmd)
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return newWriteResponse(cmd.ID, o), nil
+}
+
+func (s *grpcService) StreamAssociationHistory(req *edgestorepb.HistoryRequest, stream edgestorepb.Edgestore_StreamAssociationHistoryServer) error {
+	const op errors.Op = "api/grpcService.StreamAssociationHistory"
+
+	ctx := stream.Context()
+	events, err := s.association.GetAssociationHistory(ctx, model.ID(req.GetId()), tenantFromContext(ctx))
+	if err != nil {
+		return s.abort(op, err)
+	}
+
+	return s.sendEvents(op, events, stream.Send)
+}
+
+func (s *grpcService) CreateGUID(ctx context.Context, _ *emptypb.Empty) (*edgestorepb.GUID, error) {
+	const op errors.Op = "api/grpcService.CreateGUID"
+
+	id, err := s.guid.NextID()
+	if err != nil {
+		return nil, s.abort(op, err)
+	}
+
+	return &edgestorepb.GUID{
+		Id:        id,
+		Machine:   uint32(s.cfg.MachineID),
+		CreatedAt: timestamppb.Now(),
+	}, nil
+}
+
+func (s *grpcService) sendEvents(op errors.Op, events []model.Event, send func(*edgestorepb.Event) error) error {
+	for _, event := range events {
+		msg, err := newEventMessage(event)
+		if err != nil {
+			return s.abort(op, errors.E(op, errors.Internal, err))
+		}
+
+		if err := send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newWriteResponse(id model.ID, o *operation.Operation) *edgestorepb.WriteResponse {
+	res := &edgestorepb.WriteResponse{Id: string(id)}
+	if o != nil {
+		res.OperationId = string(o.ID)
+	}
+
+	return res
+}
+
+func newEntityMessage(ent *entity.Entity) (*edgestorepb.Entity, error) {
+	data, err := newStruct(ent.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgestorepb.Entity{
+		Id:        string(ent.ID),
+		TenantId:  string(ent.TenantID),
+		Otype:     ent.Type,
+		Data:      data,
+		Version:   int64(ent.Version),
+		CreatedAt: newTimestamp(ent.CreatedAt),
+		UpdatedAt: newTimestamp(ent.UpdatedAt),
+		DeletedAt: newTimestamp(ent.DeletedAt),
+	}, nil
+}
+
+func newAssociationMessage(assoc *association.Association) (*edgestorepb.Association, error) {
+	data, err := newStruct(assoc.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgestorepb.Association{
+		Id:        string(assoc.ID),
+		TenantId:  string(assoc.TenantID),
+		Atype:     assoc.Type,
+		In:        string(assoc.In),
+		Out:       string(assoc.Out),
+		Data:      data,
+		Version:   int64(assoc.Version),
+		CreatedAt: newTimestamp(assoc.CreatedAt),
+		UpdatedAt: newTimestamp(assoc.UpdatedAt),
+		DeletedAt: newTimestamp(assoc.DeletedAt),
+	}, nil
+}
+
+func newEventMessage(event model.Event) (*edgestorepb.Event, error) {
+	payload, err := newStruct(event)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, _ := model.EventType(event)
+	return &edgestorepb.Event{
+		Kind:    kind,
+		Id:      string(event.EventID()),
+		Version: int64(event.EventVersion()),
+		At:      newTimestamp(event.EventAt()),
+		Payload: payload,
+	}, nil
+}
+
+// newStruct converts v to a structpb.Struct through its JSON representation.
+func newStruct(v interface{}) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	if m == nil {
+		return nil, nil
+	}
+
+	return structpb.NewStruct(m)
+}
+
+func newTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
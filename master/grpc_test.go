@@ -0,0 +1,224 @@
+package master
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/edgestorepb"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestGraphService returns a test service with entity and association services backed by an
+// in-memory event store and Redis.
+func newTestGraphService(t *testing.T) *service {
+	t.Helper()
+
+	s := newTestService()
+
+	cache := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { cache.Close() })
+
+	store := eventstore.NewInMemory(s.logger)
+	s.entity = entity.New(&entity.Config{Cache: cache, Logger: s.logger, Store: store})
+	s.association = association.New(&association.Config{Cache: cache, Entities: s.entity, Logger: s.logger, Store: store})
+	s.cursors = model.NewCursorSigner([]byte("secret"))
+	return s
+}
+
+func TestGRPCError_Codes(t *testing.T) {
+	for _, tt := range []struct {
+		kind errors.Kind
+		code codes.Code
+	}{
+		{errors.Other, codes.Unknown},
+		{errors.Invalid, codes.InvalidArgument},
+		{errors.Permission, codes.Unauthenticated},
+		{errors.IO, codes.Unavailable},
+		{errors.Duplicate, codes.AlreadyExists},
+		{errors.NotFound, codes.NotFound},
+		{errors.Private, codes.PermissionDenied},
+		{errors.Internal, codes.Internal},
+		{errors.Transient, codes.Unavailable},
+		{errors.Conflict, codes.FailedPrecondition},
+		{errors.Exhausted, codes.ResourceExhausted},
+	} {
+		err := GRPCError(errors.E(errors.Op("api/test"), errors.E(tt.kind, "failed")))
+		assert.Equal(t, tt.code, status.Code(err), tt.kind.Name())
+	}
+}
+
+func TestTenantUnaryInterceptor(t *testing.T) {
+	authn := newTestAuthenticator(t)
+	key, err := authn.Keys().Create(context.Background(), "acme", "test", []string{auth.RoleAdmin})
+	require.NoError(t, err)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return tenantFromContext(ctx), nil
+	}
+
+	for _, tt := range []struct {
+		name        string
+		allowHeader bool
+		md          metadata.MD
+		code        codes.Code
+		tenant      model.ID
+	}{
+		{"no credential", false, metadata.MD{}, codes.Unauthenticated, ""},
+		{"untrusted tenant", false, metadata.Pairs(TenantMetadataKey, "acme"), codes.Unauthenticated, ""},
+		{"trusted tenant", true, metadata.Pairs(TenantMetadataKey, "acme"), codes.OK, "acme"},
+		{"no tenant", true, metadata.MD{}, codes.Unauthenticated, ""},
+		{"unknown key", false, metadata.Pairs("authorization", "Bearer esk_unknown_secret"), codes.Unauthenticated, ""},
+		{"key", false, metadata.Pairs("authorization", "Bearer "+key.Token), codes.OK, "acme"},
+		{"key of the tenant", false, metadata.Pairs("authorization", "Bearer "+key.Token, TenantMetadataKey, "acme"), codes.OK, "acme"},
+		{"key of another tenant", true, metadata.Pairs("authorization", "Bearer "+key.Token, TenantMetadataKey, "other"), codes.PermissionDenied, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := NewTenantUnaryInterceptor(authn, tt.allowHeader)
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.code == codes.OK {
+				assert.Equal(t, tt.tenant, res)
+			}
+		})
+	}
+}
+
+// testStream is a server stream of the context it holds, collecting the events sent.
+type testStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*edgestorepb.Event
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testStream) Send(event *edgestorepb.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestTenantStreamInterceptor(t *testing.T) {
+	interceptor := NewTenantStreamInterceptor(newTestAuthenticator(t), true)
+
+	var tenant model.ID
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		tenant = tenantFromContext(stream.Context())
+		return nil
+	}
+
+	stream := &testStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(TenantMetadataKey, "acme"))}
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{}, handler))
+	assert.Equal(t, model.ID("acme"), tenant)
+
+	stream = &testStream{ctx: context.Background()}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCService_Entities(t *testing.T) {
+	s := &grpcService{service: newTestGraphService(t)}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	_, err := s.entity.CreateEntityAndWait(ctx, &entity.InsertEntity{
+		CommandModel: model.CommandModel{ID: "alice", TenantID: "acme"},
+		Data:         model.Data{"name": "Alice"},
+		Type:         "user",
+	})
+	require.NoError(t, err)
+
+	res, err := s.GetEntity(ctx, &edgestorepb.GetEntityRequest{Id: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", res.GetId())
+	assert.Equal(t, "acme", res.GetTenantId())
+	assert.Equal(t, "user", res.GetOtype())
+	assert.Equal(t, "Alice", res.GetData().AsMap()["name"])
+	assert.Equal(t, int64(1), res.GetVersion())
+	assert.NotNil(t, res.GetCreatedAt())
+	assert.Nil(t, res.GetDeletedAt())
+
+	// Entities of other tenants are not found.
+	_, err = s.GetEntity(context.WithValue(context.Background(), tenantKey{}, "other"), &edgestorepb.GetEntityRequest{Id: "alice"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.GetEntity(ctx, &edgestorepb.GetEntityRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.CreateEntity(ctx, &edgestorepb.CreateEntityRequest{Id: "bob"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	data, err := structpb.NewStruct(map[string]interface{}{"name": "Bob"})
+	require.NoError(t, err)
+
+	write, err := s.CreateEntity(ctx, &edgestorepb.CreateEntityRequest{Id: "bob", Otype: "user", Data: data})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", write.GetId())
+	assert.Empty(t, write.GetOperationId())
+
+	assert.Eventually(t, func() bool {
+		_, err := s.GetEntity(ctx, &edgestorepb.GetEntityRequest{Id: "bob"})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	stream := &testStream{ctx: ctx}
+	require.NoError(t, s.StreamEntityHistory(&edgestorepb.HistoryRequest{Id: "alice"}, stream))
+	require.Len(t, stream.events, 1)
+	assert.Equal(t, "EntityInserted", stream.events[0].GetKind())
+	assert.Equal(t, "alice", stream.events[0].GetId())
+	assert.Equal(t, int64(1), stream.events[0].GetVersion())
+
+	err = s.StreamEntityHistory(&edgestorepb.HistoryRequest{Id: "carol"}, &testStream{ctx: ctx})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCService_Associations(t *testing.T) {
+	s := &grpcService{service: newTestGraphService(t)}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	for _, req := range []*edgestorepb.CreateAssociationRequest{
+		{In: "alice", Out: "bob"},
+		{Atype: "follows", Out: "bob"},
+		{Atype: "follows", In: "alice"},
+		{Atype: "follows", In: "alice", Out: "bob", OnDuplicate: "ignore"},
+	} {
+		_, err := s.CreateAssociation(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+
+	write, err := s.CreateAssociation(ctx, &edgestorepb.CreateAssociationRequest{Atype: "follows", In: "alice", Out: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, string(association.NewAssociationID("alice", "follows", "bob")), write.GetId())
+
+	var res *edgestorepb.Association
+	require.Eventually(t, func() bool {
+		res, err = s.GetAssociation(ctx, &edgestorepb.GetAssociationRequest{Id: write.GetId()})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "follows", res.GetAtype())
+	assert.Equal(t, "alice", res.GetIn())
+	assert.Equal(t, "bob", res.GetOut())
+
+	_, err = s.CreateAssociation(ctx, &edgestorepb.CreateAssociationRequest{Atype: "follows", In: "alice", Out: "bob"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = s.GetAssociation(ctx, &edgestorepb.GetAssociationRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -1,16 +1,22 @@
 package master
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/edgestore/edgestore/association"
 
 	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph"
+	"github.com/edgestore/edgestore/internal/auth"
 	"github.com/edgestore/edgestore/internal/errors"
 	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
 	"github.com/edgestore/edgestore/internal/server"
 	"github.com/gin-gonic/gin"
 )
@@ -19,20 +25,103 @@ const Prefix = "/api/v1"
 
 const DefaultPaginationLimit = 10
 
-func NewPagination(ctx *gin.Context) *model.Pagination {
+// MaxPaginationLimit bounds per_page, larger values are clamped to it.
+const MaxPaginationLimit = 100
+
+// MaxQueryBodySize bounds the body of a query request.
+const MaxQueryBodySize = 64 << 10
+
+// pageSize returns the per_page of a request, DefaultPaginationLimit when unset or invalid.
+func pageSize(perPage int) int {
+	switch {
+	case perPage <= 0:
+		return DefaultPaginationLimit
+	case perPage > MaxPaginationLimit:
+		return MaxPaginationLimit
+	}
+
+	return perPage
+}
+
+// NewPagination reads the per_page and cursor query parameters of a collection endpoint, the
+// cursor being the next_cursor of the previous page.
+func (s *service) NewPagination(ctx *gin.Context) (*model.Pagination, error) {
 	perPage, _ := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(DefaultPaginationLimit)))
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "0"))
-	return model.NewPagination(perPage, page)
+
+	after, err := s.cursors.Decode(ctx.Query("cursor"))
+	if err != nil {
+		return nil, errors.E(errors.Invalid, err)
+	}
+
+	return model.NewPagination(pageSize(perPage), after), nil
+}
+
+// DefaultWaitTimeout bounds how long a synchronous write waits for its command to be applied.
+const DefaultWaitTimeout = 10 * time.Second
+
+// Wait reports whether the client asked for the written resource instead of an acknowledgment,
+// either with a "Prefer: return=representation" header or a wait=true query parameter.
+func Wait(ctx *gin.Context) bool {
+	if wait, _ := strconv.ParseBool(ctx.Query("wait")); wait {
+		return true
+	}
+
+	for _, header := range ctx.Request.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "return=representation") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NewWaitContext returns the context of a synchronous write.
+func NewWaitContext(ctx *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.Request.Context(), DefaultWaitTimeout)
+}
+
+// RespondWritten answers a synchronous write with the written resource. Commands still pending when
+// the wait times out are acknowledged with 202 and the operation tracking them, as asynchronous
+// writes are.
+func (s *service) RespondWritten(ctx *gin.Context, op errors.Op, code int, location string, res interface{}, err error) {
+	if err != nil && !operation.IsPending(err) {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	if location != "" {
+		ctx.Header("Location", location)
+	}
+
+	if err != nil {
+		OperationLocation(ctx, operation.PendingOperation(err))
+		ctx.Writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	ctx.Header("Preference-Applied", "return=representation")
+	ctx.JSON(code, res)
+}
+
+// OperationLocation points the client to the operation tracking an asynchronous write.
+func OperationLocation(ctx *gin.Context, o *operation.Operation) {
+	if o != nil {
+		ctx.Header("Operation-Location", path.Join(Prefix, "operations", string(o.ID)))
+	}
 }
 
 func (s *service) AbortWithError(ctx *gin.Context, err error) {
 	s.logger.Error(err)
-	res := ER(err)
-	ctx.AbortWithStatusJSON(res.Code, res)
+	AbortWithError(ctx, err)
 }
 
 func (s *service) HTTPHandler() http.Handler {
 	handler := gin.New()
+	// Services read the principal of requests from their context.
+	handler.ContextWithFallback = true
 	handler.Use(gin.Recovery())
 
 	handler.Use(server.CORSHandler())
@@ -40,20 +129,87 @@ func (s *service) HTTPHandler() http.Handler {
 	handler.Use(server.RequestIDHandler())
 	handler.NoRoute(server.NotFoundHandler)
 	handler.GET("/", s.RootHandler)
+	handler.GET(OpenAPIPath, s.OpenAPIHandler())
+	handler.GET(HealthPath, s.HealthHandler)
+	handler.GET(ReadyPath, ReadyHandler(s.readinessChecks(), s.logger, false))
+
+	api := handler.Group(Prefix).Use(NewTenantMiddleware(s.auth, s.cfg.Auth.InsecureTenantHeader), s.RateLimit(), s.Idempotency())
+	readKeys := s.Authorize(auth.ResourceTenant, auth.VerbRead, "api-keys")
+	writeKeys := s.Authorize(auth.ResourceTenant, auth.VerbWrite, "api-keys")
+	api.DELETE("/api-keys/:id", writeKeys, s.RevokeAPIKeyHandler)
+	api.GET("/api-keys", readKeys, s.ListAPIKeysHandler)
+	api.GET("/api-keys/:id", readKeys, s.GetAPIKeyHandler)
+	api.POST("/api-keys", writeKeys, s.CreateAPIKeyHandler)
+	api.POST("/api-keys/:id/rotate", writeKeys, s.RotateAPIKeyHandler)
+
+	api.DELETE("/association-types/:atype", s.DeleteDefinitionHandler)
+	api.GET("/association-types/:atype", s.GetDefinitionHandler)
+	api.POST("/association-types", s.CreateDefinitionHandler)
+	api.PUT("/association-types/:atype", s.UpdateDefinitionHandler)
 
-	api := handler.Group(Prefix).Use(NewTenantMiddleware())
 	api.DELETE("/associations/:id", s.DeleteAssociationHandler)
+	api.GET("/associations", s.GetAssociationsHandler)
 	api.GET("/associations/:id", s.GetAssociationHandler)
 	api.POST("/associations", s.CreateAssociationHandler)
+	api.POST("/associations/batch-get", s.GetAssociationsHandler)
 	api.PUT("/associations/:id", s.UpdateAssociationHandler)
 
+	api.GET("/changes", s.ChangesHandler)
+	api.GET("/changes/ws", s.ChangesWebSocketHandler)
+
+	api.POST("/check", s.CheckHandler)
+
 	api.DELETE("/entities/:id", s.DeleteEntityHandler)
+	api.GET("/entities", s.GetEntitiesHandler)
 	api.GET("/entities/:id", s.GetEntityHandler)
+	api.GET("/entities/:id/associations", s.GetEntityAssociationsHandler)
 	api.POST("/entities", s.CreateEntityHandler)
+	api.POST("/entities/batch-get", s.GetEntitiesHandler)
 	api.PUT("/entities/:id", s.UpdateEntityHandler)
 
+	api.POST("/expand", s.ExpandHandler)
+
+	api.GET("/export", s.Authorize(auth.ResourceTenant, auth.VerbRead, "export"), s.ExportHandler)
+
+	api.POST("/graphql", s.GraphQLHandler())
+
 	api.POST("/guid", s.CreateGUIDHandler)
 
+	api.POST("/import", s.Authorize(auth.ResourceTenant, auth.VerbWrite, "import"), s.ImportHandler)
+
+	api.GET("/operations/:id", s.GetOperationHandler)
+
+	readNamespaces := s.Authorize(auth.ResourceTenant, auth.VerbRead, "namespaces")
+	writeNamespaces := s.Authorize(auth.ResourceTenant, auth.VerbWrite, "namespaces")
+	api.DELETE("/namespaces/:otype", writeNamespaces, s.DeleteNamespaceHandler)
+	api.GET("/namespaces", readNamespaces, s.ListNamespacesHandler)
+	api.GET("/namespaces/:otype", readNamespaces, s.GetNamespaceHandler)
+	api.PUT("/namespaces/:otype", writeNamespaces, s.PutNamespaceHandler)
+
+	readPolicies := s.Authorize(auth.ResourceTenant, auth.VerbRead, "policies")
+	writePolicies := s.Authorize(auth.ResourceTenant, auth.VerbWrite, "policies")
+	api.DELETE("/policies/:role", writePolicies, s.DeletePolicyHandler)
+	api.GET("/policies", readPolicies, s.ListPoliciesHandler)
+	api.GET("/policies/:role", readPolicies, s.GetPolicyHandler)
+	api.PUT("/policies/:role", writePolicies, s.PutPolicyHandler)
+
+	api.POST("/query", s.QueryHandler)
+
+	api.POST("/traverse", s.TraverseHandler)
+	api.POST("/traverse/path", s.ShortestPathHandler)
+
+	api.GET("/usage", s.Authorize(auth.ResourceTenant, auth.VerbRead, "usage"), s.GetUsageHandler)
+
+	readWebhooks := s.Authorize(auth.ResourceTenant, auth.VerbRead, "webhooks")
+	writeWebhooks := s.Authorize(auth.ResourceTenant, auth.VerbWrite, "webhooks")
+	api.DELETE("/webhooks/:id", writeWebhooks, s.DeleteWebhookHandler)
+	api.GET("/webhooks", readWebhooks, s.ListWebhooksHandler)
+	api.GET("/webhooks/:id", readWebhooks, s.GetWebhookHandler)
+	api.GET("/webhooks/:id/dead-letters", readWebhooks, s.GetDeadLettersHandler)
+	api.POST("/webhooks", writeWebhooks, s.CreateWebhookHandler)
+	api.POST("/webhooks/:id/dead-letters/:delivery/replay", writeWebhooks, s.ReplayDeadLetterHandler)
+	api.PUT("/webhooks/:id", writeWebhooks, s.UpdateWebhookHandler)
+
 	return handler
 }
 
@@ -63,11 +219,41 @@ func (s *service) GetAssociationHandler(ctx *gin.Context) {
 	tenant := ctx.GetString(TenantKey)
 	id := ctx.Param("id")
 
+	fields, err := Fields(ctx, association.Association{})
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	variant := ""
+	if fields != nil {
+		variant = fieldsVariant(fields)
+	}
+
+	if Conditional(ctx) {
+		rev, err := s.association.GetAssociationRevision(ctx, model.ID(id), model.ID(tenant))
+		if err != nil {
+			s.logger.Error(errors.E(op, err))
+			s.AbortWithError(ctx, err)
+			return
+		}
+
+		if NotModified(ctx, rev, variant) {
+			ctx.Writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	if agg, err := s.association.GetAssociation(ctx, model.ID(id), model.ID(tenant)); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
-		ctx.JSON(http.StatusOK, agg)
+		SetRevisionHeaders(ctx, &model.Revision{Version: agg.Version, UpdatedAt: agg.UpdatedAt}, variant)
+		if fields != nil {
+			s.projectJSON(ctx, fields, agg)
+		} else {
+			ctx.JSON(http.StatusOK, agg)
+		}
 	}
 }
 
@@ -84,12 +270,22 @@ func (s *service) CreateAssociationHandler(ctx *gin.Context) {
 	tenant := ctx.GetString(TenantKey)
 	form.TenantID = model.ID(tenant)
 
-	if err := s.association.CreateAssociation(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.association.CreateAssociationAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusCreated, path.Join(Prefix, "associations", string(form.ID)), res, err)
+		return
+	}
+
+	if o, err := s.association.CreateAssociation(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
-		location := path.Join(Prefix, "entities", string(form.ID))
+		location := path.Join(Prefix, "associations", string(form.ID))
 		ctx.Header("Location", location)
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
@@ -107,10 +303,20 @@ func (s *service) UpdateAssociationHandler(ctx *gin.Context) {
 	tenant := ctx.GetString(TenantKey)
 	form.TenantID = model.ID(tenant)
 	form.ID = model.ID(ctx.Param("id"))
-	if err := s.association.UpdateAssociation(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.association.UpdateAssociationAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusOK, "", res, err)
+		return
+	}
+
+	if o, err := s.association.UpdateAssociation(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
@@ -123,14 +329,97 @@ func (s *service) DeleteAssociationHandler(ctx *gin.Context) {
 	form.TenantID = model.ID(tenant)
 	form.ID = model.ID(ctx.Param("id"))
 
-	if err := s.association.DeleteAssociation(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.association.DeleteAssociationAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusOK, "", res, err)
+		return
+	}
+
+	if o, err := s.association.DeleteAssociation(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
 
+func (s *service) GetDefinitionHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetDefinitionHandler"
+
+	tenant := ctx.GetString(TenantKey)
+	atype := ctx.Param("atype")
+
+	if def, err := s.association.GetDefinition(ctx, atype, model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, def)
+	}
+}
+
+func (s *service) CreateDefinitionHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.CreateDefinitionHandler"
+
+	var form association.InsertDefinition
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	if def, err := s.association.CreateDefinition(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		location := path.Join(Prefix, "association-types", def.Type)
+		ctx.Header("Location", location)
+		ctx.JSON(http.StatusCreated, def)
+	}
+}
+
+func (s *service) UpdateDefinitionHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.UpdateDefinitionHandler"
+
+	var form association.UpdateDefinition
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	if def, err := s.association.UpdateDefinition(ctx, ctx.Param("atype"), &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, def)
+	}
+}
+
+func (s *service) DeleteDefinitionHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.DeleteDefinitionHandler"
+
+	form := association.DeleteDefinition{}
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	if err := s.association.DeleteDefinition(ctx, ctx.Param("atype"), &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func (s *service) GetEntityHandler(ctx *gin.Context) {
 	const op errors.Op = "api/service.GetEntityHandler"
 
@@ -139,16 +428,114 @@ func (s *service) GetEntityHandler(ctx *gin.Context) {
 
 	_, dataOnly := ctx.GetQuery("data")
 
+	fields, err := Fields(ctx, entity.Entity{})
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	if dataOnly && fields != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, "data and fields cannot be combined"))
+		return
+	}
+
+	includes, err := ParseIncludes(ctx.Query("include"))
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	if dataOnly && includes != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, "data and include cannot be combined"))
+		return
+	}
+
+	if includes != nil {
+		s.getEntityWithIncludes(ctx, model.ID(id), model.ID(tenant), fields, includes)
+		return
+	}
+
+	variant := ""
+	if dataOnly {
+		variant = "data"
+	} else if fields != nil {
+		variant = fieldsVariant(fields)
+	}
+
+	if Conditional(ctx) {
+		rev, err := s.entity.GetEntityRevision(ctx, model.ID(id), model.ID(tenant))
+		if err != nil {
+			s.logger.Error(errors.E(op, err))
+			s.AbortWithError(ctx, err)
+			return
+		}
+
+		if NotModified(ctx, rev, variant) {
+			ctx.Writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	if agg, err := s.entity.GetEntity(ctx, model.ID(id), model.ID(tenant)); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
+		SetRevisionHeaders(ctx, &model.Revision{Version: agg.Version, UpdatedAt: agg.UpdatedAt}, variant)
 		if dataOnly {
 			ctx.JSON(http.StatusOK, agg.Data)
+		} else if fields != nil {
+			s.projectJSON(ctx, fields, agg)
 		} else {
 			ctx.JSON(http.StatusOK, agg)
 		}
+	}
+}
+
+// GetEntityAssociationsHandler lists the outgoing associations of an entity, or the incoming
+// associations with ?direction=in, optionally filtered by ?atype.
+func (s *service) GetEntityAssociationsHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetEntityAssociationsHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+	id := model.ID(ctx.Param("id"))
+	atype := ctx.Query("atype")
+
+	pagination, err := s.NewPagination(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	fields, err := Fields(ctx, association.Association{})
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	var page *model.Page[*association.Association]
+	switch direction := ctx.DefaultQuery("direction", "out"); direction {
+	case "out":
+		page, err = s.association.GetOutgoingAssociations(ctx, id, atype, tenant, pagination)
+	case "in":
+		page, err = s.association.GetIncomingAssociations(ctx, id, atype, tenant, pagination)
+	default:
+		err = errors.E(op, errors.Invalid, fmt.Sprintf("invalid direction %q, expected in or out", direction))
+	}
+
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else if fields != nil {
+		page.Sign(s.cursors)
+		items, err := projectItems(fields, page.Items)
+		if err != nil {
+			s.AbortWithError(ctx, errors.E(op, errors.Internal, err))
+			return
+		}
 
+		ctx.JSON(http.StatusOK, &model.Page[map[string]interface{}]{Items: items, NextCursor: page.NextCursor, HasMore: page.HasMore})
+	} else {
+		ctx.JSON(http.StatusOK, page.Sign(s.cursors))
 	}
 }
 
@@ -165,12 +552,22 @@ func (s *service) CreateEntityHandler(ctx *gin.Context) {
 	tenant := ctx.GetString(TenantKey)
 	form.TenantID = model.ID(tenant)
 
-	if err := s.entity.CreateEntity(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.entity.CreateEntityAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusCreated, path.Join(Prefix, "entities", string(form.ID)), res, err)
+		return
+	}
+
+	if o, err := s.entity.CreateEntity(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
 		location := path.Join(Prefix, "entities", string(form.ID))
 		ctx.Header("Location", location)
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
@@ -187,10 +584,20 @@ func (s *service) UpdateEntityHandler(ctx *gin.Context) {
 	tenant := ctx.GetString(TenantKey)
 	form.TenantID = model.ID(tenant)
 	form.ID = model.ID(ctx.Param("id"))
-	if err := s.entity.UpdateEntity(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.entity.UpdateEntityAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusOK, "", res, err)
+		return
+	}
+
+	if o, err := s.entity.UpdateEntity(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
@@ -203,14 +610,38 @@ func (s *service) DeleteEntityHandler(ctx *gin.Context) {
 	form.TenantID = model.ID(tenant)
 	form.ID = model.ID(ctx.Param("id"))
 
-	if err := s.entity.DeleteEntity(ctx, &form); err != nil {
+	if Wait(ctx) {
+		wctx, cancel := NewWaitContext(ctx)
+		defer cancel()
+
+		res, err := s.entity.DeleteEntityAndWait(wctx, &form)
+		s.RespondWritten(ctx, op, http.StatusOK, "", res, err)
+		return
+	}
+
+	if o, err := s.entity.DeleteEntity(ctx, &form); err != nil {
 		s.logger.Error(errors.E(op, err))
 		s.AbortWithError(ctx, err)
 	} else {
+		OperationLocation(ctx, o)
 		ctx.Writer.WriteHeader(http.StatusAccepted)
 	}
 }
 
+func (s *service) GetOperationHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetOperationHandler"
+
+	tenant := ctx.GetString(TenantKey)
+	id := ctx.Param("id")
+
+	if o, err := s.operations.Get(ctx, model.ID(id), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, o)
+	}
+}
+
 func (s *service) CreateGUIDHandler(ctx *gin.Context) {
 	const op errors.Op = "api/service.CreateGUIDHandler"
 
@@ -227,3 +658,76 @@ func (s *service) CreateGUIDHandler(ctx *gin.Context) {
 		})
 	}
 }
+
+func (s *service) TraverseHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.TraverseHandler"
+
+	var form graph.Traverse
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	if res, err := s.graph.Traverse(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+func (s *service) ShortestPathHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ShortestPathHandler"
+
+	var form graph.ShortestPath
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	if path, err := s.graph.ShortestPath(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, path)
+	}
+}
+
+func (s *service) QueryHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.QueryHandler"
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MaxQueryBodySize)
+
+	var form graph.Query
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	form.TenantID = model.ID(tenant)
+
+	pagination, err := s.NewPagination(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+	form.Pagination = pagination
+
+	if res, err := s.graph.Query(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		res.Sign(s.cursors)
+		ctx.JSON(http.StatusOK, res)
+	}
+}
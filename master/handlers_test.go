@@ -0,0 +1,123 @@
+package master
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/graph"
+	"github.com/edgestore/edgestore/graph/query"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondWritten(t *testing.T) {
+	s := newTestService()
+
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		location  string
+		operation string
+	}{
+		{"applied", nil, http.StatusCreated, "/entities/1", ""},
+		{"pending", errors.E(errors.Transient, operation.ErrPending), http.StatusAccepted, "/entities/1", ""},
+		{"pending tracked", errors.E(errors.Transient, &operation.PendingError{Operation: &operation.Operation{ID: "op1"}}), http.StatusAccepted, "/entities/1", Prefix + "/operations/op1"},
+		{"store outage", errors.E(errors.Transient, "policy store unavailable"), http.StatusServiceUnavailable, "", ""},
+		{"invalid", errors.E(errors.Invalid, "invalid type"), http.StatusBadRequest, "", ""},
+	}
+
+	for _, tt := range tests {
+		engine := gin.New()
+		engine.POST("/entities", func(ctx *gin.Context) {
+			s.RespondWritten(ctx, "test", http.StatusCreated, "/entities/1", gin.H{}, tt.err)
+		})
+
+		w := post(engine, "/entities", "", nil)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		assert.Equal(t, tt.location, w.Header().Get("Location"), tt.name)
+		assert.Equal(t, tt.operation, w.Header().Get("Operation-Location"), tt.name)
+	}
+}
+
+func TestNewPagination(t *testing.T) {
+	s := newTestService()
+	s.cursors = model.NewCursorSigner([]byte("secret"))
+
+	engine := gin.New()
+	engine.GET("/entities", func(ctx *gin.Context) {
+		p, err := s.NewPagination(ctx)
+		if err != nil {
+			AbortWithError(ctx, err)
+			return
+		}
+
+		ctx.String(http.StatusOK, strconv.Itoa(p.Limit))
+	})
+
+	for query, limit := range map[string]string{
+		"":               strconv.Itoa(DefaultPaginationLimit),
+		"?per_page=25":   "25",
+		"?per_page=0":    strconv.Itoa(DefaultPaginationLimit),
+		"?per_page=-1":   strconv.Itoa(DefaultPaginationLimit),
+		"?per_page=abc":  strconv.Itoa(DefaultPaginationLimit),
+		"?per_page=1000": strconv.Itoa(MaxPaginationLimit),
+	} {
+		w := get(engine, "/entities"+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+		assert.Equal(t, limit, w.Body.String(), query)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get(engine, "/entities?cursor=forged", nil).Code)
+}
+
+func TestQueryHandler(t *testing.T) {
+	s := newTestGraph(t)
+	s.graph = graph.New(&graph.Config{Associations: s.association, Entities: s.entity, Logger: s.logger})
+
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.POST("/query", s.QueryHandler)
+	headers := map[string]string{TenantHeader: "acme", "Content-Type": "application/json"}
+
+	w := post(engine, "/query", `{"query": "(u:user {id: \"alice\"})-[:follows]->(f) RETURN f"}`, headers)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"truncated":false`)
+
+	w = post(engine, "/query", `{"query": "(u:user) WHERE `+strings.Repeat("NOT ", query.MaxDepth+1)+`u.x = 1"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Bodies are bounded.
+	w = post(engine, "/query", `{"query": "(u:user) RETURN u", "padding": "`+strings.Repeat("x", MaxQueryBodySize)+`"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateAssociationHandler(t *testing.T) {
+	s := newTestGraphService(t)
+
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.POST("/associations", s.CreateAssociationHandler)
+	headers := map[string]string{TenantHeader: "acme", "Content-Type": "application/json"}
+
+	// The Location points at the ID derived from the linked entities and the type.
+	w := post(engine, "/associations?wait=true", `{"in": "alice", "atype": "follows", "out": "bob"}`, headers)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, Prefix+"/associations/"+string(association.NewAssociationID("alice", "follows", "bob")), w.Header().Get("Location"))
+
+	w = post(engine, "/associations", `{"in": "alice", "atype": "follows", "out": "carol"}`, headers)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, Prefix+"/associations/"+string(association.NewAssociationID("alice", "follows", "carol")), w.Header().Get("Location"))
+
+	w = post(engine, "/associations?wait=true", `{"in": "alice", "atype": "follows", "out": "bob"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+
+	w = post(engine, "/associations?wait=true", `{"in": "alice", "atype": "follows", "out": "bob", "data": {"since": "2020"}, "on_duplicate": "upsert"}`, headers)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, Prefix+"/associations/"+string(association.NewAssociationID("alice", "follows", "bob")), w.Header().Get("Location"))
+}
@@ -0,0 +1,162 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/server"
+	"github.com/edgestore/edgestore/internal/server/pprof"
+	"github.com/edgestore/edgestore/internal/server/stats"
+	"github.com/edgestore/edgestore/version"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HealthPath answers as long as the process serves requests, for liveness probes.
+	HealthPath = "/healthz"
+
+	// ReadyPath answers 503 while a dependency of the service is unavailable, for readiness
+	// probes.
+	ReadyPath = "/readyz"
+
+	// StatsPath serves the runtime stats of the process.
+	StatsPath = "/debug/stats"
+)
+
+// ReadyTimeout bounds the checks of a readiness probe.
+var ReadyTimeout = 2 * time.Second
+
+// Check fails while a dependency of the service is unavailable.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a Check, its error reported on the admin port only.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness is the outcome of a readiness probe, "ok" when every check passed and "unavailable"
+// otherwise.
+type Readiness struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks"`
+}
+
+// QueueCheck returns a Check failing while the worker queue reported by queue is full, when
+// writes block until a worker is free.
+func QueueCheck(queue func() (length int, capacity int)) Check {
+	return func(ctx context.Context) error {
+		if length, capacity := queue(); length >= capacity {
+			return errors.E(errors.Exhausted, fmt.Sprintf("worker queue saturated: %d/%d jobs", length, capacity))
+		}
+
+		return nil
+	}
+}
+
+// HealthHandler answers as long as the process serves requests.
+func (s *service) HealthHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadyHandler returns a handler running checks concurrently, bounded by ReadyTimeout. The errors
+// of failed checks are logged, and only reported when detailed is set: they may carry addresses
+// and credentials of the dependencies.
+func ReadyHandler(checks map[string]Check, logger logrus.FieldLogger, detailed bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), ReadyTimeout)
+		defer cancel()
+
+		res := &Readiness{Status: "ok", Checks: map[string]*CheckResult{}}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check Check) {
+				defer wg.Done()
+
+				r := &CheckResult{Status: "ok"}
+				if err := check(c); err != nil {
+					logger.Warnf("readiness check %s failed: %v", name, err)
+					r = &CheckResult{Status: "failed"}
+					if detailed {
+						r.Error = err.Error()
+					}
+				}
+
+				mu.Lock()
+				res.Checks[name] = r
+				mu.Unlock()
+			}(name, check)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		for _, r := range res.Checks {
+			if r.Status != "ok" {
+				res.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		ctx.JSON(status, res)
+	}
+}
+
+// readinessChecks checks Postgres, Redis and the worker queues of the service.
+func (s *service) readinessChecks() map[string]Check {
+	checks := map[string]Check{}
+	if pinger, ok := s.store.(eventstore.Pinger); ok {
+		checks["postgres"] = pinger.Ping
+	}
+
+	if s.cache != nil {
+		checks["redis"] = func(ctx context.Context) error {
+			return s.cache.Ping(ctx).Err()
+		}
+	}
+
+	if s.entity != nil {
+		checks["entity_queue"] = QueueCheck(s.entity.QueueLen)
+	}
+
+	if s.association != nil {
+		checks["association_queue"] = QueueCheck(s.association.QueueLen)
+	}
+
+	return checks
+}
+
+// StatsHandler serves the runtime stats of the process.
+func (s *service) StatsHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, stats.GetStats(version.Version))
+}
+
+// registerDebug registers the runtime stats, and pprof when enabled, with engine. Only the admin
+// handler registers them: they are served without authentication.
+func (s *service) registerDebug(engine *gin.Engine) {
+	engine.GET(StatsPath, s.StatsHandler)
+	if s.cfg.Server.EnablePProf {
+		pprof.Register(engine)
+	}
+}
+
+// AdminHandler serves the debug endpoints on the admin port, with the health endpoints for
+// probes pointed at it.
+func (s *service) AdminHandler() http.Handler {
+	handler := gin.New()
+	handler.Use(gin.Recovery())
+	handler.Use(server.LoggerHandler(s.logger, time.RFC3339, true))
+	handler.NoRoute(server.NotFoundHandler)
+	handler.GET(HealthPath, s.HealthHandler)
+	handler.GET(ReadyPath, ReadyHandler(s.readinessChecks(), s.logger, true))
+	s.registerDebug(handler)
+
+	return handler
+}
@@ -0,0 +1,70 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyHandler(t *testing.T) {
+	queue := 0
+	checks := map[string]Check{
+		"postgres": func(ctx context.Context) error { return nil },
+		"queue":    QueueCheck(func() (int, int) { return queue, 4 }),
+	}
+
+	engine := gin.New()
+	engine.GET(ReadyPath, ReadyHandler(checks, newTestService().logger, true))
+	engine.GET("/public"+ReadyPath, ReadyHandler(checks, newTestService().logger, false))
+
+	w := get(engine, ReadyPath, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res Readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "ok", res.Status)
+	assert.Equal(t, &CheckResult{Status: "ok"}, res.Checks["queue"])
+
+	queue = 4
+	w = get(engine, ReadyPath, nil)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "unavailable", res.Status)
+	assert.Equal(t, "ok", res.Checks["postgres"].Status)
+	assert.Equal(t, "failed", res.Checks["queue"].Status)
+	assert.Contains(t, res.Checks["queue"].Error, "4/4")
+
+	// Public probes report the status of checks without their errors.
+	w = get(engine, "/public"+ReadyPath, nil)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status": "unavailable", "checks": {"postgres": {"status": "ok"}, "queue": {"status": "failed"}}}`, w.Body.String())
+
+	assert.True(t, errors.Is(errors.Exhausted, QueueCheck(func() (int, int) { return 5, 4 })(context.Background())))
+}
+
+func TestHTTPHandler_Debug(t *testing.T) {
+	s := newTestService()
+	s.cfg.Server.EnablePProf = true
+
+	// Debug endpoints are only served by the admin handler, with or without an admin port.
+	for _, port := range []int{0, 9090} {
+		s.cfg.Server.AdminPort = port
+		engine := s.HTTPHandler()
+		assert.Equal(t, http.StatusOK, get(engine, HealthPath, nil).Code)
+		assert.Equal(t, http.StatusOK, get(engine, ReadyPath, nil).Code)
+		assert.Equal(t, http.StatusNotFound, get(engine, StatsPath, nil).Code)
+		assert.Equal(t, http.StatusNotFound, get(engine, "/debug/pprof/cmdline", nil).Code)
+	}
+
+	admin := s.AdminHandler()
+	assert.Equal(t, http.StatusOK, get(admin, StatsPath, nil).Code)
+	assert.Equal(t, http.StatusOK, get(admin, "/debug/pprof/cmdline", nil).Code)
+	assert.Equal(t, http.StatusOK, get(admin, ReadyPath, nil).Code)
+}
@@ -0,0 +1,179 @@
+package master
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/idempotency"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader names the key of a write request safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks the responses replayed for a retried key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// MaxIdempotentBodySize bounds the requests with an Idempotency-Key, and the responses stored for
+// them.
+var MaxIdempotentBodySize int64 = 1 << 20
+
+// IdempotentRoute reports whether the route path, relative to Prefix, of a request accepts an
+// Idempotency-Key: the writes of entities, associations and association types, whose requests
+// and responses are small and hold no secret.
+func IdempotentRoute(method string, path string) bool {
+	if method == http.MethodGet || method == http.MethodHead || strings.HasSuffix(path, "/batch-get") {
+		return false
+	}
+
+	switch strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0] {
+	case "association-types", "associations", "entities":
+		return true
+	}
+
+	return false
+}
+
+// replayedHeaders are the response headers stored along with the response of a key.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location", "Operation-Location", "Preference-Applied"}
+
+// recorder copies the body of a response while writing it, up to MaxIdempotentBodySize.
+type recorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recorder) record(b []byte) {
+	if int64(w.body.Len()+len(b)) > MaxIdempotentBodySize {
+		w.overflow = true
+		return
+	}
+
+	w.body.Write(b)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency returns a middleware making write requests with an Idempotency-Key header safe to
+// retry: the response of the first request of a key is stored and replayed to the requests
+// retrying it, while reusing a key for another request fails. Server errors and rate limited
+// requests are not stored, so that they can be retried. Keys are only accepted by the routes of
+// IdempotentRoute.
+func (s *service) Idempotency() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		const op errors.Op = "api/service.Idempotency"
+
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" || ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+
+		if !IdempotentRoute(ctx.Request.Method, strings.TrimPrefix(ctx.FullPath(), Prefix)) {
+			s.AbortWithError(ctx, errors.E(op, errors.Invalid, errors.Code("idempotency_unsupported"), "this route does not accept idempotency keys"))
+			return
+		}
+
+		if len(key) > idempotency.MaxKeyLength {
+			s.AbortWithError(ctx, errors.E(op, errors.Invalid, "the idempotency key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, MaxIdempotentBodySize+1))
+		if err != nil {
+			s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+			return
+		}
+
+		if int64(len(body)) > MaxIdempotentBodySize {
+			s.AbortWithError(ctx, errors.E(op, errors.Invalid, errors.Code("body_too_large"), fmt.Sprintf("requests with an idempotency key are limited to %d bytes", MaxIdempotentBodySize)))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Requests are authorized after this middleware, so keys are scoped by principal: another
+		// principal of the tenant sending the same key is not replayed the response of the first.
+		var subject string
+		if p := auth.FromContext(ctx.Request.Context()); p != nil {
+			subject = p.Subject
+		}
+
+		now := time.Now().UTC()
+		rec := &idempotency.Record{
+			Key:         key,
+			TenantID:    model.ID(ctx.GetString(TenantKey)),
+			Subject:     subject,
+			Fingerprint: idempotency.Fingerprint(ctx.Request.Method, ctx.Request.URL.RequestURI(), body),
+			CreatedAt:   &now,
+		}
+
+		holder, err := s.idempotency.Begin(ctx, rec)
+		if err != nil {
+			s.AbortWithError(ctx, errors.E(op, errors.Transient, err))
+			return
+		}
+
+		if holder != nil {
+			res, err := holder.Replay(rec.Fingerprint)
+			if err != nil {
+				s.AbortWithError(ctx, err)
+				return
+			}
+
+			for name, values := range res.Header {
+				for _, v := range values {
+					ctx.Writer.Header().Add(name, v)
+				}
+			}
+			ctx.Header(IdempotentReplayedHeader, "true")
+			ctx.Writer.WriteHeader(res.Status)
+			ctx.Writer.Write(res.Body)
+			ctx.Abort()
+			return
+		}
+
+		w := &recorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+		ctx.Next()
+
+		// Responses marked no-store, such as those holding secrets, and responses too large to
+		// store are not replayed: their key is released instead.
+		status := w.Status()
+		noStore := strings.Contains(w.Header().Get("Cache-Control"), "no-store")
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || noStore || w.overflow {
+			if err := s.idempotency.Abort(context.Background(), rec); err != nil {
+				s.logger.Error(errors.E(op, err))
+			}
+			return
+		}
+
+		header := http.Header{}
+		for _, name := range replayedHeaders {
+			if v := w.Header().Values(name); len(v) > 0 {
+				header[name] = v
+			}
+		}
+
+		rec.Response = &idempotency.Response{Status: status, Header: header, Body: w.body.Bytes()}
+		if err := s.idempotency.Complete(context.Background(), rec); err != nil {
+			s.logger.Error(errors.E(op, err))
+		}
+	}
+}
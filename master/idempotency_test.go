@@ -0,0 +1,154 @@
+package master
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/idempotency"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(engine http.Handler, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	return send(engine, http.MethodPost, path, body, headers)
+}
+
+func put(engine http.Handler, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	return send(engine, http.MethodPut, path, body, headers)
+}
+
+func send(engine http.Handler, method string, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	s := newTestService()
+	s.idempotency = idempotency.NewInMemory(time.Hour)
+
+	created, failed := 0, 0
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.POST("/entities", s.Idempotency(), func(ctx *gin.Context) {
+		created++
+		ctx.Header("Location", "/entities/1")
+		ctx.JSON(http.StatusCreated, gin.H{"n": created})
+	})
+	engine.POST("/associations", s.Idempotency(), func(ctx *gin.Context) {
+		failed++
+		ctx.Status(http.StatusServiceUnavailable)
+	})
+	engine.PUT("/entities/:id", s.Idempotency(), func(ctx *gin.Context) {
+		created++
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, gin.H{"n": created})
+	})
+	engine.POST("/api-keys", s.Idempotency(), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusCreated, gin.H{"token": "esk_secret"})
+	})
+
+	headers := map[string]string{TenantHeader: "acme", IdempotencyKeyHeader: "k1"}
+	w := post(engine, "/entities", `{"otype":"user"}`, headers)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+
+	// Retries are answered the stored response without creating again.
+	w = post(engine, "/entities", `{"otype":"user"}`, headers)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "/entities/1", w.Header().Get("Location"))
+	assert.JSONEq(t, `{"n":1}`, w.Body.String())
+	assert.Equal(t, 1, created)
+
+	w = post(engine, "/entities", `{"otype":"team"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Keys are scoped by tenant, and requests without key are not deduplicated.
+	post(engine, "/entities", `{"otype":"user"}`, map[string]string{TenantHeader: "other", IdempotencyKeyHeader: "k1"})
+	post(engine, "/entities", `{"otype":"user"}`, map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, 3, created)
+
+	// Server errors are not stored.
+	headers[IdempotencyKeyHeader] = "k2"
+	post(engine, "/associations", "", headers)
+	w = post(engine, "/associations", "", headers)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 2, failed)
+
+	// Neither are responses marked no-store.
+	headers[IdempotencyKeyHeader] = "k3"
+	put(engine, "/entities/1", `{}`, headers)
+	w = put(engine, "/entities/1", `{}`, headers)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 5, created)
+
+	// Keys are only accepted by the writes of resources, with bounded bodies.
+	headers[IdempotencyKeyHeader] = "k4"
+	w = post(engine, "/api-keys", `{}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "idempotency_unsupported")
+
+	w = post(engine, "/entities", `{"data":"`+strings.Repeat("x", int(MaxIdempotentBodySize))+`"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "body_too_large")
+	assert.Equal(t, 5, created)
+}
+
+func TestIdempotency_Principals(t *testing.T) {
+	s := newTestService()
+	s.idempotency = idempotency.NewInMemory(time.Hour)
+
+	authn := newTestAuthenticator(t)
+	created := 0
+	engine := newTenantEngine(authn, false)
+	engine.POST("/entities", s.Idempotency(), func(ctx *gin.Context) {
+		created++
+		ctx.JSON(http.StatusCreated, gin.H{"n": created})
+	})
+
+	var tokens []string
+	for _, name := range []string{"writer", "reader"} {
+		key, err := authn.Keys().Create(context.Background(), "acme", name, []string{auth.RoleAdmin})
+		require.NoError(t, err)
+		tokens = append(tokens, key.Token)
+	}
+
+	headers := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token, IdempotencyKeyHeader: "k1"}
+	}
+
+	w := post(engine, "/entities", `{"otype":"user"}`, headers(tokens[0]))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = post(engine, "/entities", `{"otype":"user"}`, headers(tokens[0]))
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.JSONEq(t, `{"n":1}`, w.Body.String())
+
+	// Another principal of the tenant sending the same key is handled on its own.
+	w = post(engine, "/entities", `{"otype":"user"}`, headers(tokens[1]))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.JSONEq(t, `{"n":2}`, w.Body.String())
+}
+
+func TestIdempotentRoute(t *testing.T) {
+	assert.True(t, IdempotentRoute(http.MethodPost, "/entities"))
+	assert.True(t, IdempotentRoute(http.MethodDelete, "/associations/:id"))
+	assert.True(t, IdempotentRoute(http.MethodPut, "/association-types/:atype"))
+	assert.False(t, IdempotentRoute(http.MethodPost, "/entities/batch-get"))
+	assert.False(t, IdempotentRoute(http.MethodPost, "/api-keys"))
+	assert.False(t, IdempotentRoute(http.MethodPost, "/api-keys/:id/rotate"))
+	assert.False(t, IdempotentRoute(http.MethodPost, "/import"))
+	assert.False(t, IdempotentRoute(http.MethodPost, "/query"))
+}
@@ -0,0 +1,235 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxIncludeDepth bounds the association hops of an include.
+	MaxIncludeDepth = 2
+
+	// DefaultIncludeLimit is the number of associations embedded per type and entity, unless the
+	// include_limit query parameter sets another.
+	DefaultIncludeLimit = 10
+
+	// MaxIncludeLimit bounds include_limit.
+	MaxIncludeLimit = 50
+
+	// MaxIncludes bounds the association types included, at every depth.
+	MaxIncludes = 8
+)
+
+// Include embeds the outgoing associations of type AType of an entity and, with Entities, their
+// target entities along with their own Includes.
+type Include struct {
+	AType    string
+	Entities bool
+	Includes []*Include
+}
+
+// ParseIncludes parses a comma-separated list of includes. Each is a path of hops separated by
+// dots: associations:<atype> embeds the associations of a type, and a following entities embeds
+// their targets, which may be followed by an association hop again, e.g.
+// associations:follows.entities.associations:likes.
+func ParseIncludes(s string) ([]*Include, error) {
+	var (
+		includes []*Include
+		count    int
+	)
+	for _, spec := range strings.Split(s, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+
+		level := &includes
+		hops := strings.Split(spec, ".")
+		for i, depth := 0, 1; i < len(hops); depth++ {
+			atype, ok := strings.CutPrefix(hops[i], "associations:")
+			if !ok || atype == "" {
+				return nil, fmt.Errorf("invalid include %q: expected associations:<atype> instead of %q", spec, hops[i])
+			}
+
+			if depth > MaxIncludeDepth {
+				return nil, fmt.Errorf("invalid include %q: at most %d associations deep", spec, MaxIncludeDepth)
+			}
+
+			inc := findInclude(*level, atype)
+			if inc == nil {
+				if count++; count > MaxIncludes {
+					return nil, fmt.Errorf("invalid include: at most %d association types can be included", MaxIncludes)
+				}
+
+				inc = &Include{AType: atype}
+				*level = append(*level, inc)
+			}
+
+			if i++; i == len(hops) {
+				break
+			}
+
+			if hops[i] != "entities" {
+				return nil, fmt.Errorf("invalid include %q: expected entities instead of %q", spec, hops[i])
+			}
+
+			inc.Entities = true
+			level = &inc.Includes
+			i++
+		}
+	}
+
+	return includes, nil
+}
+
+func findInclude(includes []*Include, atype string) *Include {
+	for _, inc := range includes {
+		if inc.AType == atype {
+			return inc
+		}
+	}
+
+	return nil
+}
+
+// IncludeLimit returns the include_limit query parameter, the number of associations embedded
+// per type and entity.
+func IncludeLimit(ctx *gin.Context) (int, error) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("include_limit", strconv.Itoa(DefaultIncludeLimit)))
+	if err != nil || limit <= 0 || limit > MaxIncludeLimit {
+		return 0, errors.E(errors.Invalid, fmt.Sprintf("include_limit must be between 1 and %d", MaxIncludeLimit))
+	}
+
+	return limit, nil
+}
+
+// EmbeddedEntity is an entity with its included associations, by type.
+type EmbeddedEntity struct {
+	*entity.Entity
+	Associations map[string]*EmbeddedAssociations `json:"associations,omitempty"`
+}
+
+// EmbeddedAssociations are the first associations of a type of an entity. HasMore is set when
+// the entity has more than were embedded.
+type EmbeddedAssociations struct {
+	Items   []*EmbeddedAssociation `json:"items"`
+	HasMore bool                   `json:"has_more"`
+}
+
+// EmbeddedAssociation is an association with its target entity, when included and readable.
+type EmbeddedAssociation struct {
+	*association.Association
+	Entity *EmbeddedEntity `json:"entity,omitempty"`
+}
+
+// outgoingAssociations is implemented by association.Service.
+type outgoingAssociations interface {
+	GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error)
+}
+
+// readableEntities is implemented by entity.Service.
+type readableEntities interface {
+	GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*entity.Entity, error)
+}
+
+// embedder embeds the includes of entities, up to limit associations per type.
+type embedder struct {
+	associations outgoingAssociations
+	entities     readableEntities
+	limit        int
+	tenantID     model.ID
+}
+
+// embed returns e with the associations of includes. Target entities the caller may not read
+// are left out, as are those that no longer exist.
+func (m *embedder) embed(ctx context.Context, e *entity.Entity, includes []*Include) (*EmbeddedEntity, error) {
+	const op errors.Op = "api/embedder.embed"
+
+	embedded := &EmbeddedEntity{Entity: e, Associations: map[string]*EmbeddedAssociations{}}
+	for _, inc := range includes {
+		page, err := m.associations.GetOutgoingAssociations(ctx, e.ID, inc.AType, m.tenantID, model.NewPagination(m.limit, nil))
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		assocs := &EmbeddedAssociations{Items: make([]*EmbeddedAssociation, len(page.Items)), HasMore: page.HasMore}
+		for i, assoc := range page.Items {
+			assocs.Items[i] = &EmbeddedAssociation{Association: assoc}
+		}
+
+		if inc.Entities && len(page.Items) > 0 {
+			ids := make([]model.ID, len(page.Items))
+			for i, assoc := range page.Items {
+				ids[i] = assoc.Out
+			}
+
+			targets, err := m.entities.GetReadableEntities(ctx, ids, m.tenantID)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+
+			for i, target := range targets {
+				if target == nil {
+					continue
+				}
+
+				if assocs.Items[i].Entity, err = m.embed(ctx, target, inc.Includes); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		embedded.Associations[inc.AType] = assocs
+	}
+
+	return embedded, nil
+}
+
+// getEntityWithIncludes responds with an entity and its includes. The response has no validators
+// as the included resources change without the entity.
+func (s *service) getEntityWithIncludes(ctx *gin.Context, id model.ID, tenantID model.ID, fields model.Fields, includes []*Include) {
+	const op errors.Op = "api/service.getEntityWithIncludes"
+
+	limit, err := IncludeLimit(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	agg, err := s.entity.GetEntity(ctx, id, tenantID)
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	m := &embedder{associations: s.association, entities: s.entity, limit: limit, tenantID: tenantID}
+	embedded, err := m.embed(ctx, agg, includes)
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	if fields == nil {
+		ctx.JSON(http.StatusOK, embedded)
+		return
+	}
+
+	p, err := fields.Project(agg)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Internal, err))
+		return
+	}
+
+	p["associations"] = embedded.Associations
+	ctx.JSON(http.StatusOK, p)
+}
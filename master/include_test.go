@@ -0,0 +1,143 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIncludes(t *testing.T) {
+	includes, err := ParseIncludes("associations:follows.entities.associations:likes, associations:follows,associations:owns")
+	require.NoError(t, err)
+	assert.Equal(t, []*Include{
+		{AType: "follows", Entities: true, Includes: []*Include{{AType: "likes"}}},
+		{AType: "owns"},
+	}, includes)
+
+	includes, err = ParseIncludes("associations:follows.entities")
+	require.NoError(t, err)
+	assert.Equal(t, []*Include{{AType: "follows", Entities: true}}, includes)
+
+	includes, err = ParseIncludes("")
+	require.NoError(t, err)
+	assert.Nil(t, includes)
+
+	for _, include := range []string{
+		"follows",
+		"associations:",
+		"associations:follows.associations:likes",
+		"associations:a.entities.associations:b.entities.associations:c",
+	} {
+		_, err := ParseIncludes(include)
+		assert.Error(t, err, include)
+	}
+
+	specs := make([]string, MaxIncludes+1)
+	for i := range specs {
+		specs[i] = fmt.Sprintf("associations:a%d", i)
+	}
+	_, err = ParseIncludes(strings.Join(specs[:MaxIncludes], ","))
+	assert.NoError(t, err)
+	_, err = ParseIncludes(strings.Join(specs, ","))
+	assert.Error(t, err)
+}
+
+// stubGraph serves the outgoing associations of its edges and the entities of its types, denying
+// the read of the entities of the types in denied.
+type stubGraph struct {
+	edges  map[model.ID][]model.ID
+	types  map[model.ID]string
+	denied map[string]bool
+}
+
+func (g *stubGraph) GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error) {
+	page := &model.Page[*association.Association]{Items: []*association.Association{}}
+	for i, out := range g.edges[in] {
+		if i == p.Limit {
+			page.HasMore = true
+			break
+		}
+
+		page.Items = append(page.Items, &association.Association{ID: model.ID(fmt.Sprintf("%s-%s", in, out)), In: in, Out: out, Type: atype})
+	}
+
+	return page, nil
+}
+
+func (g *stubGraph) GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*entity.Entity, error) {
+	entities := make([]*entity.Entity, len(ids))
+	for i, id := range ids {
+		if otype, ok := g.types[id]; ok && !g.denied[otype] {
+			entities[i] = &entity.Entity{ID: id, TenantID: tenantID, Type: otype}
+		}
+	}
+
+	return entities, nil
+}
+
+func TestEmbedder_Embed(t *testing.T) {
+	g := &stubGraph{
+		edges: map[model.ID][]model.ID{
+			"alice": {"bob", "vault", "ghost"},
+			"bob":   {"carol"},
+		},
+		types:  map[model.ID]string{"alice": "user", "bob": "user", "carol": "user", "vault": "secret"},
+		denied: map[string]bool{"secret": true},
+	}
+
+	includes, err := ParseIncludes("associations:follows.entities.associations:follows")
+	require.NoError(t, err)
+
+	m := &embedder{associations: g, entities: g, limit: 10, tenantID: "anonymous"}
+	embedded, err := m.embed(context.Background(), &entity.Entity{ID: "alice", Type: "user"}, includes)
+	require.NoError(t, err)
+
+	follows := embedded.Associations["follows"]
+	require.NotNil(t, follows)
+	require.Len(t, follows.Items, 3)
+	assert.False(t, follows.HasMore)
+
+	// Unreadable and missing targets are left out, without failing the others.
+	require.NotNil(t, follows.Items[0].Entity)
+	assert.Equal(t, model.ID("bob"), follows.Items[0].Entity.ID)
+	assert.Nil(t, follows.Items[1].Entity)
+	assert.Nil(t, follows.Items[2].Entity)
+
+	nested := follows.Items[0].Entity.Associations["follows"]
+	require.NotNil(t, nested)
+	require.Len(t, nested.Items, 1)
+	assert.Equal(t, model.ID("carol"), nested.Items[0].Out)
+	assert.Nil(t, nested.Items[0].Entity)
+
+	m.limit = 2
+	embedded, err = m.embed(context.Background(), &entity.Entity{ID: "alice", Type: "user"}, includes)
+	require.NoError(t, err)
+	assert.Len(t, embedded.Associations["follows"].Items, 2)
+	assert.True(t, embedded.Associations["follows"].HasMore)
+}
+
+// failingEntities fails every read, as entity.Service does when the cache is unreachable.
+type failingEntities struct{}
+
+func (failingEntities) GetReadableEntities(ctx context.Context, ids []model.ID, tenantID model.ID) ([]*entity.Entity, error) {
+	return nil, errors.E(errors.IO, "cache unreachable")
+}
+
+func TestEmbedder_Embed_Error(t *testing.T) {
+	g := &stubGraph{edges: map[model.ID][]model.ID{"alice": {"bob"}}}
+
+	includes, err := ParseIncludes("associations:follows.entities")
+	require.NoError(t, err)
+
+	m := &embedder{associations: g, entities: failingEntities{}, limit: 10, tenantID: "anonymous"}
+	_, err = m.embed(context.Background(), &entity.Entity{ID: "alice", Type: "user"}, includes)
+	assert.True(t, errors.Is(errors.IO, err))
+}
@@ -2,23 +2,121 @@ package master
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/edgestore/edgestore/internal/server"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/limit"
+	"github.com/edgestore/edgestore/internal/model"
 	"github.com/gin-gonic/gin"
 )
 
 const TenantKey = "tenant"
 
-func NewTenantMiddleware() gin.HandlerFunc {
+// TenantHeader names the tenant of a request. With credentials, it must match their tenant.
+const TenantHeader = "Edgestore-Tenant"
+
+// Credential returns the bearer credential of the Authorization header, an API key or a JWT.
+func Credential(header string) string {
+	const scheme = "bearer "
+	if len(header) > len(scheme) && strings.EqualFold(header[:len(scheme)], scheme) {
+		return strings.TrimSpace(header[len(scheme):])
+	}
+
+	return ""
+}
+
+// NewTenantMiddleware authenticates requests and sets the tenant of their credential. With
+// allowHeader set, requests without credentials are trusted with the tenant of their
+// Edgestore-Tenant header.
+func NewTenantMiddleware(authn *auth.Authenticator, allowHeader bool) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		tenant := ctx.GetHeader("Edgestore-Tenant")
+		tenant := ctx.GetHeader(TenantHeader)
+		credential := Credential(ctx.GetHeader("Authorization"))
 
-		if tenant != "" {
+		if credential == "" && allowHeader && tenant != "" {
 			ctx.Set(TenantKey, tenant)
 			ctx.Next()
 			return
 		}
 
-		server.Abort(ctx, http.StatusUnauthorized, "Invalid Tenant ID. Make sure to provide a valid X-Edgestore-Tenant header.")
+		p, err := authn.Authenticate(ctx.Request.Context(), credential)
+		if err != nil {
+			AbortWithError(ctx, err)
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), p))
+		if tenant != "" && model.ID(tenant) != p.TenantID {
+			AbortWithError(ctx, errors.E(errors.Permission, errors.Code("tenant_mismatch"), "the credential does not grant access to the requested tenant"))
+			return
+		}
+
+		ctx.Set(TenantKey, string(p.TenantID))
+		ctx.Next()
+	}
+}
+
+// PermissionStatus returns the status of a Permission error: 401 when the request is not
+// authenticated, 403 when its principal lacks the permission.
+func PermissionStatus(ctx *gin.Context, err error) int {
+	if errors.Is(errors.Permission, err) && auth.FromContext(ctx.Request.Context()) != nil {
+		return http.StatusForbidden
+	}
+
+	return errors.KindOf(err).HTTPStatus()
+}
+
+// Authorize returns a middleware requiring the principal of requests to be granted the verb on
+// the resource of type typ, for the routes that are not authorized by the services.
+func (s *service) Authorize(resource string, verb string, typ string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenant := model.ID(ctx.GetString(TenantKey))
+		if err := s.policies.Authorize(ctx.Request.Context(), tenant, resource, verb, typ); err != nil {
+			s.AbortWithError(ctx, err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RouteGroup returns the rate limit group of the route path, relative to Prefix, of a request.
+func RouteGroup(method string, path string) string {
+	switch strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0] {
+	case "api-keys", "association-types", "export", "import", "namespaces", "policies", "usage", "webhooks":
+		return limit.GroupAdmin
+	case "check", "expand", "graphql", "query", "traverse":
+		return limit.GroupQuery
+	}
+
+	if method == http.MethodGet || method == http.MethodHead || strings.HasSuffix(path, "/batch-get") {
+		return limit.GroupRead
+	}
+
+	return limit.GroupWrite
+}
+
+// RateLimit returns a middleware taking a request from the bucket of the tenant for the route
+// group of each request. Limited requests are answered 429 with a Retry-After header; requests
+// are let through when the bucket store is unavailable.
+func (s *service) RateLimit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenant := model.ID(ctx.GetString(TenantKey))
+		group := RouteGroup(ctx.Request.Method, strings.TrimPrefix(ctx.FullPath(), Prefix))
+
+		wait, err := s.limits.Take(ctx.Request.Context(), tenant, group)
+		if errors.Is(errors.Exhausted, err) {
+			ctx.Header("Retry-After", strconv.Itoa(limit.RetryAfter(wait)))
+			s.AbortWithError(ctx, err)
+			return
+		}
+
+		if err != nil {
+			s.logger.Error(err)
+		}
+
+		ctx.Next()
 	}
 }
@@ -0,0 +1,159 @@
+package master
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/limit"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthenticator(t *testing.T) *auth.Authenticator {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	authn, err := auth.New(&auth.Config{Keys: auth.NewInMemory(), Logger: logger})
+	require.NoError(t, err)
+	return authn
+}
+
+func newTenantEngine(authn *auth.Authenticator, allowHeader bool) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+
+	engine := gin.New()
+	engine.Use(NewTenantMiddleware(authn, allowHeader))
+	engine.GET("/tenant", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(TenantKey))
+	})
+	engine.GET("/forbidden", func(ctx *gin.Context) {
+		newTestService().AbortWithError(ctx, errors.E(errors.Permission, "forbidden"))
+	})
+
+	return engine
+}
+
+func get(engine http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	authn := newTestAuthenticator(t)
+	key, err := authn.Keys().Create(context.Background(), "acme", "test", []string{auth.RoleAdmin})
+	require.NoError(t, err)
+
+	engine := newTenantEngine(authn, false)
+
+	w := get(engine, "/tenant", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	// The header alone is not trusted.
+	w = get(engine, "/tenant", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = get(engine, "/tenant", map[string]string{"Authorization": "Bearer esk_unknown_secret"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = get(engine, "/tenant", map[string]string{"Authorization": "Bearer " + key.Token})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = get(engine, "/tenant", map[string]string{"Authorization": "Bearer " + key.Token, TenantHeader: "other"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Permission errors of authenticated requests are forbidden.
+	w = get(engine, "/forbidden", map[string]string{"Authorization": "Bearer " + key.Token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestTenantMiddleware_InsecureTenantHeader(t *testing.T) {
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+
+	w := get(engine, "/tenant", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = get(engine, "/forbidden", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = get(engine, "/tenant", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthorize(t *testing.T) {
+	authn := newTestAuthenticator(t)
+	admin, err := authn.Keys().Create(context.Background(), "acme", "admin", []string{auth.RoleAdmin})
+	require.NoError(t, err)
+	reader, err := authn.Keys().Create(context.Background(), "acme", "reader", []string{auth.RoleReader})
+	require.NoError(t, err)
+
+	s := newTestService()
+	s.policies = auth.NewPolicies(auth.NewInMemory(), time.Minute)
+
+	engine := newTenantEngine(authn, true)
+	engine.GET("/policies", s.Authorize(auth.ResourceTenant, auth.VerbRead, "policies"), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	w := get(engine, "/policies", map[string]string{"Authorization": "Bearer " + admin.Token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get(engine, "/policies", map[string]string{"Authorization": "Bearer " + reader.Token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Requests trusted with their tenant header have no principal to authorize.
+	w = get(engine, "/policies", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRouteGroup(t *testing.T) {
+	assert.Equal(t, limit.GroupRead, RouteGroup(http.MethodGet, "/entities/:id"))
+	assert.Equal(t, limit.GroupWrite, RouteGroup(http.MethodPut, "/entities/:id"))
+	assert.Equal(t, limit.GroupRead, RouteGroup(http.MethodPost, "/entities/batch-get"))
+	assert.Equal(t, limit.GroupQuery, RouteGroup(http.MethodPost, "/traverse/path"))
+	assert.Equal(t, limit.GroupAdmin, RouteGroup(http.MethodGet, "/webhooks/:id/dead-letters"))
+	assert.Equal(t, limit.GroupAdmin, RouteGroup(http.MethodPost, "/import"))
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newTestService()
+	s.limits = limit.New(limit.NewInMemory(), &limit.Table{
+		Default: limit.Limits{Rates: map[string]limit.Rate{limit.GroupRead: {PerSecond: 0.5, Burst: 1}}},
+		Tenants: map[model.ID]limit.Limits{"other": {Rates: map[string]limit.Rate{limit.GroupRead: {}}}},
+	})
+
+	engine := newTenantEngine(newTestAuthenticator(t), true)
+	engine.GET("/entities/:id", s.RateLimit(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	w := get(engine, "/entities/1", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get(engine, "/entities/1", map[string]string{TenantHeader: "acme"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// Tenants overriding the rate with zero are unlimited.
+	for i := 0; i < 3; i++ {
+		w = get(engine, "/entities/1", map[string]string{TenantHeader: "other"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,159 @@
+package master
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxMultiGetIDs bounds the IDs of a multi-get.
+const MaxMultiGetIDs = 100
+
+// MultiGet is the form of the POST variant of multi-gets, for lists of IDs too long for a URL.
+type MultiGet struct {
+	IDs []model.ID `json:"ids" binding:"required"`
+}
+
+// MultiGetResult answers a multi-get with the items in the order of the requested IDs. The IDs
+// that do not exist have a null item, and are listed in NotFound; those the caller may not read
+// have a null item too, and are listed in Forbidden.
+type MultiGetResult[T any] struct {
+	Items     []T        `json:"items"`
+	NotFound  []model.ID `json:"not_found"`
+	Forbidden []model.ID `json:"forbidden"`
+}
+
+// projectMultiGet projects the items of res with f, when it is not nil.
+func projectMultiGet[T any](f model.Fields, res *MultiGetResult[T]) (interface{}, error) {
+	if f == nil {
+		return res, nil
+	}
+
+	items, err := projectItems(f, res.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultiGetResult[map[string]interface{}]{Items: items, NotFound: res.NotFound, Forbidden: res.Forbidden}, nil
+}
+
+func newMultiGetResult[T any](ids []model.ID, items []*T, forbidden []model.ID) *MultiGetResult[*T] {
+	res := &MultiGetResult[*T]{Items: items, NotFound: []model.ID{}, Forbidden: []model.ID{}}
+	denied := make(map[model.ID]bool, len(forbidden))
+	for _, id := range forbidden {
+		denied[id] = true
+		res.Forbidden = append(res.Forbidden, id)
+	}
+
+	for i, item := range items {
+		if item == nil && !denied[ids[i]] {
+			res.NotFound = append(res.NotFound, ids[i])
+		}
+	}
+
+	return res
+}
+
+// MultiGetIDs returns the IDs of a multi-get: the comma-separated, possibly repeated ids query
+// parameter of GET requests, or the MultiGet body of POST requests.
+func MultiGetIDs(ctx *gin.Context) ([]model.ID, error) {
+	var ids []model.ID
+	if ctx.Request.Method == http.MethodGet {
+		for _, param := range ctx.QueryArray("ids") {
+			for _, id := range strings.Split(param, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					ids = append(ids, model.ID(id))
+				}
+			}
+		}
+	} else {
+		var form MultiGet
+		if err := ctx.ShouldBind(&form); err != nil {
+			return nil, errors.E(errors.Invalid, err)
+		}
+
+		ids = form.IDs
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.E(errors.Invalid, "ids are required")
+	}
+
+	if len(ids) > MaxMultiGetIDs {
+		return nil, errors.E(errors.Invalid, fmt.Sprintf("at most %d ids can be fetched at once", MaxMultiGetIDs))
+	}
+
+	return ids, nil
+}
+
+func (s *service) GetEntitiesHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetEntitiesHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	ids, err := MultiGetIDs(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	fields, err := Fields(ctx, entity.Entity{})
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	entities, forbidden, err := s.entity.GetEntities(ctx, ids, model.ID(tenant))
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	res, err := projectMultiGet(fields, newMultiGetResult(ids, entities, forbidden))
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Internal, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (s *service) GetAssociationsHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetAssociationsHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	ids, err := MultiGetIDs(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	fields, err := Fields(ctx, association.Association{})
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	assocs, forbidden, err := s.association.GetAssociations(ctx, ids, model.ID(tenant))
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	res, err := projectMultiGet(fields, newMultiGetResult(ids, assocs, forbidden))
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, errors.Internal, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
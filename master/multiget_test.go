@@ -0,0 +1,50 @@
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGetIDs(t *testing.T) {
+	engine := gin.New()
+	handler := func(ctx *gin.Context) {
+		ids, err := MultiGetIDs(ctx)
+		if err != nil {
+			AbortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newMultiGetResult(ids, []*model.ID{&ids[0], nil, &ids[2], nil}, []model.ID{"d"}))
+	}
+	engine.GET("/entities", handler)
+	engine.POST("/entities/batch-get", handler)
+
+	// The items the caller may not read are null, listed apart from those not found.
+	w := get(engine, "/entities?ids=a,%20b,&ids=c,d", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items": ["a", null, "c", null], "not_found": ["b"], "forbidden": ["d"]}`, w.Body.String())
+
+	w = post(engine, "/entities/batch-get", `{"ids": ["a", "b", "c", "d"]}`, map[string]string{"Content-Type": "application/json"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res MultiGetResult[*model.ID]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, []model.ID{"b"}, res.NotFound)
+
+	assert.Equal(t, http.StatusBadRequest, get(engine, "/entities?ids=", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, post(engine, "/entities/batch-get", `{}`, nil).Code)
+
+	ids := make([]string, MaxMultiGetIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+	assert.Equal(t, http.StatusBadRequest, get(engine, "/entities?ids="+strings.Join(ids, ","), nil).Code)
+}
@@ -0,0 +1,482 @@
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/feed"
+	"github.com/edgestore/edgestore/internal/idempotency"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
+	"github.com/edgestore/edgestore/internal/server"
+	"github.com/edgestore/edgestore/internal/webhook"
+	"github.com/edgestore/edgestore/permission"
+	"github.com/edgestore/edgestore/version"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIPath serves the OpenAPI specification of the REST API.
+var OpenAPIPath = path.Join(Prefix, "openapi.json")
+
+// param is a query or header parameter of an endpoint. Path parameters are derived from the path.
+type param struct {
+	name        string
+	in          string
+	schema      string
+	description string
+}
+
+var (
+	waitParams = []param{
+		{"wait", "query", "boolean", "Respond with the written resource once the command is applied."},
+		{"Prefer", "header", "string", "return=representation has the same effect as wait=true."},
+	}
+
+	pageParams = []param{
+		{"per_page", "query", "integer", "Maximum number of items of the page, at most 100."},
+		{"cursor", "query", "string", "next_cursor of the previous page."},
+	}
+
+	fieldsParam = param{"fields", "query", "string", "Comma-separated attributes to return, with nested data paths such as data.address.city."}
+)
+
+// reply is a response of an endpoint. A nil body has no content.
+type reply struct {
+	description string
+	body        interface{}
+	headers     []string
+}
+
+// accepted acknowledges an asynchronous write.
+var accepted = reply{
+	description: "The command is queued, or still pending after waiting.",
+	headers:     []string{"Location", "Operation-Location"},
+}
+
+// endpoint documents a route of HTTPHandler.
+type endpoint struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	params  []param
+
+	// public endpoints do not require credentials.
+	public bool
+
+	body      interface{}
+	responses map[int]reply
+}
+
+// gin paths name parameters ":id", OpenAPI paths "{id}".
+func openAPIPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func apiPath(p string) string {
+	return path.Join(Prefix, p)
+}
+
+// endpoints lists every route of HTTPHandler, see TestOpenAPI_Routes.
+var endpoints = []endpoint{
+	{method: http.MethodGet, path: "/", tag: "meta", summary: "Describe the service.", public: true,
+		responses: map[int]reply{http.StatusOK: {"Service banner.", map[string]string{}, nil}}},
+	{method: http.MethodGet, path: OpenAPIPath, tag: "meta", summary: "Get this specification.", public: true,
+		responses: map[int]reply{http.StatusOK: {"OpenAPI 3 document.", map[string]interface{}{}, nil}}},
+	{method: http.MethodGet, path: HealthPath, tag: "meta", summary: "Check that the service is alive.", public: true,
+		responses: map[int]reply{http.StatusOK: {"The service serves requests.", map[string]string{}, nil}}},
+	{method: http.MethodGet, path: ReadyPath, tag: "meta", summary: "Check that Postgres, Redis and the worker queues are available.", public: true,
+		responses: map[int]reply{
+			http.StatusOK:                 {"Every check passed.", Readiness{}, nil},
+			http.StatusServiceUnavailable: {"A check failed.", Readiness{}, nil},
+		}},
+
+	{method: http.MethodGet, path: apiPath("/api-keys"), tag: "api-keys", summary: "List the API keys of the tenant.",
+		responses: map[int]reply{http.StatusOK: {"The API keys, without tokens.", model.Page[*auth.Key]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/api-keys/:id"), tag: "api-keys", summary: "Get an API key.",
+		responses: map[int]reply{http.StatusOK: {"The API key, without token.", auth.Key{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/api-keys"), tag: "api-keys", summary: "Issue an API key.",
+		body:      CreateAPIKey{},
+		responses: map[int]reply{http.StatusCreated: {"The API key and its token.", auth.Key{}, []string{"Location"}}}},
+	{method: http.MethodPost, path: apiPath("/api-keys/:id/rotate"), tag: "api-keys", summary: "Replace the secret of an API key.",
+		responses: map[int]reply{http.StatusOK: {"The API key and its new token.", auth.Key{}, nil}}},
+	{method: http.MethodDelete, path: apiPath("/api-keys/:id"), tag: "api-keys", summary: "Revoke an API key.",
+		responses: map[int]reply{http.StatusNoContent: {description: "Revoked."}}},
+
+	{method: http.MethodGet, path: apiPath("/association-types/:atype"), tag: "association-types", summary: "Get an association type.",
+		responses: map[int]reply{http.StatusOK: {"The association type.", association.Definition{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/association-types"), tag: "association-types", summary: "Define an association type.",
+		body:      association.InsertDefinition{},
+		responses: map[int]reply{http.StatusCreated: {"The association type.", association.Definition{}, []string{"Location"}}}},
+	{method: http.MethodPut, path: apiPath("/association-types/:atype"), tag: "association-types", summary: "Update an association type.",
+		body:      association.UpdateDefinition{},
+		responses: map[int]reply{http.StatusOK: {"The association type.", association.Definition{}, nil}}},
+	{method: http.MethodDelete, path: apiPath("/association-types/:atype"), tag: "association-types", summary: "Delete an association type.",
+		responses: map[int]reply{http.StatusNoContent: {description: "Deleted."}}},
+
+	{method: http.MethodGet, path: apiPath("/associations"), tag: "associations", summary: "Get several associations.",
+		params: []param{
+			{"ids", "query", "string", "Comma-separated IDs of the associations."},
+			fieldsParam,
+		},
+		responses: map[int]reply{http.StatusOK: {"The associations, in the order of the IDs.", MultiGetResult[*association.Association]{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/associations/batch-get"), tag: "associations", summary: "Get several associations, for lists of IDs too long for a URL.",
+		params: []param{fieldsParam}, body: MultiGet{},
+		responses: map[int]reply{http.StatusOK: {"The associations, in the order of the IDs.", MultiGetResult[*association.Association]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/associations/:id"), tag: "associations", summary: "Get an association.",
+		params: []param{
+			fieldsParam,
+			{"If-None-Match", "header", "string", "ETag of a cached representation."},
+			{"If-Modified-Since", "header", "string", "Last-Modified of a cached representation."},
+		},
+		responses: map[int]reply{
+			http.StatusOK:          {"The association.", association.Association{}, []string{"ETag", "Last-Modified"}},
+			http.StatusNotModified: {description: "The cached representation is current."},
+		}},
+	{method: http.MethodPost, path: apiPath("/associations"), tag: "associations", summary: "Create an association.",
+		params: waitParams, body: association.InsertAssociation{},
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusCreated:  {"The association, when waiting.", association.Association{}, []string{"Location"}},
+		}},
+	{method: http.MethodPut, path: apiPath("/associations/:id"), tag: "associations", summary: "Update the data of an association.",
+		params: waitParams, body: association.UpdateAssociation{},
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusOK:       {"The association, when waiting.", association.Association{}, nil},
+		}},
+	{method: http.MethodDelete, path: apiPath("/associations/:id"), tag: "associations", summary: "Delete an association.",
+		params: waitParams,
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusOK:       {"The deleted association, when waiting.", association.Association{}, nil},
+		}},
+
+	{method: http.MethodGet, path: apiPath("/changes"), tag: "changes", summary: "Stream the changes of the tenant as Server-Sent Events.",
+		params: []param{
+			{"resource", "query", "string", "Only changes of entities or associations."},
+			{"otype", "query", "string", "Only changes of entities of this type."},
+			{"atype", "query", "string", "Only changes of associations of this type."},
+			{"id", "query", "string", "Only changes of this resource."},
+			{"last_event_id", "query", "string", "Resume after this position."},
+			{"Last-Event-ID", "header", "string", "Resume after this position."},
+		},
+		responses: map[int]reply{http.StatusOK: {"text/event-stream of changes.", feed.Change{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/changes/ws"), tag: "changes", summary: "Stream the changes of the tenant over a WebSocket.",
+		params:    []param{{"last_event_id", "query", "string", "Resume after this position."}},
+		responses: map[int]reply{http.StatusSwitchingProtocols: {description: "JSON messages of changes."}}},
+
+	{method: http.MethodGet, path: apiPath("/export"), tag: "bulk", summary: "Export the entities and then the associations of the tenant.",
+		params: []param{
+			{"history", "query", "boolean", "Add the events of every resource, deleted ones included."},
+			{"resources", "query", "string", "Comma-separated entities or associations, both by default."},
+		},
+		responses: map[int]reply{http.StatusOK: {"application/x-ndjson rows, ended by a row with an error when the export fails midway.", Row{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/import"), tag: "bulk", summary: "Import application/x-ndjson rows of entities and associations, such as exported ones, from any tenant.",
+		body:      Row{},
+		responses: map[int]reply{http.StatusOK: {"The outcome of the import and the rows that failed.", ImportResult{}, nil}}},
+
+	{method: http.MethodGet, path: apiPath("/entities"), tag: "entities", summary: "Get several entities.",
+		params: []param{
+			{"ids", "query", "string", "Comma-separated IDs of the entities."},
+			fieldsParam,
+		},
+		responses: map[int]reply{http.StatusOK: {"The entities, in the order of the IDs.", MultiGetResult[*entity.Entity]{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/entities/batch-get"), tag: "entities", summary: "Get several entities, for lists of IDs too long for a URL.",
+		params: []param{fieldsParam}, body: MultiGet{},
+		responses: map[int]reply{http.StatusOK: {"The entities, in the order of the IDs.", MultiGetResult[*entity.Entity]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/entities/:id"), tag: "entities", summary: "Get an entity.",
+		params: []param{
+			{"data", "query", "boolean", "Respond with the data of the entity only."},
+			fieldsParam,
+			{"include", "query", "string", "Comma-separated outgoing associations to embed, such as associations:follows.entities to embed their targets too. At most 8 association types, and targets the caller may not read are left out."},
+			{"include_limit", "query", "integer", "Maximum number of associations embedded per type and entity."},
+			{"If-None-Match", "header", "string", "ETag of a cached representation."},
+			{"If-Modified-Since", "header", "string", "Last-Modified of a cached representation."},
+		},
+		responses: map[int]reply{
+			http.StatusOK:          {"The entity, with its included associations.", EmbeddedEntity{}, []string{"ETag", "Last-Modified"}},
+			http.StatusNotModified: {description: "The cached representation is current."},
+		}},
+	{method: http.MethodGet, path: apiPath("/entities/:id/associations"), tag: "entities", summary: "List the associations of an entity.",
+		params: append([]param{
+			{"direction", "query", "string", "out (default) or in."},
+			{"atype", "query", "string", "Only associations of this type."},
+			fieldsParam,
+		}, pageParams...),
+		responses: map[int]reply{http.StatusOK: {"A page of associations.", model.Page[*association.Association]{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/entities"), tag: "entities", summary: "Create an entity.",
+		params: waitParams, body: entity.InsertEntity{},
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusCreated:  {"The entity, when waiting.", entity.Entity{}, []string{"Location"}},
+		}},
+	{method: http.MethodPut, path: apiPath("/entities/:id"), tag: "entities", summary: "Update the data of an entity.",
+		params: waitParams, body: entity.UpdateEntity{},
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusOK:       {"The entity, when waiting.", entity.Entity{}, nil},
+		}},
+	{method: http.MethodDelete, path: apiPath("/entities/:id"), tag: "entities", summary: "Delete an entity.",
+		params: waitParams,
+		responses: map[int]reply{
+			http.StatusAccepted: accepted,
+			http.StatusOK:       {"The deleted entity, when waiting.", entity.Entity{}, nil},
+		}},
+
+	{method: http.MethodPost, path: apiPath("/check"), tag: "permissions", summary: "Check whether a subject has a relation on an object.",
+		body:      permission.Check{},
+		responses: map[int]reply{http.StatusOK: {"The decision and its consistency token.", permission.CheckResult{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/expand"), tag: "permissions", summary: "Expand the subjects of a relation on an object.",
+		body:      permission.Expand{},
+		responses: map[int]reply{http.StatusOK: {"The evaluation tree and its consistency token.", permission.ExpandResult{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/namespaces"), tag: "permissions", summary: "List the namespaces of the tenant.",
+		responses: map[int]reply{http.StatusOK: {"The namespaces.", model.Page[*permission.Namespace]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/namespaces/:otype"), tag: "permissions", summary: "Get the rewrite rules of the relations of an entity type.",
+		responses: map[int]reply{http.StatusOK: {"The namespace.", permission.Namespace{}, nil}}},
+	{method: http.MethodPut, path: apiPath("/namespaces/:otype"), tag: "permissions", summary: "Create or replace the rewrite rules of the relations of an entity type.",
+		body:      PutNamespace{},
+		responses: map[int]reply{http.StatusOK: {"The namespace.", permission.Namespace{}, nil}}},
+	{method: http.MethodDelete, path: apiPath("/namespaces/:otype"), tag: "permissions", summary: "Delete the rewrite rules of an entity type.",
+		responses: map[int]reply{http.StatusNoContent: {description: "Deleted."}}},
+
+	{method: http.MethodPost, path: apiPath("/graphql"), tag: "graph", summary: "Run a GraphQL request.",
+		body:      GraphQLRequest{},
+		responses: map[int]reply{http.StatusOK: {"GraphQL response with data and errors.", map[string]interface{}{}, nil}}},
+
+	{method: http.MethodPost, path: apiPath("/guid"), tag: "meta", summary: "Generate a globally unique ID.",
+		responses: map[int]reply{http.StatusOK: {"The ID.", struct {
+			ID        string `json:"id"`
+			Machine   int    `json:"machine"`
+			CreatedAt string `json:"created_at"`
+		}{}, []string{"Location"}}}},
+
+	{method: http.MethodGet, path: apiPath("/operations/:id"), tag: "operations", summary: "Get the state of an asynchronous write.",
+		responses: map[int]reply{http.StatusOK: {"The operation.", operation.Operation{}, nil}}},
+
+	{method: http.MethodGet, path: apiPath("/policies"), tag: "policies", summary: "List the policies of the tenant.",
+		responses: map[int]reply{http.StatusOK: {"The policies.", model.Page[*auth.Policy]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/policies/:role"), tag: "policies", summary: "Get the policy of a role.",
+		responses: map[int]reply{http.StatusOK: {"The policy.", auth.Policy{}, nil}}},
+	{method: http.MethodPut, path: apiPath("/policies/:role"), tag: "policies", summary: "Create or replace the policy of a role.",
+		body:      PutPolicy{},
+		responses: map[int]reply{http.StatusOK: {"The policy.", auth.Policy{}, nil}}},
+	{method: http.MethodDelete, path: apiPath("/policies/:role"), tag: "policies", summary: "Delete the policy of a role.",
+		responses: map[int]reply{http.StatusNoContent: {description: "Deleted."}}},
+
+	{method: http.MethodPost, path: apiPath("/query"), tag: "graph", summary: "Run a statement of the query language.",
+		params: pageParams, body: graph.Query{},
+		responses: map[int]reply{http.StatusOK: {"A page of rows.", graph.QueryResult{}, nil}}},
+
+	{method: http.MethodPost, path: apiPath("/traverse"), tag: "graph", summary: "Traverse the graph from an entity.",
+		body:      graph.Traverse{},
+		responses: map[int]reply{http.StatusOK: {"The visited entities and associations.", graph.Result{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/traverse/path"), tag: "graph", summary: "Find the shortest path between two entities.",
+		body:      graph.ShortestPath{},
+		responses: map[int]reply{http.StatusOK: {"The path.", graph.Path{}, nil}}},
+
+	{method: http.MethodGet, path: apiPath("/usage"), tag: "usage", summary: "Get the limits of the tenant and the storage it uses.",
+		responses: map[int]reply{http.StatusOK: {"The limits and usage.", TenantUsage{}, nil}}},
+
+	{method: http.MethodGet, path: apiPath("/webhooks"), tag: "webhooks", summary: "List the webhooks of the tenant.",
+		responses: map[int]reply{http.StatusOK: {"The webhooks, without secrets.", model.Page[*webhook.Webhook]{}, nil}}},
+	{method: http.MethodGet, path: apiPath("/webhooks/:id"), tag: "webhooks", summary: "Get a webhook.",
+		responses: map[int]reply{http.StatusOK: {"The webhook, without secret.", webhook.Webhook{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/webhooks"), tag: "webhooks", summary: "Register a webhook. Its URL must resolve to public addresses.",
+		body:      webhook.Webhook{},
+		responses: map[int]reply{http.StatusCreated: {"The webhook and its secret.", webhook.Webhook{}, []string{"Location"}}}},
+	{method: http.MethodPut, path: apiPath("/webhooks/:id"), tag: "webhooks", summary: "Update a webhook.",
+		body:      webhook.Webhook{},
+		responses: map[int]reply{http.StatusOK: {"The webhook, without secret.", webhook.Webhook{}, nil}}},
+	{method: http.MethodDelete, path: apiPath("/webhooks/:id"), tag: "webhooks", summary: "Delete a webhook.",
+		responses: map[int]reply{http.StatusNoContent: {description: "Deleted."}}},
+	{method: http.MethodGet, path: apiPath("/webhooks/:id/dead-letters"), tag: "webhooks", summary: "List the deliveries that exhausted their attempts.",
+		params:    pageParams,
+		responses: map[int]reply{http.StatusOK: {"A page of deliveries.", model.Page[*webhook.Delivery]{}, nil}}},
+	{method: http.MethodPost, path: apiPath("/webhooks/:id/dead-letters/:delivery/replay"), tag: "webhooks", summary: "Queue a dead delivery again.",
+		responses: map[int]reply{http.StatusAccepted: {"The queued delivery.", webhook.Delivery{}, nil}}},
+}
+
+// NewOpenAPI returns the OpenAPI 3 document of endpoints.
+func NewOpenAPI() map[string]interface{} {
+	components := schemas{}
+	errorSchema := components.of(reflect.TypeOf(server.Problem{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, e := range endpoints {
+		var parameters []interface{}
+		for _, segment := range strings.Split(e.path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     segment[1:],
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+
+		if !e.public {
+			parameters = append(parameters, map[string]interface{}{"$ref": "#/components/parameters/Tenant"})
+			if IdempotentRoute(e.method, strings.TrimPrefix(e.path, Prefix)) {
+				parameters = append(parameters, map[string]interface{}{"$ref": "#/components/parameters/IdempotencyKey"})
+			}
+		}
+
+		for _, p := range e.params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"schema":      map[string]interface{}{"type": p.schema},
+			})
+		}
+
+		responses := map[string]interface{}{
+			"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+		}
+
+		for code, r := range e.responses {
+			res := map[string]interface{}{"description": r.description}
+			if r.body != nil {
+				res["content"] = map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": components.of(reflect.TypeOf(r.body))},
+				}
+			}
+
+			if len(r.headers) > 0 {
+				headers := map[string]interface{}{}
+				for _, h := range r.headers {
+					headers[h] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+				}
+				res["headers"] = headers
+			}
+
+			responses[fmt.Sprint(code)] = res
+		}
+
+		operation := map[string]interface{}{
+			"operationId": operationID(e),
+			"summary":     e.summary,
+			"tags":        []string{e.tag},
+			"responses":   responses,
+		}
+
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if e.public {
+			operation["security"] = []interface{}{}
+		}
+
+		if e.body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": components.of(reflect.TypeOf(e.body))},
+				},
+			}
+		}
+
+		p := openAPIPath(e.path)
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(e.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Edgestore",
+			"description": "Distributed data store of entities and the associations between them.",
+			"version":     version.Version,
+		},
+		"paths":    paths,
+		"security": []interface{}{map[string]interface{}{"bearer": []string{}}},
+		"components": map[string]interface{}{
+			"schemas": components,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An API key of the tenant (esk_...) or a JWT naming the tenant in its tenant claim.",
+				},
+			},
+			"parameters": map[string]interface{}{
+				"Tenant": map[string]interface{}{
+					"name":        TenantHeader,
+					"in":          "header",
+					"description": "Tenant of the request, which must match the tenant of the credential.",
+					"schema":      map[string]interface{}{"type": "string"},
+				},
+				"IdempotencyKey": map[string]interface{}{
+					"name":        IdempotencyKeyHeader,
+					"in":          "header",
+					"description": "Unique key making the request safe to retry: retries are answered the response of the first request, marked by an Idempotent-Replayed header.",
+					"schema":      map[string]interface{}{"type": "string", "maxLength": idempotency.MaxKeyLength},
+				},
+			},
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error: 400 invalid request, 401 missing or invalid credential, 403 forbidden, 404 not found, 409 conflict, 429 rate limit or quota exceeded, 500 internal error, 503 temporarily unavailable. Bodies are RFC 7807 problem details with the kind and a stable code of the error.",
+					"content": map[string]interface{}{
+						server.ProblemContentType: map[string]interface{}{"schema": errorSchema},
+					},
+					"headers": map[string]interface{}{
+						"Retry-After": map[string]interface{}{
+							"description": "Seconds to wait before retrying a rate limited request.",
+							"schema":      map[string]interface{}{"type": "integer"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// operationID names an endpoint after its method and path, e.g. get_entities_id_associations.
+func operationID(e endpoint) string {
+	var words []string
+	for _, segment := range strings.Split(strings.TrimPrefix(e.path, Prefix), "/") {
+		segment = strings.Trim(segment, ":*")
+		segment = strings.NewReplacer("-", "_", ".", "_").Replace(segment)
+		if segment != "" {
+			words = append(words, segment)
+		}
+	}
+
+	if len(words) == 0 {
+		words = []string{"root"}
+	}
+
+	return strings.ToLower(e.method) + "_" + strings.Join(words, "_")
+}
+
+// OpenAPIHandler serves the OpenAPI specification returned by NewOpenAPI.
+func (s *service) OpenAPIHandler() gin.HandlerFunc {
+	spec, err := json.Marshal(NewOpenAPI())
+	if err != nil {
+		panic(err)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
@@ -0,0 +1,80 @@
+package master
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService() *service {
+	gin.SetMode(gin.ReleaseMode)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return &service{logger: logger}
+}
+
+// TestOpenAPI_Routes fails when a route of HTTPHandler is missing from the specification, or the
+// specification documents a route that does not exist.
+func TestOpenAPI_Routes(t *testing.T) {
+	engine := newTestService().HTTPHandler().(*gin.Engine)
+	paths := NewOpenAPI()["paths"].(map[string]map[string]interface{})
+
+	registered := map[string]bool{}
+	for _, r := range engine.Routes() {
+		p := openAPIPath(r.Path)
+		method := strings.ToLower(r.Method)
+		registered[method+" "+p] = true
+
+		assert.Contains(t, paths[p], method, "%s %s is not documented", r.Method, r.Path)
+	}
+
+	for p, operations := range paths {
+		for method := range operations {
+			assert.True(t, registered[method+" "+p], "%s %s is documented but not routed", method, p)
+		}
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc := NewOpenAPI()
+	schemas := doc["components"].(map[string]interface{})["schemas"].(schemas)
+
+	for _, name := range []string{"InsertEntity", "UpdateAssociation", "Entity", "AssociationPage", "Problem"} {
+		assert.Contains(t, schemas, name)
+	}
+
+	insert := schemas["InsertEntity"].(map[string]interface{})
+	assert.Equal(t, []string{"otype"}, insert["required"])
+	assert.Contains(t, insert["properties"], "data")
+	assert.Contains(t, insert["properties"], "tenant_id")
+
+	// Every reference resolves.
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	for _, ref := range strings.Split(string(b), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		assert.NotNil(t, schemas[name], "undefined schema %s", name)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	engine := newTestService().HTTPHandler()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Contains(t, doc["paths"], "/api/v1/entities/{id}")
+}
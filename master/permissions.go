@@ -0,0 +1,122 @@
+package master
+
+import (
+	"net/http"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/permission"
+	"github.com/gin-gonic/gin"
+)
+
+// PutNamespace is the form of PutNamespaceHandler.
+type PutNamespace struct {
+	Relations map[string]*permission.Rewrite `json:"relations" binding:"required"`
+}
+
+// CheckHandler answers whether a subject has a relation on an object.
+func (s *service) CheckHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.CheckHandler"
+
+	var form permission.Check
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	form.TenantID = model.ID(ctx.GetString(TenantKey))
+
+	if res, err := s.permissions.Check(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+// ExpandHandler returns the tree of the subjects of a relation on an object.
+func (s *service) ExpandHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ExpandHandler"
+
+	var form permission.Expand
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	form.TenantID = model.ID(ctx.GetString(TenantKey))
+
+	if res, err := s.permissions.Expand(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+func (s *service) ListNamespacesHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ListNamespacesHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if namespaces, err := s.permissions.ListNamespaces(ctx, model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		// Tenants have few namespaces, listed on a single page.
+		ctx.JSON(http.StatusOK, model.NewPage(namespaces, nil))
+	}
+}
+
+func (s *service) GetNamespaceHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetNamespaceHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if n, err := s.permissions.GetNamespace(ctx, ctx.Param("otype"), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, n)
+	}
+}
+
+// PutNamespaceHandler creates or replaces the rewrite rules of the relations of an entity type.
+func (s *service) PutNamespaceHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.PutNamespaceHandler"
+
+	var form PutNamespace
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	n := &permission.Namespace{
+		TenantID:  model.ID(ctx.GetString(TenantKey)),
+		Type:      ctx.Param("otype"),
+		Relations: form.Relations,
+	}
+
+	if n, err := s.permissions.PutNamespace(ctx, n); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, n)
+	}
+}
+
+func (s *service) DeleteNamespaceHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.DeleteNamespaceHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if err := s.permissions.DeleteNamespace(ctx, ctx.Param("otype"), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,86 @@
+package master
+
+import (
+	"net/http"
+
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// PutPolicy is the form of PutPolicyHandler.
+type PutPolicy struct {
+	// Scopes granted by the policy, "<resource>:<verb>:<type>" such as entities:read:invoice.
+	Scopes []string `json:"scopes" binding:"required"`
+
+	// Subjects are granted the scopes without holding the role, API key IDs or JWT subjects.
+	Subjects []string `json:"subjects"`
+}
+
+func (s *service) ListPoliciesHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ListPoliciesHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if policies, err := s.policies.List(ctx, model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		// Tenants have few policies, listed on a single page.
+		ctx.JSON(http.StatusOK, model.NewPage(policies, nil))
+	}
+}
+
+func (s *service) GetPolicyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetPolicyHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if p, err := s.policies.Get(ctx, model.ID(tenant), ctx.Param("role")); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, p)
+	}
+}
+
+// PutPolicyHandler creates or replaces the policy of a role.
+func (s *service) PutPolicyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.PutPolicyHandler"
+
+	var form PutPolicy
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+	p := &auth.Policy{
+		TenantID: model.ID(tenant),
+		Role:     ctx.Param("role"),
+		Scopes:   form.Scopes,
+		Subjects: form.Subjects,
+	}
+
+	if p, err := s.policies.Put(ctx.Request.Context(), p); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, p)
+	}
+}
+
+func (s *service) DeletePolicyHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.DeletePolicyHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if err := s.policies.Delete(ctx, model.ID(tenant), ctx.Param("role")); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
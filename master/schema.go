@@ -0,0 +1,127 @@
+package master
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas collects the OpenAPI schemas of named struct types, derived from their JSON encoding.
+type schemas map[string]interface{}
+
+// schemaName returns the component name of t. Instances of generic types are named after their
+// type argument, e.g. EntityPage for model.Page[*entity.Entity].
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.Index(name, "["); i >= 0 {
+		arg := strings.TrimSuffix(name[i+1:], "]")
+		arg = arg[strings.LastIndexAny(arg, "./*")+1:]
+		name = arg + name[:i]
+	}
+
+	return name
+}
+
+// of returns the schema of t, a reference for named struct types.
+func (c schemas) of(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": c.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": c.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return c.object(t)
+		}
+
+		name := schemaName(t)
+		if _, ok := c[name]; !ok {
+			// Registered before the fields are visited, for recursive types.
+			c[name] = nil
+			c[name] = c.object(t)
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	// Interfaces, such as events, hold any value.
+	return map[string]interface{}{}
+}
+
+// object returns the schema of the JSON object encoding the struct type t.
+func (c schemas) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	c.fields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func (c schemas) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			// Promoted fields of embedded structs.
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				c.fields(ft, properties, required)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = c.of(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
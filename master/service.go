@@ -2,18 +2,31 @@ package master
 
 import (
 	"context"
+	"crypto/rand"
 	"net/http"
 	"time"
 
 	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/edgestorepb"
 	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/graph"
+	"github.com/edgestore/edgestore/internal/auth"
 	"github.com/edgestore/edgestore/internal/eventstore"
 	"github.com/edgestore/edgestore/internal/eventstore/pgstore"
+	"github.com/edgestore/edgestore/internal/feed"
 	"github.com/edgestore/edgestore/internal/guid"
+	"github.com/edgestore/edgestore/internal/idempotency"
+	"github.com/edgestore/edgestore/internal/limit"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/operation"
 	"github.com/edgestore/edgestore/internal/server"
+	"github.com/edgestore/edgestore/internal/webhook"
+	"github.com/edgestore/edgestore/permission"
 	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 // CacheKeyPrefix is used to define caching keys.
@@ -23,13 +36,27 @@ type Service interface{}
 
 type service struct {
 	association *association.Service
+	auth        *auth.Authenticator
 	cache       *redis.Client
+	changes     *feed.Hub
 	cfg         Config
+	cursors     *model.CursorSigner
 	entity      *entity.Service
+	graph       *graph.Service
 	guid        *guid.Generator
+	idempotency idempotency.Store
+	limits      *limit.Limiter
 	logger      logrus.FieldLogger
+	operations  *operation.Tracker
+	permissions *permission.Service
+	policies    *auth.Policies
+	store       eventstore.Store
+	webhooks    *webhook.Dispatcher
 
-	run func() error
+	// ctx is canceled on Shutdown to stop background work.
+	ctx  context.Context
+	run  func() error
+	stop context.CancelFunc
 }
 
 func New(cfg Config) *service {
@@ -50,38 +77,165 @@ func New(cfg Config) *service {
 
 	cache := redis.NewClient(cfg.Cache)
 
+	// Operations
+	retention := cfg.OperationRetention
+	if retention == 0 {
+		retention = operation.DefaultRetention
+	}
+	operations := operation.NewTracker(operation.NewRedis(cache, CacheKeyPrefix, retention), logger)
+
+	// Idempotency Keys
+	keyRetention := cfg.IdempotencyRetention
+	if keyRetention == 0 {
+		keyRetention = idempotency.DefaultRetention
+	}
+
+	// Change Feed
+	changes := feed.NewHub(feed.DefaultHistorySize, feed.DefaultClientBuffer, logger)
+
+	// Webhooks
+	webhooks := webhook.NewDispatcher(&webhook.Config{
+		Store:  webhook.NewRedis(cache, CacheKeyPrefix),
+		Logger: logger,
+	})
+	publish := newPublisher(changes, webhooks, logger)
+
+	// Authorization
+	authStore := auth.NewRedis(cache, CacheKeyPrefix)
+	policies := auth.NewPolicies(authStore, cfg.Auth.PolicyTTL)
+
+	// Limits
+	var limitStore limit.Store = limit.NewInMemory()
+	if cfg.Limits.Shared {
+		limitStore = limit.NewRedis(cache, CacheKeyPrefix)
+	}
+	limits := limit.New(limitStore, cfg.Limits.Table)
+
+	// Usage is only tracked when quotas may apply.
+	var quotas limit.Quotas
+	if cfg.Limits.Table != nil {
+		quotas = limits
+	}
+
 	// Data Store Service
-	assocSvc := association.New(&association.Config{
+	entitySvc := entity.New(&entity.Config{
+		Authorizer:     policies,
 		Cache:          cache,
 		CacheKeyPrefix: CacheKeyPrefix,
+		Observers:      []eventstore.Observer{newEntityObserver(publish)},
+		Operations:     operations,
+		Quotas:         quotas,
 		Store:          store,
 		Logger:         logger,
 	})
 
-	entitySvc := entity.New(&entity.Config{
+	assocSvc := association.New(&association.Config{
+		Authorizer:     policies,
 		Cache:          cache,
 		CacheKeyPrefix: CacheKeyPrefix,
+		Entities:       entitySvc,
+		Observers:      []eventstore.Observer{newAssociationObserver(publish)},
+		Operations:     operations,
+		Quotas:         quotas,
 		Store:          store,
 		Logger:         logger,
 	})
 
+	graphSvc := graph.New(&graph.Config{
+		Associations: assocSvc,
+		Entities:     entitySvc,
+		Logger:       logger,
+	})
+
+	// Pagination cursors and consistency tokens
+	secret := []byte(cfg.CursorSecret)
+	if len(secret) == 0 {
+		logger.Warn("cursor secret is empty: cursors and consistency tokens are signed with a random secret, rejected by other masters and after restarts")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+
+	permissionSvc := permission.New(&permission.Config{
+		Associations: assocSvc,
+		Entities:     entitySvc,
+		Logger:       logger,
+		Store:        permission.NewRedis(cache, CacheKeyPrefix),
+		TokenSecret:  secret,
+	})
+
 	guidSvc := guid.New(guid.Settings{
 		StartTime: time.Now(),
 		MachineID: func() (uint16, error) { return cfg.MachineID, nil },
 	})
 
+	// Authentication
+	authn, err := auth.New(&auth.Config{
+		Keys:        authStore,
+		Logger:      logger,
+		JWKS:        cfg.Auth.JWKS,
+		Issuer:      cfg.Auth.Issuer,
+		Audience:    cfg.Auth.Audience,
+		TenantClaim: cfg.Auth.TenantClaim,
+		RolesClaim:  cfg.Auth.RolesClaim,
+	})
+	if err != nil {
+		logger.Fatalf("unable to configure authentication: %v", err)
+	}
+
+	if cfg.Auth.InsecureTenantHeader {
+		logger.Warn("requests without credentials are trusted with the tenant of their Edgestore-Tenant header")
+	}
+
 	// Main Service
 	svc := &service{
 		association: assocSvc,
+		auth:        authn,
 		cache:       cache,
+		changes:     changes,
 		cfg:         cfg,
+		cursors:     model.NewCursorSigner(secret),
 		entity:      entitySvc,
+		graph:       graphSvc,
 		guid:        guidSvc,
+		idempotency: idempotency.NewRedis(cache, CacheKeyPrefix, keyRetention),
+		limits:      limits,
 		logger:      logger.WithField("component", "API"),
+		operations:  operations,
+		permissions: permissionSvc,
+		policies:    policies,
+		store:       store,
+		webhooks:    webhooks,
+	}
+	svc.ctx, svc.stop = context.WithCancel(context.Background())
+
+	// Usage survives restarts and losses of its store.
+	if quotas != nil {
+		limits.Backfill(svc.countUsage)
 	}
 
 	srv := server.New(cfg.Server, logger)
 	srv.HTTPServer = server.NewHTTPServer(cfg.Server, svc.HTTPHandler())
+	if cfg.Server.AdminPort != 0 {
+		srv.AdminServer = server.NewAdminServer(cfg.Server, svc.AdminHandler())
+	}
+	srv.GRPCServer = server.NewGRPCServer(
+		&grpcService{service: svc},
+		&edgestorepb.Edgestore_ServiceDesc,
+		grpc_middleware.ChainUnaryServer(
+			server.RequestIDUnaryInterceptor(),
+			server.LoggerUnaryInterceptor(svc.logger),
+			NewTenantUnaryInterceptor(authn, cfg.Auth.InsecureTenantHeader),
+		),
+		[]grpc.ServerOption{
+			grpc.ChainStreamInterceptor(
+				server.RequestIDStreamInterceptor(),
+				server.LoggerStreamInterceptor(svc.logger),
+				NewTenantStreamInterceptor(authn, cfg.Auth.InsecureTenantHeader),
+			),
+		},
+	)
 	svc.run = srv.Run
 
 	return svc
@@ -98,12 +252,16 @@ func (s *service) Run() error {
 		}
 	}
 
+	go s.webhooks.Run(s.ctx)
+
 	return s.run()
 }
 
 func (s *service) Shutdown() {
 	s.logger.Info("Edgestore: Stopping Master")
 
+	s.stop()
+
 	if s.cache != nil {
 		if _, err := s.cache.Shutdown(context.Background()).Result(); err != nil {
 			s.logger.Error(err)
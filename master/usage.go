@@ -0,0 +1,75 @@
+package master
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/eventstore"
+	"github.com/edgestore/edgestore/internal/limit"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantUsage is the response of GetUsageHandler.
+type TenantUsage struct {
+	Limits limit.Limits `json:"limits"`
+	Usage  *limit.Usage `json:"usage"`
+}
+
+// GetUsageHandler returns the limits of the tenant and the storage it uses. Usage is tracked
+// while limits are configured, starting from the storage counted by countUsage.
+func (s *service) GetUsageHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetUsageHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+
+	if usage, err := s.limits.Usage(ctx, tenant); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, &TenantUsage{Limits: s.limits.Limits(tenant), Usage: usage})
+	}
+}
+
+// countUsage counts the live entities of a tenant and the bytes of the events of its entities and
+// associations, to seed its usage.
+func (s *service) countUsage(ctx context.Context, tenantID model.ID) (limit.Usage, error) {
+	const op errors.Op = "api/service.countUsage"
+
+	ctx = auth.Internal(ctx)
+	u := limit.Usage{}
+	err := s.entity.ExportEntities(ctx, tenantID, func(e *entity.Entity, history eventstore.History) error {
+		if e.DeletedAt == nil {
+			u.Entities++
+		}
+
+		u.EventBytes += historyBytes(history)
+		return nil
+	})
+	if err != nil {
+		return u, errors.E(op, err)
+	}
+
+	err = s.association.ExportAssociations(ctx, tenantID, func(a *association.Association, history eventstore.History) error {
+		u.EventBytes += historyBytes(history)
+		return nil
+	})
+	if err != nil {
+		return u, errors.E(op, err)
+	}
+
+	return u, nil
+}
+
+func historyBytes(history eventstore.History) int64 {
+	var n int64
+	for _, record := range history {
+		n += int64(len(record.Data))
+	}
+
+	return n
+}
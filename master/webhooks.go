@@ -0,0 +1,214 @@
+package master
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/edgestore/edgestore/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// EventKinds are the event kinds webhooks subscribe to.
+var EventKinds = eventKinds(
+	&entity.EntityInserted{},
+	&entity.EntityUpdated{},
+	&entity.EntityDeleted{},
+	&association.AssociationInserted{},
+	&association.AssociationUpdated{},
+	&association.AssociationDeleted{},
+)
+
+func eventKinds(events ...model.Event) []string {
+	kinds := make([]string, len(events))
+	for i, event := range events {
+		kinds[i], _ = model.EventType(event)
+	}
+
+	return kinds
+}
+
+// redact hides the secret of w, which is only returned when the webhook is created.
+func redact(w *webhook.Webhook) *webhook.Webhook {
+	redacted := *w
+	redacted.Secret = ""
+	return &redacted
+}
+
+func (s *service) ListWebhooksHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ListWebhooksHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	webhooks, err := s.webhooks.Store().ListWebhooks(ctx, model.ID(tenant))
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Transient, err))
+		return
+	}
+
+	res := make([]*webhook.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		res[i] = redact(w)
+	}
+
+	// Tenants have few webhooks, listed on a single page.
+	ctx.JSON(http.StatusOK, model.NewPage(res, nil))
+}
+
+func (s *service) GetWebhookHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetWebhookHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if w, err := s.webhooks.Store().GetWebhook(ctx, model.ID(ctx.Param("id")), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusOK, redact(w))
+	}
+}
+
+// CreateWebhookHandler registers a webhook. The response is the only one including its secret.
+func (s *service) CreateWebhookHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.CreateWebhookHandler"
+
+	var form webhook.Webhook
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	if err := form.Validate(ctx.Request.Context(), EventKinds); err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	id, err := s.guid.NextID()
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	form.ID = model.ID(id)
+	form.TenantID = model.ID(ctx.GetString(TenantKey))
+	form.CreatedAt = &now
+	form.UpdatedAt = &now
+	if form.Secret == "" {
+		form.Secret = webhook.NewSecret()
+	}
+
+	if err := s.webhooks.Store().PutWebhook(ctx, &form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Transient, err))
+		return
+	}
+
+	location := path.Join(Prefix, "webhooks", id)
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusCreated, form)
+}
+
+// UpdateWebhookHandler replaces the URL and kinds of a webhook, and its secret when given.
+func (s *service) UpdateWebhookHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.UpdateWebhookHandler"
+
+	var form webhook.Webhook
+	if err := ctx.ShouldBind(&form); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Invalid, err))
+		return
+	}
+
+	if err := form.Validate(ctx.Request.Context(), EventKinds); err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	tenant := ctx.GetString(TenantKey)
+
+	w, err := s.webhooks.Store().GetWebhook(ctx, model.ID(ctx.Param("id")), model.ID(tenant))
+	if err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	w.URL = form.URL
+	w.Kinds = form.Kinds
+	w.UpdatedAt = &now
+	if form.Secret != "" {
+		w.Secret = form.Secret
+	}
+
+	if err := s.webhooks.Store().PutWebhook(ctx, w); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Transient, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, redact(w))
+}
+
+func (s *service) DeleteWebhookHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.DeleteWebhookHandler"
+
+	tenant := ctx.GetString(TenantKey)
+
+	if err := s.webhooks.Store().DeleteWebhook(ctx, model.ID(ctx.Param("id")), model.ID(tenant)); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDeadLettersHandler lists the deliveries of a webhook that exhausted their attempts.
+func (s *service) GetDeadLettersHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.GetDeadLettersHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+	id := model.ID(ctx.Param("id"))
+
+	if _, err := s.webhooks.Store().GetWebhook(ctx, id, tenant); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+		return
+	}
+
+	pagination, err := s.NewPagination(ctx)
+	if err != nil {
+		s.AbortWithError(ctx, errors.E(op, err))
+		return
+	}
+
+	if page, err := s.webhooks.Store().DeadLetters(ctx, id, tenant, pagination); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, errors.E(op, errors.Transient, err))
+	} else {
+		ctx.JSON(http.StatusOK, page.Sign(s.cursors))
+	}
+}
+
+// ReplayDeadLetterHandler queues a dead delivery for another round of attempts.
+func (s *service) ReplayDeadLetterHandler(ctx *gin.Context) {
+	const op errors.Op = "api/service.ReplayDeadLetterHandler"
+
+	tenant := model.ID(ctx.GetString(TenantKey))
+	id := model.ID(ctx.Param("id"))
+
+	if d, err := s.webhooks.Replay(ctx, model.ID(ctx.Param("delivery")), id, tenant); err != nil {
+		s.logger.Error(errors.E(op, err))
+		s.AbortWithError(ctx, err)
+	} else {
+		ctx.JSON(http.StatusAccepted, d)
+	}
+}
@@ -0,0 +1,3 @@
+-- Built concurrently so that writes to records go on meanwhile. CREATE INDEX CONCURRENTLY cannot
+-- run inside a transaction: apply this file on its own, without wrapping it in BEGIN/COMMIT.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS records_tenant_id_aggregate_id_index ON records (tenant_id, aggregate_id);
@@ -0,0 +1,117 @@
+package permission
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/internal/model"
+)
+
+// SubjectRelationKey is the data key of associations granting their relation to a userset: an
+// association doc -viewer-> team with {"subject_relation": "member"} grants viewer on doc to the
+// members of team.
+const SubjectRelationKey = "subject_relation"
+
+// Namespace holds the rewrite rules of the relations of an entity type. Relations without a rule
+// are granted by direct associations only.
+type Namespace struct {
+	TenantID  model.ID            `json:"tenant_id"`
+	Type      string              `json:"otype"`
+	Relations map[string]*Rewrite `json:"relations" binding:"required"`
+	UpdatedAt *time.Time          `json:"updated_at"`
+}
+
+// Rewrite computes the subjects of a relation. Exactly one of its fields is set.
+type Rewrite struct {
+	// This grants the relation to the subjects of the associations of the relation type leaving
+	// the object.
+	This bool `json:"this,omitempty"`
+
+	// ComputedUserset grants the relation to the subjects of another relation of the object, such
+	// as editors being viewers.
+	ComputedUserset string `json:"computed_userset,omitempty"`
+
+	// TupleToUserset grants the relation to the subjects of a relation of the entities the object
+	// is associated with, such as the viewers of the parent folder of a document.
+	TupleToUserset *TupleToUserset `json:"tuple_to_userset,omitempty"`
+
+	// Union grants the relation to the subjects of any of its rewrites.
+	Union []*Rewrite `json:"union,omitempty"`
+}
+
+// TupleToUserset follows the associations of type Tupleset leaving the object, and evaluates
+// ComputedUserset on their targets.
+type TupleToUserset struct {
+	Tupleset        string `json:"tupleset" binding:"required"`
+	ComputedUserset string `json:"computed_userset" binding:"required"`
+}
+
+// direct is the rewrite of relations without rule.
+var direct = &Rewrite{This: true}
+
+// rewrite returns the rule of a relation.
+func (n *Namespace) rewrite(relation string) *Rewrite {
+	if n != nil {
+		if rw, ok := n.Relations[relation]; ok {
+			return rw
+		}
+	}
+
+	return direct
+}
+
+// validate returns an error when a rule is malformed or computes an undefined relation of the
+// namespace.
+func (n *Namespace) validate() error {
+	if len(n.Relations) == 0 {
+		return fmt.Errorf("namespace %s has no relations", n.Type)
+	}
+
+	for relation, rw := range n.Relations {
+		if err := n.validateRewrite(relation, rw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *Namespace) validateRewrite(relation string, rw *Rewrite) error {
+	if rw == nil {
+		return fmt.Errorf("relation %s has an empty rewrite", relation)
+	}
+
+	set := 0
+	if rw.This {
+		set++
+	}
+
+	if rw.ComputedUserset != "" {
+		set++
+		if _, ok := n.Relations[rw.ComputedUserset]; !ok {
+			return fmt.Errorf("relation %s computes undefined relation %s", relation, rw.ComputedUserset)
+		}
+	}
+
+	if rw.TupleToUserset != nil {
+		set++
+		if rw.TupleToUserset.Tupleset == "" || rw.TupleToUserset.ComputedUserset == "" {
+			return fmt.Errorf("relation %s has an incomplete tuple_to_userset", relation)
+		}
+	}
+
+	if len(rw.Union) > 0 {
+		set++
+		for _, child := range rw.Union {
+			if err := n.validateRewrite(relation, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("relation %s must set exactly one of this, computed_userset, tuple_to_userset or union", relation)
+	}
+
+	return nil
+}
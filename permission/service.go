@@ -0,0 +1,533 @@
+package permission
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/auth"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxDepth is the maximum number of relations evaluated in a chain by a single check.
+	DefaultMaxDepth = 16
+
+	// DefaultMaxFanOut is the maximum number of associations of a relation leaving a single object.
+	DefaultMaxFanOut = 1000
+
+	// DefaultTimeout is the maximum duration of a single check or expand.
+	DefaultTimeout = 5 * time.Second
+)
+
+// EntityGetter is implemented by entity.Service.
+type EntityGetter interface {
+	GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error)
+}
+
+// AssociationGetter is implemented by association.Service.
+type AssociationGetter interface {
+	GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error)
+	GetAssociationAtLeast(ctx context.Context, id model.ID, tenantID model.ID, version model.Version) (*association.Association, error)
+}
+
+// Check asks whether Subject has Relation on Object.
+type Check struct {
+	TenantID model.ID `json:"-"`
+	Object   model.ID `json:"object" binding:"required"`
+	Relation string   `json:"relation" binding:"required"`
+	Subject  model.ID `json:"subject" binding:"required"`
+
+	// AtLeastAsFresh is the consistency token of a previous check or expand.
+	AtLeastAsFresh string `json:"at_least_as_fresh,omitempty"`
+}
+
+type CheckResult struct {
+	Allowed          bool   `json:"allowed"`
+	ConsistencyToken string `json:"consistency_token,omitempty"`
+}
+
+// Expand asks for the subjects of Relation on Object.
+type Expand struct {
+	TenantID model.ID `json:"-"`
+	Object   model.ID `json:"object" binding:"required"`
+	Relation string   `json:"relation" binding:"required"`
+
+	// AtLeastAsFresh is the consistency token of a previous check or expand.
+	AtLeastAsFresh string `json:"at_least_as_fresh,omitempty"`
+}
+
+// Tree is the evaluation of the rewrite of a relation: the subjects it grants directly and the
+// evaluation of the rewrites and relations it depends on.
+type Tree struct {
+	Object   model.ID   `json:"object"`
+	Relation string     `json:"relation"`
+	Rewrite  string     `json:"rewrite"`
+	Subjects []model.ID `json:"subjects,omitempty"`
+	Children []*Tree    `json:"children,omitempty"`
+
+	// Cycle is set when the relation is already being expanded by a parent.
+	Cycle bool `json:"cycle,omitempty"`
+}
+
+type ExpandResult struct {
+	Tree             *Tree  `json:"tree"`
+	ConsistencyToken string `json:"consistency_token,omitempty"`
+}
+
+// Service evaluates relation-based permissions over associations: an association
+// object -relation-> subject grants relation on object to subject, and the namespaces of tenants
+// rewrite relations into others.
+type Service struct {
+	associations AssociationGetter
+	entities     EntityGetter
+	logger       logrus.FieldLogger
+	maxDepth     int
+	maxFanOut    int
+	now          func() time.Time
+	store        Store
+	timeout      time.Duration
+	tokens       *TokenSigner
+}
+
+type Config struct {
+	Associations AssociationGetter
+	Entities     EntityGetter
+	Logger       logrus.FieldLogger
+	MaxDepth     int
+	MaxFanOut    int
+	Store        Store
+	Timeout      time.Duration
+
+	// TokenSecret signs consistency tokens, shared by every node. A random secret is used when
+	// empty.
+	TokenSecret []byte
+}
+
+func New(cfg *Config) *Service {
+	svc := &Service{
+		associations: cfg.Associations,
+		entities:     cfg.Entities,
+		logger:       cfg.Logger.WithField("component", "permission-service"),
+		maxDepth:     cfg.MaxDepth,
+		maxFanOut:    cfg.MaxFanOut,
+		now:          time.Now,
+		store:        cfg.Store,
+		timeout:      cfg.Timeout,
+	}
+
+	secret := cfg.TokenSecret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	svc.tokens = NewTokenSigner(secret)
+
+	if svc.maxDepth <= 0 {
+		svc.maxDepth = DefaultMaxDepth
+	}
+
+	if svc.maxFanOut <= 0 {
+		svc.maxFanOut = DefaultMaxFanOut
+	}
+
+	if svc.timeout <= 0 {
+		svc.timeout = DefaultTimeout
+	}
+
+	return svc
+}
+
+// evaluator evaluates the relations of a single check or expand.
+type evaluator struct {
+	svc      *Service
+	tenantID model.ID
+	subject  model.ID
+
+	// fresh are the versions required by the consistency token of the request, seen the versions
+	// read so far.
+	fresh Token
+	seen  Token
+
+	namespaces map[model.ID]*Namespace
+	visiting   map[string]bool
+}
+
+func (s *Service) newEvaluator(tenantID model.ID, subject model.ID, token string) (*evaluator, error) {
+	if tenantID == "" {
+		return nil, errors.E(errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	fresh, err := s.tokens.Decode(tenantID, token)
+	if err != nil {
+		return nil, errors.E(errors.Invalid, err)
+	}
+
+	seen := Token{}
+	for id, e := range fresh {
+		seen[id] = e
+	}
+
+	return &evaluator{
+		svc:        s,
+		tenantID:   tenantID,
+		subject:    subject,
+		fresh:      fresh,
+		seen:       seen,
+		namespaces: map[model.ID]*Namespace{},
+		visiting:   map[string]bool{},
+	}, nil
+}
+
+// namespace returns the namespace of the type of object, nil when it has none.
+func (e *evaluator) namespace(ctx context.Context, object model.ID) (*Namespace, error) {
+	if n, ok := e.namespaces[object]; ok {
+		return n, nil
+	}
+
+	// The type of objects is looked up on behalf of the service, their data is not disclosed.
+	var n *Namespace
+	ent, err := e.svc.entities.GetEntity(auth.Internal(ctx), object, e.tenantID)
+	if err != nil && !errors.Is(errors.NotFound, err) {
+		return nil, err
+	}
+
+	if ent != nil {
+		n, err = e.svc.store.GetNamespace(ctx, ent.Type, e.tenantID)
+		if err != nil && !errors.Is(errors.NotFound, err) {
+			return nil, errors.E(errors.Transient, err)
+		}
+	}
+
+	e.namespaces[object] = n
+	return n, nil
+}
+
+// edges returns the live associations of type atype leaving object, at least as fresh as the
+// consistency token of the request.
+func (e *evaluator) edges(ctx context.Context, object model.ID, atype string) ([]*association.Association, error) {
+	page, err := e.svc.associations.GetOutgoingAssociations(ctx, object, atype, e.tenantID, model.NewPagination(e.svc.maxFanOut, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	if page.HasMore {
+		return nil, errors.E(errors.Invalid, fmt.Sprintf("%s has more than %d associations of type %s", object, e.svc.maxFanOut, atype))
+	}
+
+	listed := map[model.ID]bool{}
+	candidates := page.Items
+	for _, assoc := range page.Items {
+		listed[assoc.ID] = true
+	}
+
+	// Associations of the token not listed yet were created since the cache was updated.
+	for id, edge := range e.fresh {
+		if edge.In == object && edge.Type == atype && !listed[id] {
+			candidates = append(candidates, &association.Association{ID: id, In: object, Type: atype})
+		}
+	}
+
+	edges := make([]*association.Association, 0, len(candidates))
+	for _, assoc := range candidates {
+		if edge, ok := e.fresh[assoc.ID]; ok && assoc.Version < edge.Version {
+			if assoc, err = e.svc.associations.GetAssociationAtLeast(ctx, assoc.ID, e.tenantID, edge.Version); err != nil {
+				return nil, err
+			}
+		}
+
+		e.seen.observe(assoc.ID, Edge{In: assoc.In, Type: assoc.Type, Version: assoc.Version})
+		if assoc.DeletedAt == nil {
+			edges = append(edges, assoc)
+		}
+	}
+
+	return edges, nil
+}
+
+func subjectRelation(assoc *association.Association) string {
+	relation, _ := assoc.Data[SubjectRelationKey].(string)
+	return relation
+}
+
+// enter marks object#relation as evaluated, reporting false when it already is, in a cycle.
+func (e *evaluator) enter(object model.ID, relation string, depth int) (bool, error) {
+	if depth > e.svc.maxDepth {
+		return false, errors.E(errors.Invalid, fmt.Sprintf("evaluation exceeds the maximum depth of %d", e.svc.maxDepth))
+	}
+
+	key := fmt.Sprintf("%s#%s", object, relation)
+	if e.visiting[key] {
+		return false, nil
+	}
+
+	e.visiting[key] = true
+	return true, nil
+}
+
+func (e *evaluator) leave(object model.ID, relation string) {
+	delete(e.visiting, fmt.Sprintf("%s#%s", object, relation))
+}
+
+func (e *evaluator) check(ctx context.Context, object model.ID, relation string, depth int) (bool, error) {
+	if ok, err := e.enter(object, relation, depth); !ok {
+		return false, err
+	}
+	defer e.leave(object, relation)
+
+	n, err := e.namespace(ctx, object)
+	if err != nil {
+		return false, err
+	}
+
+	return e.checkRewrite(ctx, object, relation, n.rewrite(relation), depth)
+}
+
+func (e *evaluator) checkRewrite(ctx context.Context, object model.ID, relation string, rw *Rewrite, depth int) (bool, error) {
+	switch {
+	case rw.This:
+		edges, err := e.edges(ctx, object, relation)
+		if err != nil {
+			return false, err
+		}
+
+		for _, assoc := range edges {
+			if userset := subjectRelation(assoc); userset != "" {
+				if ok, err := e.check(ctx, assoc.Out, userset, depth+1); err != nil || ok {
+					return ok, err
+				}
+			} else if assoc.Out == e.subject {
+				return true, nil
+			}
+		}
+	case rw.ComputedUserset != "":
+		return e.check(ctx, object, rw.ComputedUserset, depth+1)
+	case rw.TupleToUserset != nil:
+		edges, err := e.edges(ctx, object, rw.TupleToUserset.Tupleset)
+		if err != nil {
+			return false, err
+		}
+
+		for _, assoc := range edges {
+			if ok, err := e.check(ctx, assoc.Out, rw.TupleToUserset.ComputedUserset, depth+1); err != nil || ok {
+				return ok, err
+			}
+		}
+	default:
+		for _, child := range rw.Union {
+			if ok, err := e.checkRewrite(ctx, object, relation, child, depth); err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (e *evaluator) expand(ctx context.Context, object model.ID, relation string, depth int) (*Tree, error) {
+	ok, err := e.enter(object, relation, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return &Tree{Object: object, Relation: relation, Cycle: true}, nil
+	}
+	defer e.leave(object, relation)
+
+	n, err := e.namespace(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.expandRewrite(ctx, object, relation, n.rewrite(relation), depth)
+}
+
+func (e *evaluator) expandRewrite(ctx context.Context, object model.ID, relation string, rw *Rewrite, depth int) (*Tree, error) {
+	tree := &Tree{Object: object, Relation: relation}
+
+	switch {
+	case rw.This:
+		tree.Rewrite = "this"
+
+		edges, err := e.edges(ctx, object, relation)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, assoc := range edges {
+			userset := subjectRelation(assoc)
+			if userset == "" {
+				tree.Subjects = append(tree.Subjects, assoc.Out)
+				continue
+			}
+
+			child, err := e.expand(ctx, assoc.Out, userset, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			tree.Children = append(tree.Children, child)
+		}
+	case rw.ComputedUserset != "":
+		tree.Rewrite = "computed_userset"
+
+		child, err := e.expand(ctx, object, rw.ComputedUserset, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		tree.Children = append(tree.Children, child)
+	case rw.TupleToUserset != nil:
+		tree.Rewrite = "tuple_to_userset"
+
+		edges, err := e.edges(ctx, object, rw.TupleToUserset.Tupleset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, assoc := range edges {
+			child, err := e.expand(ctx, assoc.Out, rw.TupleToUserset.ComputedUserset, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			tree.Children = append(tree.Children, child)
+		}
+	default:
+		tree.Rewrite = "union"
+
+		for _, rewrite := range rw.Union {
+			child, err := e.expandRewrite(ctx, object, relation, rewrite, depth)
+			if err != nil {
+				return nil, err
+			}
+
+			tree.Children = append(tree.Children, child)
+		}
+	}
+
+	return tree, nil
+}
+
+// Check reports whether the subject has the relation on the object. The result holds a
+// consistency token for later checks to be at least as fresh.
+func (s *Service) Check(ctx context.Context, c *Check) (*CheckResult, error) {
+	const op errors.Op = "permission/Service.Check"
+	s.logger.Infof("%s: tenant=%s, object=%s, relation=%s, subject=%s", op, c.TenantID, c.Object, c.Relation, c.Subject)
+
+	e, err := s.newEvaluator(c.TenantID, c.Subject, c.AtLeastAsFresh)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	allowed, err := e.check(ctx, c.Object, c.Relation, 0)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return &CheckResult{Allowed: allowed, ConsistencyToken: s.tokens.Encode(e.tenantID, e.seen)}, nil
+}
+
+// Expand returns the tree of the subjects of the relation on the object, to debug rewrites.
+func (s *Service) Expand(ctx context.Context, x *Expand) (*ExpandResult, error) {
+	const op errors.Op = "permission/Service.Expand"
+	s.logger.Infof("%s: tenant=%s, object=%s, relation=%s", op, x.TenantID, x.Object, x.Relation)
+
+	e, err := s.newEvaluator(x.TenantID, "", x.AtLeastAsFresh)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tree, err := e.expand(ctx, x.Object, x.Relation, 0)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return &ExpandResult{Tree: tree, ConsistencyToken: s.tokens.Encode(e.tenantID, e.seen)}, nil
+}
+
+// GetNamespace returns the namespace of an entity type.
+func (s *Service) GetNamespace(ctx context.Context, otype string, tenantID model.ID) (*Namespace, error) {
+	const op errors.Op = "permission/Service.GetNamespace"
+
+	n, err := s.store.GetNamespace(ctx, otype, tenantID)
+	if err != nil {
+		if errors.Is(errors.NotFound, err) {
+			return nil, errors.E(op, err)
+		}
+
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return n, nil
+}
+
+// ListNamespaces returns the namespaces of the tenant, ordered by type.
+func (s *Service) ListNamespaces(ctx context.Context, tenantID model.ID) ([]*Namespace, error) {
+	const op errors.Op = "permission/Service.ListNamespaces"
+
+	namespaces, err := s.store.ListNamespaces(ctx, tenantID)
+	if err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return namespaces, nil
+}
+
+// PutNamespace creates or replaces the namespace of an entity type.
+func (s *Service) PutNamespace(ctx context.Context, n *Namespace) (*Namespace, error) {
+	const op errors.Op = "permission/Service.PutNamespace"
+	s.logger.Infof("%s: tenant=%s, otype=%s", op, n.TenantID, n.Type)
+
+	if n.TenantID == "" {
+		return nil, errors.E(op, errors.Invalid, "Tenant ID cannot be empty")
+	}
+
+	if n.Type == "" {
+		return nil, errors.E(op, errors.Invalid, "type is required")
+	}
+
+	if err := n.validate(); err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+
+	now := s.now().UTC()
+	n.UpdatedAt = &now
+
+	if err := s.store.PutNamespace(ctx, n); err != nil {
+		return nil, errors.E(op, errors.Transient, err)
+	}
+
+	return n, nil
+}
+
+// DeleteNamespace removes the namespace of an entity type, whose relations are then granted by
+// direct associations only.
+func (s *Service) DeleteNamespace(ctx context.Context, otype string, tenantID model.ID) error {
+	const op errors.Op = "permission/Service.DeleteNamespace"
+	s.logger.Infof("%s: tenant=%s, otype=%s", op, tenantID, otype)
+
+	if _, err := s.GetNamespace(ctx, otype, tenantID); err != nil {
+		return errors.E(op, err)
+	}
+
+	if err := s.store.DeleteNamespace(ctx, otype, tenantID); err != nil {
+		return errors.E(op, errors.Transient, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package permission
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgestore/edgestore/association"
+	"github.com/edgestore/edgestore/entity"
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tenantID = model.ID("acme")
+
+type fakeGraph struct {
+	entities     map[model.ID]*entity.Entity
+	associations []*association.Association
+
+	// stored are the associations of the event store, cached are listed.
+	stored map[model.ID]*association.Association
+}
+
+func (g *fakeGraph) GetEntity(ctx context.Context, id model.ID, tenantID model.ID) (*entity.Entity, error) {
+	if e, ok := g.entities[id]; ok {
+		return e, nil
+	}
+
+	return nil, errors.E(errors.NotFound)
+}
+
+func (g *fakeGraph) GetOutgoingAssociations(ctx context.Context, in model.ID, atype string, tenantID model.ID, p *model.Pagination) (*model.Page[*association.Association], error) {
+	var found []*association.Association
+	for _, assoc := range g.associations {
+		if assoc.In == in && assoc.Type == atype && assoc.DeletedAt == nil {
+			found = append(found, assoc)
+		}
+	}
+
+	return model.NewPage(found, nil), nil
+}
+
+func (g *fakeGraph) GetAssociationAtLeast(ctx context.Context, id model.ID, tenantID model.ID, version model.Version) (*association.Association, error) {
+	if assoc, ok := g.stored[id]; ok && assoc.Version >= version {
+		return assoc, nil
+	}
+
+	return nil, errors.E(errors.Invalid)
+}
+
+func (g *fakeGraph) link(in model.ID, atype string, out model.ID, data model.Data) *association.Association {
+	assoc := &association.Association{
+		ID:      association.NewAssociationID(in, atype, out),
+		In:      in,
+		Type:    atype,
+		Out:     out,
+		Data:    data,
+		Version: 1,
+	}
+	g.associations = append(g.associations, assoc)
+	return assoc
+}
+
+func newEntity(id model.ID, otype string) *entity.Entity {
+	return &entity.Entity{ID: id, Type: otype, TenantID: tenantID}
+}
+
+// newService returns a service over documents in folders, shared with users directly or through
+// the members of teams:
+//
+//	folder: viewer = this + editor + viewer of parent; editor = this
+//	readme -parent-> docs, docs -viewer-> alice, docs -editor-> eng#member, eng -member-> bob
+func newService(t *testing.T) (*Service, *fakeGraph) {
+	g := &fakeGraph{entities: map[model.ID]*entity.Entity{}, stored: map[model.ID]*association.Association{}}
+	for id, otype := range map[model.ID]string{"readme": "document", "docs": "folder", "eng": "team", "alice": "user", "bob": "user", "carol": "user"} {
+		g.entities[id] = newEntity(id, otype)
+	}
+
+	g.link("readme", "parent", "docs", nil)
+	g.link("docs", "viewer", "alice", nil)
+	g.link("docs", "editor", "eng", model.Data{SubjectRelationKey: "member"})
+	g.link("eng", "member", "bob", nil)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	svc := New(&Config{Associations: g, Entities: g, Logger: logger, Store: NewInMemory()})
+
+	viewer := &Rewrite{Union: []*Rewrite{
+		{This: true},
+		{ComputedUserset: "editor"},
+		{TupleToUserset: &TupleToUserset{Tupleset: "parent", ComputedUserset: "viewer"}},
+	}}
+	for _, otype := range []string{"document", "folder"} {
+		_, err := svc.PutNamespace(context.Background(), &Namespace{
+			TenantID:  tenantID,
+			Type:      otype,
+			Relations: map[string]*Rewrite{"viewer": viewer, "editor": {This: true}},
+		})
+		require.NoError(t, err)
+	}
+
+	return svc, g
+}
+
+func check(t *testing.T, svc *Service, object model.ID, relation string, subject model.ID) bool {
+	res, err := svc.Check(context.Background(), &Check{TenantID: tenantID, Object: object, Relation: relation, Subject: subject})
+	require.NoError(t, err)
+	return res.Allowed
+}
+
+func TestService_Check(t *testing.T) {
+	svc, _ := newService(t)
+
+	assert.True(t, check(t, svc, "docs", "viewer", "alice"))
+	assert.True(t, check(t, svc, "docs", "editor", "bob"))
+	assert.True(t, check(t, svc, "docs", "viewer", "bob"))
+	assert.True(t, check(t, svc, "readme", "viewer", "alice"))
+	assert.True(t, check(t, svc, "readme", "viewer", "bob"))
+	assert.False(t, check(t, svc, "readme", "editor", "bob"))
+	assert.False(t, check(t, svc, "readme", "viewer", "carol"))
+
+	// Types without namespace are granted by direct associations only.
+	assert.True(t, check(t, svc, "eng", "member", "bob"))
+	assert.False(t, check(t, svc, "eng", "member", "alice"))
+}
+
+func TestService_Check_Cycle(t *testing.T) {
+	svc, g := newService(t)
+	g.link("docs", "parent", "readme", nil)
+
+	assert.True(t, check(t, svc, "readme", "viewer", "bob"))
+	assert.False(t, check(t, svc, "readme", "viewer", "carol"))
+}
+
+func TestService_Check_ConsistencyToken(t *testing.T) {
+	ctx := context.Background()
+	svc, g := newService(t)
+	membership := g.associations[3]
+
+	res, err := svc.Check(ctx, &Check{TenantID: tenantID, Object: "eng", Relation: "member", Subject: "bob"})
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	token, err := svc.tokens.Decode(tenantID, res.ConsistencyToken)
+	require.NoError(t, err)
+	assert.Equal(t, Edge{In: "eng", Type: "member", Version: 1}, token[membership.ID])
+
+	// Associations of the token missing from a stale cache are read from the event store.
+	g.associations = g.associations[:3]
+	g.stored[membership.ID] = membership
+	res, err = svc.Check(ctx, &Check{TenantID: tenantID, Object: "eng", Relation: "member", Subject: "bob", AtLeastAsFresh: res.ConsistencyToken})
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// So are cached associations older than the token, such as a membership revoked since.
+	g.associations = append(g.associations, membership)
+	revoked := *membership
+	revoked.Version = 2
+	revoked.DeletedAt = &time.Time{}
+	g.stored[membership.ID] = &revoked
+
+	fresh := svc.tokens.Encode(tenantID, Token{membership.ID: {In: "eng", Type: "member", Version: 2}})
+	res, err = svc.Check(ctx, &Check{TenantID: tenantID, Object: "docs", Relation: "viewer", Subject: "bob", AtLeastAsFresh: fresh})
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, check(t, svc, "docs", "viewer", "bob"))
+
+	_, err = svc.Check(ctx, &Check{TenantID: tenantID, Object: "docs", Relation: "viewer", Subject: "bob", AtLeastAsFresh: "invalid!"})
+	assert.True(t, errors.Is(errors.Invalid, err))
+}
+
+func TestTokenSigner(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"))
+	token := Token{"eng:member:bob": {In: "eng", Type: "member", Version: 3}}
+
+	encoded := signer.Encode(tenantID, token)
+	decoded, err := signer.Decode(tenantID, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, token, decoded)
+
+	// Tokens are bound to their tenant and secret.
+	_, err = signer.Decode("other", encoded)
+	assert.Error(t, err)
+	_, err = NewTokenSigner([]byte("other")).Decode(tenantID, encoded)
+	assert.Error(t, err)
+
+	// Unsigned tokens, such as forged ones, are rejected.
+	payload, _ := json.Marshal(token)
+	_, err = signer.Decode(tenantID, base64.RawURLEncoding.EncodeToString(payload))
+	assert.Error(t, err)
+
+	// Tokens are bounded.
+	large := Token{}
+	for i := 0; i <= MaxTokenEdges; i++ {
+		large[model.ID(fmt.Sprintf("eng:member:%d", i))] = Edge{In: "eng", Type: "member", Version: 1}
+	}
+	assert.Empty(t, signer.Encode(tenantID, large))
+	_, err = signer.Decode(tenantID, strings.Repeat("a", maxTokenSize+1))
+	assert.Error(t, err)
+
+	decoded, err = signer.Decode(tenantID, "")
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+}
+
+func TestService_Expand(t *testing.T) {
+	svc, _ := newService(t)
+
+	res, err := svc.Expand(context.Background(), &Expand{TenantID: tenantID, Object: "readme", Relation: "viewer"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, res.ConsistencyToken)
+
+	tree := res.Tree
+	assert.Equal(t, "union", tree.Rewrite)
+	require.Len(t, tree.Children, 3)
+
+	// viewer of the parent folder: alice directly, bob as a member of the editing team.
+	parent := tree.Children[2]
+	assert.Equal(t, "tuple_to_userset", parent.Rewrite)
+	require.Len(t, parent.Children, 1)
+	docs := parent.Children[0]
+	assert.Equal(t, model.ID("docs"), docs.Object)
+	assert.Equal(t, []model.ID{"alice"}, docs.Children[0].Subjects)
+
+	editors := docs.Children[1].Children[0]
+	assert.Equal(t, "this", editors.Rewrite)
+	assert.Equal(t, []model.ID{"bob"}, editors.Children[0].Subjects)
+}
+
+func TestService_PutNamespace(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService(t)
+
+	invalid := map[string]map[string]*Rewrite{
+		"empty":     {},
+		"nil":       {"viewer": nil},
+		"undefined": {"viewer": {ComputedUserset: "editor"}},
+		"ambiguous": {"viewer": {This: true, ComputedUserset: "viewer"}},
+		"partial":   {"viewer": {TupleToUserset: &TupleToUserset{Tupleset: "parent"}}},
+	}
+
+	for name, relations := range invalid {
+		_, err := svc.PutNamespace(ctx, &Namespace{TenantID: tenantID, Type: "document", Relations: relations})
+		assert.True(t, errors.Is(errors.Invalid, err), name)
+	}
+
+	namespaces, err := svc.ListNamespaces(ctx, tenantID)
+	require.NoError(t, err)
+	assert.Len(t, namespaces, 2)
+
+	require.NoError(t, svc.DeleteNamespace(ctx, "document", tenantID))
+	assert.True(t, errors.Is(errors.NotFound, svc.DeleteNamespace(ctx, "document", tenantID)))
+}
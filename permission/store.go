@@ -0,0 +1,157 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/edgestore/edgestore/internal/errors"
+	"github.com/edgestore/edgestore/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store persists the namespaces of tenants.
+type Store interface {
+	GetNamespace(ctx context.Context, otype string, tenantID model.ID) (*Namespace, error)
+	ListNamespaces(ctx context.Context, tenantID model.ID) ([]*Namespace, error)
+	PutNamespace(ctx context.Context, n *Namespace) error
+	DeleteNamespace(ctx context.Context, otype string, tenantID model.ID) error
+}
+
+func notFound(otype string) error {
+	return errors.E(errors.NotFound, fmt.Sprintf("namespace %s not found", otype))
+}
+
+// InMemory is a Store for tests and single node deployments.
+type InMemory struct {
+	mux        sync.Mutex
+	namespaces map[model.ID]map[string]Namespace
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{namespaces: map[model.ID]map[string]Namespace{}}
+}
+
+// GetNamespace implements the Store interface.
+func (m *InMemory) GetNamespace(ctx context.Context, otype string, tenantID model.ID) (*Namespace, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, ok := m.namespaces[tenantID][otype]
+	if !ok {
+		return nil, notFound(otype)
+	}
+
+	return &n, nil
+}
+
+// ListNamespaces implements the Store interface.
+func (m *InMemory) ListNamespaces(ctx context.Context, tenantID model.ID) ([]*Namespace, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	namespaces := make([]*Namespace, 0, len(m.namespaces[tenantID]))
+	for _, n := range m.namespaces[tenantID] {
+		n := n
+		namespaces = append(namespaces, &n)
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Type < namespaces[j].Type })
+	return namespaces, nil
+}
+
+// PutNamespace implements the Store interface.
+func (m *InMemory) PutNamespace(ctx context.Context, n *Namespace) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.namespaces[n.TenantID] == nil {
+		m.namespaces[n.TenantID] = map[string]Namespace{}
+	}
+
+	m.namespaces[n.TenantID][n.Type] = *n
+	return nil
+}
+
+// DeleteNamespace implements the Store interface.
+func (m *InMemory) DeleteNamespace(ctx context.Context, otype string, tenantID model.ID) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.namespaces[tenantID], otype)
+	return nil
+}
+
+// Redis is a Store keeping the namespaces of a tenant as JSON values of a hash.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *Redis) key(tenantID model.ID) string {
+	return fmt.Sprintf("%s:%s:namespaces", r.prefix, tenantID)
+}
+
+// GetNamespace implements the Store interface.
+func (r *Redis) GetNamespace(ctx context.Context, otype string, tenantID model.ID) (*Namespace, error) {
+	b, err := r.client.HGet(ctx, r.key(tenantID), otype).Bytes()
+	if err == redis.Nil {
+		return nil, notFound(otype)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var n Namespace
+	if err := json.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// ListNamespaces implements the Store interface.
+func (r *Redis) ListNamespaces(ctx context.Context, tenantID model.ID) ([]*Namespace, error) {
+	values, err := r.client.HGetAll(ctx, r.key(tenantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]*Namespace, 0, len(values))
+	for _, v := range values {
+		var n Namespace
+		if err := json.Unmarshal([]byte(v), &n); err != nil {
+			return nil, err
+		}
+
+		namespaces = append(namespaces, &n)
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Type < namespaces[j].Type })
+	return namespaces, nil
+}
+
+// PutNamespace implements the Store interface.
+func (r *Redis) PutNamespace(ctx context.Context, n *Namespace) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	return r.client.HSet(ctx, r.key(n.TenantID), n.Type, b).Err()
+}
+
+// DeleteNamespace implements the Store interface.
+func (r *Redis) DeleteNamespace(ctx context.Context, otype string, tenantID model.ID) error {
+	return r.client.HDel(ctx, r.key(tenantID), otype).Err()
+}